@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -40,6 +41,15 @@ type RunbookSpec struct {
 	// Template specifies which template to use for generation
 	Template string `json:"template,omitempty"`
 
+	// Variables supplies values for the variables declared by the template
+	Variables map[string]string `json:"variables,omitempty"`
+
+	// ExampleLabels are example values of the alert labels that command
+	// placeholders such as {{ .Labels.namespace }} reference. Generated docs
+	// show the commands rendered with them, automation renders the commands
+	// with the labels of the firing alert.
+	ExampleLabels map[string]string `json:"exampleLabels,omitempty"`
+
 	// AutoGenerate indicates if this runbook should be auto-generated
 	// +kubebuilder:default=true
 	AutoGenerate bool `json:"autoGenerate,omitempty"`
@@ -74,27 +84,69 @@ type InvestigationStep struct {
 	// Description of what to investigate
 	Description string `json:"description"`
 
-	// Command to execute (optional)
+	// Command to execute (optional). Alert labels are referenced as
+	// {{ .Labels.<name> }} and are always quoted for the shell.
 	Command string `json:"command,omitempty"`
 
 	// Expected result or what to look for
 	Expected string `json:"expected,omitempty"`
+
+	// Expectation checks the output of the command when an execution runs the step
+	Expectation *Expectation `json:"expectation,omitempty"`
+
+	// Automated lets executions run the command, before any remediation, to
+	// capture a diagnostic snapshot
+	Automated bool `json:"automated,omitempty"`
+
+	// Risk level of the command, gated like the risk of remediation steps.
+	// Defaults to medium for automated steps, set low for read-only commands
+	// that should run right away.
+	// +kubebuilder:validation:Enum=low;medium;high
+	Risk string `json:"risk,omitempty"`
 }
 
+// Expectation is a machine-checkable expectation of an investigation command.
+// Every check that is set must hold.
+type Expectation struct {
+	// ExitCode the command must exit with, defaults to 0
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// Regex the output of the command must match
+	Regex string `json:"regex,omitempty"`
+
+	// Threshold compares the last line of the output, as a number or a
+	// duration, e.g. "<80" or ">=0.5"
+	Threshold string `json:"threshold,omitempty"`
+}
+
+// Risk levels of investigation and remediation steps
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
 // RemediationStep represents a single remediation action
 type RemediationStep struct {
 	// Description of the remediation action
 	Description string `json:"description"`
 
-	// Command to execute (optional)
+	// Command to execute (optional). Alert labels are referenced as
+	// {{ .Labels.<name> }} and are always quoted for the shell.
 	Command string `json:"command,omitempty"`
 
-	// Risk level of this action
+	// Risk level of this action. Automated low risk steps run right away,
+	// medium risk steps wait for an approval and high risk steps never run
+	// automatically. Defaults to medium for automated steps.
 	// +kubebuilder:validation:Enum=low;medium;high
 	Risk string `json:"risk,omitempty"`
 
 	// Whether this step can be automated
 	Automated bool `json:"automated,omitempty"`
+
+	// Script is the name of a script in AutomationConfig.ScriptsConfigMap to
+	// run instead of Command when the step is automated
+	Script string `json:"script,omitempty"`
 }
 
 // AutomationConfig defines automation settings
@@ -102,11 +154,61 @@ type AutomationConfig struct {
 	// Enabled indicates if automation is enabled
 	Enabled bool `json:"enabled,omitempty"`
 
-	// Scripts to execute for automatic remediation
+	// Scripts to execute for automatic remediation, after the automated
+	// remediation steps. Each entry names a script in ScriptsConfigMap.
 	Scripts []string `json:"scripts,omitempty"`
 
+	// ScriptsConfigMap is the ConfigMap in the runbook namespace whose keys are the scripts
+	ScriptsConfigMap string `json:"scriptsConfigMap,omitempty"`
+
 	// Triggers define when automation should run
 	Triggers []TriggerConfig `json:"triggers,omitempty"`
+
+	// DryRun evaluates triggers and approvals and records what the
+	// executions would run, without creating any Jobs
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Image is the container image the automation steps run in, defaults to the operator setting
+	Image string `json:"image,omitempty"`
+
+	// ServiceAccountName is the ServiceAccount the automation steps run as,
+	// defaults to the operator setting. Anyone who can edit the runbook can
+	// run commands with its permissions, so other ServiceAccounts than the
+	// default one must be allowed with the --automation-service-accounts
+	// flag of the operator.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// ScriptsRisk is the risk level of the automation scripts
+	// +kubebuilder:validation:Enum=low;medium;high
+	// +kubebuilder:default=medium
+	ScriptsRisk string `json:"scriptsRisk,omitempty"`
+
+	// ApprovalTimeout is how long an execution waits for the approval of a
+	// medium risk step before failing, defaults to one hour
+	ApprovalTimeout *metav1.Duration `json:"approvalTimeout,omitempty"`
+
+	// WebhookSecretRef selects the key in a Secret of the runbook namespace
+	// that signs the requests of webhook triggers with HMAC-SHA256
+	WebhookSecretRef *corev1.SecretKeySelector `json:"webhookSecretRef,omitempty"`
+
+	// MaxConcurrentExecutions is how many executions of the runbook may run
+	// at the same time, further executions wait. 0 means no limit.
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrentExecutions int32 `json:"maxConcurrentExecutions,omitempty"`
+
+	// Cooldown is the minimum time between the start of two executions,
+	// executions triggered sooner are skipped
+	Cooldown *metav1.Duration `json:"cooldown,omitempty"`
+
+	// MaxExecutionsPerHour is how many executions may start within an hour,
+	// further executions are skipped. 0 means no limit.
+	// +kubebuilder:validation:Minimum=0
+	MaxExecutionsPerHour int32 `json:"maxExecutionsPerHour,omitempty"`
+
+	// HistoryLimit is how many executions status.automation.history keeps, defaults to 10
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	HistoryLimit int32 `json:"historyLimit,omitempty"`
 }
 
 // TriggerConfig defines when automation should trigger
@@ -134,15 +236,30 @@ type Reference struct {
 
 // OutputConfig defines where runbooks should be published
 type OutputConfig struct {
-	// Format of the output (markdown, html, pdf)
-	// +kubebuilder:validation:Enum=markdown;html;pdf
+	// Format of the output (markdown, html, pdf, git, configmap, s3, confluence, api)
+	// +kubebuilder:validation:Enum=markdown;html;pdf;git;configmap;s3;confluence;api
 	Format string `json:"format"`
 
-	// Destination where the output should be published
+	// Destination where the output should be published. For git outputs it
+	// is the directory of the repository the runbooks are written to, for
+	// configmap outputs the namespace of the ConfigMaps, for s3 outputs the
+	// bucket followed by the key prefix and for confluence and api outputs
+	// the base URL of the service.
 	Destination string `json:"destination"`
 
+	// SecretRef names a Secret in the runbook namespace with the location
+	// and credentials of outputs that publish to remote services
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// Parameters are the settings of outputs that publish to remote
+	// services, e.g. the branch of a git output
+	Parameters map[string]string `json:"parameters,omitempty"`
+
 	// Template to use for this output
 	Template string `json:"template,omitempty"`
+
+	// Variables overrides the runbook variables for this output
+	Variables map[string]string `json:"variables,omitempty"`
 }
 
 // RunbookStatus defines the observed state of Runbook
@@ -164,11 +281,24 @@ type RunbookStatus struct {
 	// ValidationErrors contains validation error messages
 	ValidationErrors []string `json:"validationErrors,omitempty"`
 
+	// ValidationWarnings contains validation findings that do not fail the runbook
+	ValidationWarnings []string `json:"validationWarnings,omitempty"`
+
 	// GeneratedOutputs lists the successfully generated outputs
 	GeneratedOutputs []GeneratedOutput `json:"generatedOutputs,omitempty"`
 
 	// SourceRule reference to the PrometheusRule that generated this runbook
 	SourceRule *SourceRuleRef `json:"sourceRule,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the outputs were last generated from
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ContentHash fingerprints the spec, templates and output configuration
+	// of the last successful generation. Outputs are not regenerated while it matches.
+	ContentHash string `json:"contentHash,omitempty"`
+
+	// Automation reports the triggers that matched this runbook
+	Automation *AutomationStatus `json:"automation,omitempty"`
 }
 
 // GeneratedOutput represents a successfully generated output
@@ -176,9 +306,15 @@ type GeneratedOutput struct {
 	// Format of the generated output
 	Format string `json:"format"`
 
+	// Destination the output was configured with
+	Destination string `json:"destination,omitempty"`
+
 	// Location where the output was published
 	Location string `json:"location"`
 
+	// Revision of the published document, e.g. the version of a wiki page
+	Revision string `json:"revision,omitempty"`
+
 	// Timestamp when this output was generated
 	GeneratedAt metav1.Time `json:"generatedAt"`
 }
@@ -195,6 +331,105 @@ type SourceRuleRef struct {
 	UID string `json:"uid,omitempty"`
 }
 
+// AutomationStatus reports the automation activity of a runbook
+type AutomationStatus struct {
+	// TriggerCount is the number of times automation was triggered
+	TriggerCount int64 `json:"triggerCount,omitempty"`
+
+	// LastTrigger is the most recent event that matched a trigger
+	LastTrigger *TriggerEvent `json:"lastTrigger,omitempty"`
+
+	// LastExecution is the name of the RunbookExecution started by the last trigger
+	LastExecution string `json:"lastExecution,omitempty"`
+
+	// RecentTriggers are the keys of the most recent events with a
+	// fingerprint, newest first, so that each firing of an alert or webhook
+	// delivery starts a single execution
+	RecentTriggers []string `json:"recentTriggers,omitempty"`
+
+	// ManualRun reports the last run requested with the run-now annotation
+	ManualRun *ManualRunStatus `json:"manualRun,omitempty"`
+
+	// RunningExecutions are the executions counted against MaxConcurrentExecutions
+	// +listType=set
+	RunningExecutions []string `json:"runningExecutions,omitempty"`
+
+	// RecentStarts are the start times of the executions in the last hour,
+	// counted against MaxExecutionsPerHour
+	RecentStarts []metav1.Time `json:"recentStarts,omitempty"`
+
+	// LastStartTime is when the last execution started, for the Cooldown
+	LastStartTime *metav1.Time `json:"lastStartTime,omitempty"`
+
+	// History of the most recent executions, newest first
+	History []ExecutionRecord `json:"history,omitempty"`
+}
+
+// ExecutionRecord summarizes an execution of the runbook for postmortems
+type ExecutionRecord struct {
+	// Name of the RunbookExecution
+	Name string `json:"name"`
+
+	// Trigger is the type of the trigger that started the execution, empty
+	// for executions created directly
+	Trigger string `json:"trigger,omitempty"`
+
+	// Source that delivered the trigger event
+	Source string `json:"source,omitempty"`
+
+	// TriggeredAt is when the execution was triggered
+	TriggeredAt metav1.Time `json:"triggeredAt"`
+
+	// StartTime is when the execution started running its steps
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the execution finished
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Phase is the outcome of the execution once it finished
+	Phase string `json:"phase,omitempty"`
+
+	// Message explains the outcome
+	Message string `json:"message,omitempty"`
+
+	// DryRun reports that no Jobs were created
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Approvals of the steps of the execution
+	Approvals []Approval `json:"approvals,omitempty"`
+}
+
+// ManualRunStatus reports a run requested with the run-now annotation
+type ManualRunStatus struct {
+	// Nonce is the annotation value of the run, each nonce starts a single execution
+	Nonce string `json:"nonce"`
+
+	// Execution is the name of the RunbookExecution started for the nonce
+	Execution string `json:"execution,omitempty"`
+}
+
+// TriggerEvent records an event that matched an automation trigger
+type TriggerEvent struct {
+	// Type of the trigger that matched (alert, webhook, manual)
+	Type string `json:"type"`
+
+	// Source that delivered the event, e.g. the Alertmanager receiver
+	Source string `json:"source,omitempty"`
+
+	// Fingerprint identifies the alert, repeated notifications of the same
+	// firing alert are only recorded once
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Labels of the alert or event the conditions were evaluated against
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// StartsAt is the time the alert started firing
+	StartsAt *metav1.Time `json:"startsAt,omitempty"`
+
+	// TriggeredAt is the time the event matched
+	TriggeredAt metav1.Time `json:"triggeredAt"`
+}
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Namespaced
@@ -0,0 +1,195 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Phases of a RunbookExecution and of its steps
+const (
+	ExecutionPending   = "pending"
+	ExecutionRunning   = "running"
+	ExecutionSucceeded = "succeeded"
+	ExecutionFailed    = "failed"
+	ExecutionSkipped   = "skipped"
+)
+
+// RunbookExecutionSpec defines the desired state of RunbookExecution
+type RunbookExecutionSpec struct {
+	// RunbookName is the name of the Runbook in the same namespace to execute
+	// +kubebuilder:validation:Required
+	RunbookName string `json:"runbookName"`
+
+	// Trigger is the event that started this execution
+	Trigger *TriggerEvent `json:"trigger,omitempty"`
+
+	// DryRun records the plan of the execution in its status and events
+	// without creating any Jobs. Executions of runbooks whose automation is
+	// in dry run mode are always dry runs.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Approvals of medium risk steps. An approval can only be added by the
+	// user it names, who must be allowed to approve RunbookExecutions.
+	// +listType=map
+	// +listMapKey=step
+	Approvals []Approval `json:"approvals,omitempty"`
+}
+
+// Approval allows a medium risk step of an execution to run
+type Approval struct {
+	// Step is the name of the approved step
+	// +kubebuilder:validation:Required
+	Step string `json:"step"`
+
+	// User is the name of the user that approved the step
+	// +kubebuilder:validation:Required
+	User string `json:"user"`
+
+	// Comment explains the approval
+	Comment string `json:"comment,omitempty"`
+}
+
+// RunbookExecutionStatus defines the observed state of RunbookExecution
+type RunbookExecutionStatus struct {
+	// Phase represents the current phase of the execution
+	// +kubebuilder:validation:Enum=pending;running;succeeded;failed;skipped
+	Phase string `json:"phase,omitempty"`
+
+	// Conditions represent the latest available observations
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// StartTime is when the first step was started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the execution succeeded or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Steps is the plan of the execution, captured from the Runbook when it started
+	Steps []ExecutionStep `json:"steps,omitempty"`
+
+	// Investigation summarizes the investigation checks of the execution
+	Investigation *InvestigationSummary `json:"investigation,omitempty"`
+}
+
+// InvestigationSummary reports how many investigation steps met their expectation
+type InvestigationSummary struct {
+	// Passed is the number of investigation steps that met their expectation
+	Passed int32 `json:"passed"`
+
+	// Failed is the number of investigation steps that did not
+	Failed int32 `json:"failed"`
+
+	// Summary describes the result, e.g. "2 of 3 checks passed"
+	Summary string `json:"summary,omitempty"`
+}
+
+// ExecutionStep reports a single step of an execution
+type ExecutionStep struct {
+	// Name identifies the step within the execution
+	Name string `json:"name"`
+
+	// Type of the step. Failed investigation steps are reported in the
+	// investigation summary and do not stop the execution.
+	// +kubebuilder:validation:Enum=investigation;remediation;script
+	Type string `json:"type,omitempty"`
+
+	// Description of the remediation step
+	Description string `json:"description,omitempty"`
+
+	// Command run by the step
+	Command string `json:"command,omitempty"`
+
+	// Script is the name of the script run by the step
+	Script string `json:"script,omitempty"`
+
+	// Risk level of the step
+	// +kubebuilder:validation:Enum=low;medium;high
+	Risk string `json:"risk,omitempty"`
+
+	// Expectation the output of an investigation step is checked against
+	Expectation *Expectation `json:"expectation,omitempty"`
+
+	// Phase of the step
+	// +kubebuilder:validation:Enum=pending;running;succeeded;failed;skipped
+	Phase string `json:"phase"`
+
+	// JobName is the Job that runs the step
+	JobName string `json:"jobName,omitempty"`
+
+	// ExitCode of the step container
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// Logs holds the tail of the step container logs
+	Logs string `json:"logs,omitempty"`
+
+	// Message explains the phase of the step
+	Message string `json:"message,omitempty"`
+
+	// ApprovalRequestedAt is when the step started waiting for an approval
+	ApprovalRequestedAt *metav1.Time `json:"approvalRequestedAt,omitempty"`
+
+	// ApprovedBy is the user whose approval allowed the step to run
+	ApprovedBy string `json:"approvedBy,omitempty"`
+
+	// StartTime is when the Job of the step was created
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the step finished
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Namespaced,shortName=rbe
+//+kubebuilder:printcolumn:name="Runbook",type=string,JSONPath=`.spec.runbookName`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Dry Run",type=boolean,JSONPath=`.spec.dryRun`
+//+kubebuilder:printcolumn:name="Checks",type=string,JSONPath=`.status.investigation.summary`
+//+kubebuilder:printcolumn:name="Started",type=date,JSONPath=`.status.startTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RunbookExecution is the Schema for the runbookexecutions API
+type RunbookExecution struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RunbookExecutionSpec   `json:"spec,omitempty"`
+	Status RunbookExecutionStatus `json:"status,omitempty"`
+}
+
+// Finished reports whether the execution succeeded, failed or was skipped
+func (e *RunbookExecution) Finished() bool {
+	switch e.Status.Phase {
+	case ExecutionSucceeded, ExecutionFailed, ExecutionSkipped:
+		return true
+	}
+	return false
+}
+
+//+kubebuilder:object:root=true
+
+// RunbookExecutionList contains a list of RunbookExecution
+type RunbookExecutionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RunbookExecution `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RunbookExecution{}, &RunbookExecutionList{})
+}
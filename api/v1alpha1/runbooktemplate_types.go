@@ -36,7 +36,7 @@ type RunbookTemplateSpec struct {
 	// Variables that can be used in the template
 	Variables map[string]TemplateVariable `json:"variables,omitempty"`
 
-	// OutputFormats supported by this template
+	// OutputFormats supported by this template, every format when empty
 	OutputFormats []string `json:"outputFormats,omitempty"`
 
 	// Metadata for template organization
@@ -92,6 +92,9 @@ type RunbookTemplateStatus struct {
 
 	// UsageCount tracks how many runbooks use this template
 	UsageCount int `json:"usageCount,omitempty"`
+
+	// ObservedGeneration is the generation of the template that was last loaded
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 //+kubebuilder:object:root=true
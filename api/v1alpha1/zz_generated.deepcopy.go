@@ -21,10 +21,26 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Approval) DeepCopyInto(out *Approval) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Approval.
+func (in *Approval) DeepCopy() *Approval {
+	if in == nil {
+		return nil
+	}
+	out := new(Approval)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AutomationConfig) DeepCopyInto(out *AutomationConfig) {
 	*out = *in
@@ -40,6 +56,21 @@ func (in *AutomationConfig) DeepCopyInto(out *AutomationConfig) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ApprovalTimeout != nil {
+		in, out := &in.ApprovalTimeout, &out.ApprovalTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.WebhookSecretRef != nil {
+		in, out := &in.WebhookSecretRef, &out.WebhookSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cooldown != nil {
+		in, out := &in.Cooldown, &out.Cooldown
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutomationConfig.
@@ -52,6 +83,145 @@ func (in *AutomationConfig) DeepCopy() *AutomationConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutomationStatus) DeepCopyInto(out *AutomationStatus) {
+	*out = *in
+	if in.LastTrigger != nil {
+		in, out := &in.LastTrigger, &out.LastTrigger
+		*out = new(TriggerEvent)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RecentTriggers != nil {
+		in, out := &in.RecentTriggers, &out.RecentTriggers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ManualRun != nil {
+		in, out := &in.ManualRun, &out.ManualRun
+		*out = new(ManualRunStatus)
+		**out = **in
+	}
+	if in.RunningExecutions != nil {
+		in, out := &in.RunningExecutions, &out.RunningExecutions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RecentStarts != nil {
+		in, out := &in.RecentStarts, &out.RecentStarts
+		*out = make([]v1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastStartTime != nil {
+		in, out := &in.LastStartTime, &out.LastStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]ExecutionRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutomationStatus.
+func (in *AutomationStatus) DeepCopy() *AutomationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AutomationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutionRecord) DeepCopyInto(out *ExecutionRecord) {
+	*out = *in
+	in.TriggeredAt.DeepCopyInto(&out.TriggeredAt)
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Approvals != nil {
+		in, out := &in.Approvals, &out.Approvals
+		*out = make([]Approval, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutionRecord.
+func (in *ExecutionRecord) DeepCopy() *ExecutionRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutionRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutionStep) DeepCopyInto(out *ExecutionStep) {
+	*out = *in
+	if in.Expectation != nil {
+		in, out := &in.Expectation, &out.Expectation
+		*out = new(Expectation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ApprovalRequestedAt != nil {
+		in, out := &in.ApprovalRequestedAt, &out.ApprovalRequestedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutionStep.
+func (in *ExecutionStep) DeepCopy() *ExecutionStep {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutionStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Expectation) DeepCopyInto(out *Expectation) {
+	*out = *in
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Expectation.
+func (in *Expectation) DeepCopy() *Expectation {
+	if in == nil {
+		return nil
+	}
+	out := new(Expectation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GeneratedOutput) DeepCopyInto(out *GeneratedOutput) {
 	*out = *in
@@ -71,6 +241,11 @@ func (in *GeneratedOutput) DeepCopy() *GeneratedOutput {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InvestigationStep) DeepCopyInto(out *InvestigationStep) {
 	*out = *in
+	if in.Expectation != nil {
+		in, out := &in.Expectation, &out.Expectation
+		*out = new(Expectation)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InvestigationStep.
@@ -83,9 +258,58 @@ func (in *InvestigationStep) DeepCopy() *InvestigationStep {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InvestigationSummary) DeepCopyInto(out *InvestigationSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InvestigationSummary.
+func (in *InvestigationSummary) DeepCopy() *InvestigationSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(InvestigationSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManualRunStatus) DeepCopyInto(out *ManualRunStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManualRunStatus.
+func (in *ManualRunStatus) DeepCopy() *ManualRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ManualRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OutputConfig) DeepCopyInto(out *OutputConfig) {
 	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputConfig.
@@ -161,7 +385,9 @@ func (in *RunbookContent) DeepCopyInto(out *RunbookContent) {
 	if in.Investigation != nil {
 		in, out := &in.Investigation, &out.Investigation
 		*out = make([]InvestigationStep, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Remediation != nil {
 		in, out := &in.Remediation, &out.Remediation
@@ -190,6 +416,132 @@ func (in *RunbookContent) DeepCopy() *RunbookContent {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunbookExecution) DeepCopyInto(out *RunbookExecution) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunbookExecution.
+func (in *RunbookExecution) DeepCopy() *RunbookExecution {
+	if in == nil {
+		return nil
+	}
+	out := new(RunbookExecution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunbookExecution) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunbookExecutionList) DeepCopyInto(out *RunbookExecutionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RunbookExecution, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunbookExecutionList.
+func (in *RunbookExecutionList) DeepCopy() *RunbookExecutionList {
+	if in == nil {
+		return nil
+	}
+	out := new(RunbookExecutionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunbookExecutionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunbookExecutionSpec) DeepCopyInto(out *RunbookExecutionSpec) {
+	*out = *in
+	if in.Trigger != nil {
+		in, out := &in.Trigger, &out.Trigger
+		*out = new(TriggerEvent)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Approvals != nil {
+		in, out := &in.Approvals, &out.Approvals
+		*out = make([]Approval, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunbookExecutionSpec.
+func (in *RunbookExecutionSpec) DeepCopy() *RunbookExecutionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RunbookExecutionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunbookExecutionStatus) DeepCopyInto(out *RunbookExecutionStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]ExecutionStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Investigation != nil {
+		in, out := &in.Investigation, &out.Investigation
+		*out = new(InvestigationSummary)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunbookExecutionStatus.
+func (in *RunbookExecutionStatus) DeepCopy() *RunbookExecutionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RunbookExecutionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RunbookList) DeepCopyInto(out *RunbookList) {
 	*out = *in
@@ -226,10 +578,26 @@ func (in *RunbookList) DeepCopyObject() runtime.Object {
 func (in *RunbookSpec) DeepCopyInto(out *RunbookSpec) {
 	*out = *in
 	in.Content.DeepCopyInto(&out.Content)
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExampleLabels != nil {
+		in, out := &in.ExampleLabels, &out.ExampleLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Outputs != nil {
 		in, out := &in.Outputs, &out.Outputs
 		*out = make([]OutputConfig, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -262,6 +630,11 @@ func (in *RunbookStatus) DeepCopyInto(out *RunbookStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ValidationWarnings != nil {
+		in, out := &in.ValidationWarnings, &out.ValidationWarnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.GeneratedOutputs != nil {
 		in, out := &in.GeneratedOutputs, &out.GeneratedOutputs
 		*out = make([]GeneratedOutput, len(*in))
@@ -274,6 +647,11 @@ func (in *RunbookStatus) DeepCopyInto(out *RunbookStatus) {
 		*out = new(SourceRuleRef)
 		**out = **in
 	}
+	if in.Automation != nil {
+		in, out := &in.Automation, &out.Automation
+		*out = new(AutomationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunbookStatus.
@@ -469,3 +847,30 @@ func (in *TriggerConfig) DeepCopy() *TriggerConfig {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TriggerEvent) DeepCopyInto(out *TriggerEvent) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.StartsAt != nil {
+		in, out := &in.StartsAt, &out.StartsAt
+		*out = (*in).DeepCopy()
+	}
+	in.TriggeredAt.DeepCopyInto(&out.TriggeredAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TriggerEvent.
+func (in *TriggerEvent) DeepCopy() *TriggerEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(TriggerEvent)
+	in.DeepCopyInto(out)
+	return out
+}
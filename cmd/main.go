@@ -17,8 +17,12 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -26,6 +30,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -34,8 +39,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
 	"github.com/guibes/runbook-operator/internal/controller"
+	"github.com/guibes/runbook-operator/internal/receiver"
+	webhookv1alpha1 "github.com/guibes/runbook-operator/internal/webhook/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/audit"
 	"github.com/guibes/runbook-operator/pkg/generator"
+	"github.com/guibes/runbook-operator/pkg/outputs"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -54,8 +64,38 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var automationAddr string
+	var automationImage string
+	var automationServiceAccount string
+	var automationServiceAccounts string
+	var auditLog string
+	var alertmanagerTokenFile string
+	var configMapNamespaces string
+	var s3AmbientCredentials bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&automationAddr, "automation-bind-address", ":8082", "The address the automation receiver "+
+		"endpoints, such as the Alertmanager webhook, bind to. Use 0 to disable the receiver.")
+	flag.StringVar(&automationImage, "automation-image", "bitnami/kubectl:1.33.3",
+		"The default container image automated runbook steps run in.")
+	flag.StringVar(&automationServiceAccount, "automation-service-account", "",
+		"The default ServiceAccount automated runbook steps run as, in the namespace of the runbook. "+
+			"Defaults to the namespace default ServiceAccount.")
+	flag.StringVar(&automationServiceAccounts, "automation-service-accounts", "",
+		"Comma separated ServiceAccounts runbooks may run their automated steps as besides the default one. "+
+			"Any runbook author can run commands with the permissions of these ServiceAccounts.")
+	flag.StringVar(&alertmanagerTokenFile, "alertmanager-token-file", "",
+		"The file holding the bearer token Alertmanager authenticates to the automation receiver with, "+
+			"e.g. from a mounted Secret. The Alertmanager endpoint is disabled when empty.")
+	flag.StringVar(&auditLog, "audit-log", "",
+		"The file the audit log of automated actions is appended to as JSON lines, or - for stdout. "+
+			"Empty disables the audit log.")
+	flag.StringVar(&configMapNamespaces, "configmap-output-namespaces", "",
+		"Comma separated namespaces configmap outputs may write to besides the namespace of their runbook. "+
+			"Empty restricts them to the namespace of the runbook.")
+	flag.BoolVar(&s3AmbientCredentials, "s3-ambient-credentials", false,
+		"Let s3 outputs without a secretRef use the AWS environment variables or instance role of the operator. "+
+			"Any runbook author can then write to and delete from the buckets the operator can reach.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -84,27 +124,137 @@ func main() {
 		os.Exit(1)
 	}
 
+	auditSink := audit.Discard
+	if auditLog != "" {
+		auditFile, err := audit.Open(auditLog)
+		if err != nil {
+			setupLog.Error(err, "unable to open audit log", "path", auditLog)
+			os.Exit(1)
+		}
+		defer auditFile.Close() //nolint:errcheck
+		auditSink = auditFile
+	}
+
 	// Initialize the runbook generator
 	runbookGenerator := generator.NewRunbookGenerator()
 
 	// Setup controllers
 	if err = (&controller.RunbookReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		Generator:            runbookGenerator,
+		Recorder:             mgr.GetEventRecorderFor("runbook-controller"),
+		Audit:                auditSink,
+		APIReader:            mgr.GetAPIReader(),
+		ConfigMapNamespaces:  splitList(configMapNamespaces),
+		S3AmbientCredentials: s3AmbientCredentials,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Runbook")
+		os.Exit(1)
+	}
+
+	// Push the pending changes of git outputs when the manager stops
+	if err := mgr.Add(outputs.DefaultGitPublisher); err != nil {
+		setupLog.Error(err, "unable to set up git output publisher")
+		os.Exit(1)
+	}
+
+	if err = (&controller.RunbookTemplateReconciler{
 		Client:    mgr.GetClient(),
 		Scheme:    mgr.GetScheme(),
 		Generator: runbookGenerator,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Runbook")
+		setupLog.Error(err, "unable to create controller", "controller", "RunbookTemplate")
 		os.Exit(1)
 	}
 
-	if err = (&controller.RunbookTemplateReconciler{
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create clientset")
+		os.Exit(1)
+	}
+	if err = (&controller.RunbookExecutionReconciler{
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		Clientset:             clientset,
+		Recorder:              mgr.GetEventRecorderFor("runbookexecution-controller"),
+		DefaultImage:          automationImage,
+		DefaultServiceAccount: automationServiceAccount,
+		ServiceAccounts:       splitList(automationServiceAccounts),
+		Audit:                 auditSink,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RunbookExecution")
+		os.Exit(1)
+	}
+
+	// PrometheusRule support is optional, the prometheus-operator CRDs may not be installed
+	if _, err := mgr.GetRESTMapper().RESTMapping(
+		controller.PrometheusRuleGVK.GroupKind(), controller.PrometheusRuleGVK.Version); err != nil {
+		setupLog.Info("PrometheusRule CRD not found, runbooks will not be generated from PrometheusRules")
+	} else if err = (&controller.PrometheusRuleReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "RunbookTemplate")
+		setupLog.Error(err, "unable to create controller", "controller", "PrometheusRule")
 		os.Exit(1)
 	}
 
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookv1alpha1.SetupRunbookWebhookWithManager(mgr, splitList(configMapNamespaces),
+			automationServiceAccount, splitList(automationServiceAccounts)); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Runbook")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupRunbookTemplateWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "RunbookTemplate")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupRunbookExecutionWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "RunbookExecution")
+			os.Exit(1)
+		}
+	}
+
+	// Serve the endpoints that trigger runbook automation
+	if automationAddr != "0" {
+		if err := automation.IndexAlertName(context.Background(), mgr.GetFieldIndexer()); err != nil {
+			setupLog.Error(err, "unable to index runbooks by alert name")
+			os.Exit(1)
+		}
+		var alertmanagerToken string
+		if alertmanagerTokenFile != "" {
+			token, err := os.ReadFile(alertmanagerTokenFile)
+			if err == nil && len(bytes.TrimSpace(token)) == 0 {
+				err = errors.New("the token file is empty")
+			}
+			if err != nil {
+				setupLog.Error(err, "unable to read the Alertmanager token", "file", alertmanagerTokenFile)
+				os.Exit(1)
+			}
+			alertmanagerToken = string(bytes.TrimSpace(token))
+		} else {
+			setupLog.Info("Alertmanager endpoint disabled, set --alertmanager-token-file to enable alert triggers")
+		}
+		automationReceiver := &receiver.Receiver{
+			Client:            mgr.GetClient(),
+			APIReader:         mgr.GetAPIReader(),
+			AlertmanagerToken: alertmanagerToken,
+			Dispatcher: &automation.Dispatcher{
+				Client:   mgr.GetClient(),
+				Recorder: mgr.GetEventRecorderFor("runbook-automation"),
+				Audit:    auditSink,
+			},
+		}
+		if err := mgr.Add(&receiver.Server{
+			BindAddress: automationAddr,
+			Handler:     automationReceiver.Handler(),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up automation receiver")
+			os.Exit(1)
+		}
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -122,3 +272,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList returns the non-empty items of a comma separated flag value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
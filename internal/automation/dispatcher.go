@@ -0,0 +1,324 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package automation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/audit"
+	"github.com/guibes/runbook-operator/pkg/triggers"
+)
+
+// Trigger types of TriggerConfig
+const (
+	TriggerAlert   = "alert"
+	TriggerWebhook = "webhook"
+	TriggerManual  = "manual"
+)
+
+// Labels set on the RunbookExecutions and Jobs started for a runbook
+const (
+	RunbookLabel   = "runbook.runbook.io/runbook"
+	ExecutionLabel = "runbook.runbook.io/execution"
+)
+
+// RunNowAnnotation starts a manual run of a Runbook once for every new value
+const RunNowAnnotation = "runbook.runbook.io/run-now"
+
+// RunNowByAnnotation is the user that set the run-now annotation, recorded by
+// the Runbook admission webhook
+const RunNowByAnnotation = "runbook.runbook.io/run-now-by"
+
+// RequestedByAnnotation is the user an execution was started for, recorded by
+// the RunbookExecution admission webhook. Only users with the dispatch verb
+// on runbookexecutions, such as the operator, may name another user.
+const RequestedByAnnotation = "runbook.runbook.io/requested-by"
+
+//+kubebuilder:rbac:groups=runbook.runbook.io,resources=runbookexecutions,verbs=dispatch
+
+// AlertNameField indexes Runbooks by spec.alertName so alerts can be matched without listing every runbook
+const AlertNameField = "spec.alertName"
+
+// IndexAlertName registers the AlertNameField index with the manager cache
+func IndexAlertName(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &runbookv1alpha1.Runbook{}, AlertNameField, AlertNameIndex)
+}
+
+// AlertNameIndex extracts the AlertNameField index value of a Runbook
+func AlertNameIndex(obj client.Object) []string {
+	runbook := obj.(*runbookv1alpha1.Runbook)
+	if runbook.Spec.AlertName == "" {
+		return nil
+	}
+	return []string{runbook.Spec.AlertName}
+}
+
+// MatchTrigger returns the first trigger of the given type whose conditions
+// hold for the values, or nil when automation is disabled or nothing matched
+func MatchTrigger(runbook *runbookv1alpha1.Runbook, triggerType string, values triggers.Values) (*runbookv1alpha1.TriggerConfig, error) {
+	automation := runbook.Spec.Content.Automation
+	if automation == nil || !automation.Enabled {
+		return nil, nil
+	}
+
+	for i := range automation.Triggers {
+		trigger := &automation.Triggers[i]
+		if trigger.Type != triggerType {
+			continue
+		}
+		matched, err := triggers.Match(trigger.Conditions, values)
+		if err != nil {
+			return nil, fmt.Errorf("trigger %d of runbook %s/%s: %w", i, runbook.Namespace, runbook.Name, err)
+		}
+		if matched {
+			return trigger, nil
+		}
+	}
+	return nil, nil
+}
+
+// Reasons an execution is not allowed by the automation of its runbook
+const (
+	ReasonAutomationDisabled = "AutomationDisabled"
+	ReasonNoManualTrigger    = "NoManualTrigger"
+)
+
+// CheckAllowed returns the reason the runbook does not allow the execution,
+// or an empty reason when it does. Executions without a trigger are started
+// by hand, so like the run-now annotation they need a manual trigger whose
+// conditions hold for the runbook labels.
+func CheckAllowed(runbook *runbookv1alpha1.Runbook, execution *runbookv1alpha1.RunbookExecution) (string, string) {
+	if config := runbook.Spec.Content.Automation; config == nil || !config.Enabled {
+		return ReasonAutomationDisabled, fmt.Sprintf("Automation is disabled for runbook %s", runbook.Name)
+	}
+	if trigger := execution.Spec.Trigger; trigger != nil && trigger.Type != TriggerManual {
+		return "", ""
+	}
+
+	trigger, err := MatchTrigger(runbook, TriggerManual, triggers.Labels(runbook.Labels))
+	if err != nil {
+		return ReasonNoManualTrigger, err.Error()
+	}
+	if trigger == nil {
+		return ReasonNoManualTrigger, fmt.Sprintf("No manual trigger of runbook %s matches its labels", runbook.Name)
+	}
+	return "", ""
+}
+
+// Dispatcher starts a RunbookExecution for trigger events and records them on the Runbook
+type Dispatcher struct {
+	Client   client.Client
+	Recorder record.EventRecorder
+
+	// Audit records the triggers in the audit log
+	Audit audit.Sink
+}
+
+// Dispatch starts an execution of the runbook and records the event in the
+// runbook status. It returns false when the event was already recorded, e.g.
+// for a repeated Alertmanager notification.
+func (d *Dispatcher) Dispatch(ctx context.Context, runbook *runbookv1alpha1.Runbook, event runbookv1alpha1.TriggerEvent) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	recorded := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := d.Client.Get(ctx, client.ObjectKeyFromObject(runbook), runbook); err != nil {
+			return err
+		}
+		key, err := eventKey(&event)
+		if err != nil {
+			return err
+		}
+		if isRecorded(runbook.Status.Automation, &event, key) {
+			recorded = false
+			return nil
+		}
+
+		execution, err := d.createExecution(ctx, runbook, &event, key)
+		if err != nil {
+			return err
+		}
+
+		// Concurrent deliveries must not lose trigger counts, so the patch
+		// fails on conflict and is retried against the latest status
+		original := runbook.DeepCopy()
+		if runbook.Status.Automation == nil {
+			runbook.Status.Automation = &runbookv1alpha1.AutomationStatus{}
+		}
+		runbook.Status.Automation.TriggerCount++
+		runbook.Status.Automation.LastTrigger = event.DeepCopy()
+		runbook.Status.Automation.LastExecution = execution.Name
+		if event.Fingerprint != "" {
+			recent := append([]string{key}, runbook.Status.Automation.RecentTriggers...)
+			runbook.Status.Automation.RecentTriggers = recent[:min(len(recent), maxRecentTriggers)]
+		}
+		RecordExecution(runbook.Status.Automation, NewExecutionRecord(execution),
+			HistoryLimit(runbook.Spec.Content.Automation))
+		if event.Type == TriggerManual {
+			runbook.Status.Automation.ManualRun = &runbookv1alpha1.ManualRunStatus{
+				Nonce:     event.Fingerprint,
+				Execution: execution.Name,
+			}
+		}
+
+		recorded = true
+		return d.Client.Status().Patch(ctx, runbook, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+	})
+	if err != nil {
+		return false, err
+	}
+	if !recorded {
+		logger.V(1).Info("Trigger already recorded", "runbook", runbook.Name, "fingerprint", event.Fingerprint)
+		return false, nil
+	}
+
+	logger.Info("Automation triggered", "runbook", runbook.Name, "trigger", event.Type, "source", event.Source,
+		"execution", runbook.Status.Automation.LastExecution)
+	d.Recorder.Eventf(runbook, corev1.EventTypeNormal, "AutomationTriggered",
+		"Automation triggered by %s %s, started execution %s", event.Type, event.Source, runbook.Status.Automation.LastExecution)
+	if err := d.auditSink().Record(audit.Event{
+		Action:      audit.ActionTriggered,
+		Namespace:   runbook.Namespace,
+		Runbook:     runbook.Name,
+		Execution:   runbook.Status.Automation.LastExecution,
+		Trigger:     event.Type,
+		Source:      event.Source,
+		RequestedBy: requestedBy(runbook, &event),
+		Labels:      event.Labels,
+	}); err != nil {
+		logger.Error(err, "Failed to write audit event", "runbook", runbook.Name)
+	}
+	return true, nil
+}
+
+func (d *Dispatcher) auditSink() audit.Sink {
+	if d.Audit != nil {
+		return d.Audit
+	}
+	return audit.Discard
+}
+
+// createExecution creates the RunbookExecution for the event. The name is
+// derived from the event key so that retries of a dispatch and repeated
+// notifications do not start it twice.
+func (d *Dispatcher) createExecution(ctx context.Context, runbook *runbookv1alpha1.Runbook, event *runbookv1alpha1.TriggerEvent, key string) (*runbookv1alpha1.RunbookExecution, error) {
+	execution := &runbookv1alpha1.RunbookExecution{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      executionName(runbook.Name, key),
+			Namespace: runbook.Namespace,
+			Labels:    map[string]string{RunbookLabel: runbook.Name},
+		},
+		Spec: runbookv1alpha1.RunbookExecutionSpec{
+			RunbookName: runbook.Name,
+			Trigger:     event.DeepCopy(),
+			DryRun:      runbook.Spec.Content.Automation != nil && runbook.Spec.Content.Automation.DryRun,
+		},
+	}
+	if user := requestedBy(runbook, event); user != "" {
+		execution.Annotations = map[string]string{RequestedByAnnotation: user}
+	}
+	if err := controllerutil.SetControllerReference(runbook, execution, d.Client.Scheme()); err != nil {
+		return nil, err
+	}
+
+	if err := d.Client.Create(ctx, execution); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create RunbookExecution: %w", err)
+	}
+	return execution, nil
+}
+
+// requestedBy is the user that set the run-now annotation of a manual run
+func requestedBy(runbook *runbookv1alpha1.Runbook, event *runbookv1alpha1.TriggerEvent) string {
+	if event.Type != TriggerManual {
+		return ""
+	}
+	return runbook.Annotations[RunNowByAnnotation]
+}
+
+// maxRecentTriggers bounds the event keys kept in the runbook status
+const maxRecentTriggers = 50
+
+// eventKey identifies an event. Events with a fingerprint, such as the
+// firing of an alert, a webhook delivery or a manual run, are identified by
+// their type, fingerprint and start time only, so that notifications
+// repeated later map to the same key. Other events are identified by their
+// content.
+func eventKey(event *runbookv1alpha1.TriggerEvent) (string, error) {
+	var data []byte
+	if event.Fingerprint != "" {
+		startsAt := ""
+		if event.StartsAt != nil {
+			startsAt = event.StartsAt.UTC().Format(time.RFC3339)
+		}
+		data = []byte(strings.Join([]string{event.Type, event.Fingerprint, startsAt}, "\x00"))
+	} else {
+		var err error
+		if data, err = json.Marshal(event); err != nil {
+			return "", err
+		}
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
+// executionName appends the event key to the runbook name, keeping it a valid object name
+func executionName(runbookName string, key string) string {
+	suffix := key[:10]
+
+	// Leave room for the step index appended to the Job names
+	maxPrefix := 52 - len(suffix) - 1
+	if len(runbookName) > maxPrefix {
+		runbookName = strings.TrimRight(runbookName[:maxPrefix], "-.")
+	}
+	return runbookName + "-" + suffix
+}
+
+// isRecorded reports whether an execution was already started for the
+// event. Manual runs are recorded once per nonce, carried in the
+// fingerprint, even when other triggers matched since.
+func isRecorded(status *runbookv1alpha1.AutomationStatus, event *runbookv1alpha1.TriggerEvent, key string) bool {
+	if status == nil || event.Fingerprint == "" {
+		return false
+	}
+	if event.Type == TriggerManual {
+		return status.ManualRun != nil && status.ManualRun.Nonce == event.Fingerprint
+	}
+	if slices.Contains(status.RecentTriggers, key) {
+		return true
+	}
+
+	// Runbooks triggered before RecentTriggers was recorded only have the last trigger
+	last := status.LastTrigger
+	return last != nil && last.Type == event.Type && last.Fingerprint == event.Fingerprint && last.StartsAt.Equal(event.StartsAt)
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package automation
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/audit"
+)
+
+// auditLog keeps the recorded audit events in memory
+type auditLog []audit.Event
+
+func (l *auditLog) Record(event audit.Event) error {
+	*l = append(*l, event)
+	return nil
+}
+
+var _ = Describe("Dispatcher", func() {
+	ctx := context.Background()
+
+	var (
+		c          client.Client
+		events     *auditLog
+		dispatcher *Dispatcher
+		runbook    *runbookv1alpha1.Runbook
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(runbookv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		runbook = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "api-errors",
+				Namespace: "payments",
+				UID:       "0b7c5d2e-4a2f-4c3b-9d1e-8f6a7b5c4d3e",
+				Annotations: map[string]string{
+					RunNowAnnotation:   "1",
+					RunNowByAnnotation: "alice",
+				},
+			},
+			Spec: runbookv1alpha1.RunbookSpec{
+				AlertName: "APIErrors",
+				Content: runbookv1alpha1.RunbookContent{
+					Automation: &runbookv1alpha1.AutomationConfig{Enabled: true},
+				},
+			},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(runbook.DeepCopy()).
+			WithStatusSubresource(&runbookv1alpha1.Runbook{}).
+			Build()
+		events = &auditLog{}
+		dispatcher = &Dispatcher{Client: c, Recorder: record.NewFakeRecorder(10), Audit: events}
+	})
+
+	executions := func() []runbookv1alpha1.RunbookExecution {
+		list := &runbookv1alpha1.RunbookExecutionList{}
+		Expect(c.List(ctx, list, client.InNamespace("payments"))).To(Succeed())
+		return list.Items
+	}
+
+	It("should record the user that started a manual run", func() {
+		dispatched, err := dispatcher.Dispatch(ctx, runbook, runbookv1alpha1.TriggerEvent{
+			Type:        TriggerManual,
+			Source:      RunNowAnnotation,
+			Fingerprint: "1",
+			TriggeredAt: metav1.Now(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dispatched).To(BeTrue())
+
+		Expect(executions()).To(HaveLen(1))
+		Expect(executions()[0].Annotations).To(HaveKeyWithValue(RequestedByAnnotation, "alice"))
+		Expect(*events).To(HaveLen(1))
+		Expect((*events)[0].Action).To(Equal(audit.ActionTriggered))
+		Expect((*events)[0].RequestedBy).To(Equal("alice"))
+	})
+
+	It("should not name a user for alerts", func() {
+		dispatched, err := dispatcher.Dispatch(ctx, runbook, runbookv1alpha1.TriggerEvent{
+			Type:        TriggerAlert,
+			Source:      "APIErrors",
+			Fingerprint: "4f2a9c",
+			TriggeredAt: metav1.Now(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dispatched).To(BeTrue())
+
+		Expect(executions()).To(HaveLen(1))
+		Expect(executions()[0].Annotations).NotTo(HaveKey(RequestedByAnnotation))
+		Expect((*events)[0].RequestedBy).To(BeEmpty())
+	})
+})
+
+var _ = Describe("CheckAllowed", func() {
+	var runbook *runbookv1alpha1.Runbook
+
+	BeforeEach(func() {
+		runbook = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-errors", Namespace: "payments", Labels: map[string]string{"team": "payments"}},
+			Spec: runbookv1alpha1.RunbookSpec{
+				Content: runbookv1alpha1.RunbookContent{
+					Automation: &runbookv1alpha1.AutomationConfig{
+						Enabled:  true,
+						Triggers: []runbookv1alpha1.TriggerConfig{{Type: TriggerManual, Conditions: []string{"team=payments"}}},
+					},
+				},
+			},
+		}
+	})
+
+	execution := func(trigger *runbookv1alpha1.TriggerEvent) *runbookv1alpha1.RunbookExecution {
+		return &runbookv1alpha1.RunbookExecution{Spec: runbookv1alpha1.RunbookExecutionSpec{RunbookName: "api-errors", Trigger: trigger}}
+	}
+
+	It("should allow executions without a trigger when a manual trigger matches", func() {
+		reason, _ := CheckAllowed(runbook, execution(nil))
+		Expect(reason).To(BeEmpty())
+	})
+
+	It("should not allow any execution when automation is disabled", func() {
+		runbook.Spec.Content.Automation.Enabled = false
+		for _, trigger := range []*runbookv1alpha1.TriggerEvent{nil, {Type: TriggerManual}, {Type: TriggerAlert}} {
+			reason, _ := CheckAllowed(runbook, execution(trigger))
+			Expect(reason).To(Equal(ReasonAutomationDisabled))
+		}
+
+		runbook.Spec.Content.Automation = nil
+		reason, _ := CheckAllowed(runbook, execution(nil))
+		Expect(reason).To(Equal(ReasonAutomationDisabled))
+	})
+
+	It("should require a matching manual trigger for manual executions", func() {
+		runbook.Labels["team"] = "checkout"
+		reason, message := CheckAllowed(runbook, execution(&runbookv1alpha1.TriggerEvent{Type: TriggerManual}))
+		Expect(reason).To(Equal(ReasonNoManualTrigger))
+		Expect(message).To(ContainSubstring("No manual trigger of runbook api-errors"))
+
+		By("leaving the conditions of alert triggers to the dispatcher")
+		reason, _ = CheckAllowed(runbook, execution(&runbookv1alpha1.TriggerEvent{Type: TriggerAlert}))
+		Expect(reason).To(BeEmpty())
+	})
+})
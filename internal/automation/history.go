@@ -0,0 +1,109 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package automation
+
+import (
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// defaultHistoryLimit is how many executions the history keeps when the runbook does not set a limit
+const defaultHistoryLimit = 10
+
+// HistoryLimit returns how many executions the history of the runbook keeps
+func HistoryLimit(config *runbookv1alpha1.AutomationConfig) int {
+	if config == nil || config.HistoryLimit <= 0 {
+		return defaultHistoryLimit
+	}
+	return int(config.HistoryLimit)
+}
+
+// NewExecutionRecord summarizes the execution for the runbook history
+func NewExecutionRecord(execution *runbookv1alpha1.RunbookExecution) runbookv1alpha1.ExecutionRecord {
+	record := runbookv1alpha1.ExecutionRecord{
+		Name:           execution.Name,
+		TriggeredAt:    execution.CreationTimestamp,
+		StartTime:      execution.Status.StartTime,
+		CompletionTime: execution.Status.CompletionTime,
+		Phase:          execution.Status.Phase,
+		DryRun:         execution.Spec.DryRun,
+	}
+	if record.Phase == "" {
+		record.Phase = runbookv1alpha1.ExecutionPending
+	}
+	if trigger := execution.Spec.Trigger; trigger != nil {
+		record.Trigger = trigger.Type
+		record.Source = trigger.Source
+		record.TriggeredAt = trigger.TriggeredAt
+	}
+	for _, approval := range execution.Spec.Approvals {
+		record.Approvals = append(record.Approvals, *approval.DeepCopy())
+	}
+
+	// The message of a running execution changes with every step
+	if record.CompletionTime != nil {
+		if condition := meta.FindStatusCondition(execution.Status.Conditions, "Succeeded"); condition != nil {
+			record.Message = condition.Message
+			// Executions of runbooks in dry run mode are dry runs too
+			record.DryRun = record.DryRun || condition.Reason == "DryRun"
+		}
+	}
+	return record
+}
+
+// RecordExecution adds the record to the history, or replaces the record of
+// the same execution, and keeps the newest limit records. It reports whether
+// the history changed.
+func RecordExecution(status *runbookv1alpha1.AutomationStatus, record runbookv1alpha1.ExecutionRecord, limit int) bool {
+	history := slices.DeleteFunc(slices.Clone(status.History), func(existing runbookv1alpha1.ExecutionRecord) bool {
+		return existing.Name == record.Name
+	})
+	history = append(history, record)
+	slices.SortStableFunc(history, func(a, b runbookv1alpha1.ExecutionRecord) int {
+		if !a.TriggeredAt.Equal(&b.TriggeredAt) {
+			return b.TriggeredAt.Compare(a.TriggeredAt.Time)
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	if len(history) > limit {
+		history = history[:limit]
+	}
+
+	if equality.Semantic.DeepEqual(history, status.History) {
+		return false
+	}
+	status.History = history
+	return true
+}
+
+// FindRecord returns the history record of the execution, or nil
+func FindRecord(status *runbookv1alpha1.AutomationStatus, execution string) *runbookv1alpha1.ExecutionRecord {
+	if status == nil {
+		return nil
+	}
+	for i := range status.History {
+		if status.History[i].Name == execution {
+			return &status.History[i]
+		}
+	}
+	return nil
+}
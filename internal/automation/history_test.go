@@ -0,0 +1,131 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package automation
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+var _ = Describe("History", func() {
+	base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	record := func(name string, minute int) runbookv1alpha1.ExecutionRecord {
+		return runbookv1alpha1.ExecutionRecord{
+			Name:        name,
+			Phase:       runbookv1alpha1.ExecutionPending,
+			TriggeredAt: metav1.NewTime(base.Add(time.Duration(minute) * time.Minute)),
+		}
+	}
+
+	names := func(status *runbookv1alpha1.AutomationStatus) []string {
+		var result []string
+		for _, record := range status.History {
+			result = append(result, record.Name)
+		}
+		return result
+	}
+
+	It("should default the history limit", func() {
+		Expect(HistoryLimit(nil)).To(Equal(10))
+		Expect(HistoryLimit(&runbookv1alpha1.AutomationConfig{})).To(Equal(10))
+		Expect(HistoryLimit(&runbookv1alpha1.AutomationConfig{HistoryLimit: 3})).To(Equal(3))
+	})
+
+	It("should summarize an execution", func() {
+		triggeredAt := metav1.NewTime(base)
+		completedAt := metav1.NewTime(base.Add(time.Minute))
+		execution := &runbookv1alpha1.RunbookExecution{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-errors-1", CreationTimestamp: metav1.NewTime(base.Add(time.Second))},
+			Spec: runbookv1alpha1.RunbookExecutionSpec{
+				Trigger:   &runbookv1alpha1.TriggerEvent{Type: TriggerAlert, Source: "APIErrors", TriggeredAt: triggeredAt},
+				Approvals: []runbookv1alpha1.Approval{{Step: "remediation-0", User: "alice"}},
+			},
+			Status: runbookv1alpha1.RunbookExecutionStatus{
+				Phase:          runbookv1alpha1.ExecutionSucceeded,
+				CompletionTime: &completedAt,
+				Conditions: []metav1.Condition{
+					{Type: "Succeeded", Status: metav1.ConditionTrue, Reason: "DryRun", Message: "All steps printed"},
+				},
+			},
+		}
+
+		summary := NewExecutionRecord(execution)
+		Expect(summary.Name).To(Equal("api-errors-1"))
+		Expect(summary.Trigger).To(Equal(TriggerAlert))
+		Expect(summary.Source).To(Equal("APIErrors"))
+		Expect(summary.TriggeredAt).To(Equal(triggeredAt))
+		Expect(summary.Phase).To(Equal(runbookv1alpha1.ExecutionSucceeded))
+		Expect(summary.Message).To(Equal("All steps printed"))
+		Expect(summary.DryRun).To(BeTrue())
+		Expect(summary.Approvals).To(Equal(execution.Spec.Approvals))
+
+		By("Treating a new execution as pending and leaving out the message of a running one")
+		execution.Status.Phase = ""
+		execution.Status.CompletionTime = nil
+		summary = NewExecutionRecord(execution)
+		Expect(summary.Phase).To(Equal(runbookv1alpha1.ExecutionPending))
+		Expect(summary.Message).To(BeEmpty())
+		Expect(summary.DryRun).To(BeFalse())
+	})
+
+	It("should keep the newest records first", func() {
+		status := &runbookv1alpha1.AutomationStatus{}
+		Expect(RecordExecution(status, record("run-2", 2), 3)).To(BeTrue())
+		Expect(RecordExecution(status, record("run-1", 1), 3)).To(BeTrue())
+		Expect(RecordExecution(status, record("run-3", 3), 3)).To(BeTrue())
+		Expect(names(status)).To(Equal([]string{"run-3", "run-2", "run-1"}))
+
+		By("Dropping the oldest record beyond the limit")
+		Expect(RecordExecution(status, record("run-4", 4), 3)).To(BeTrue())
+		Expect(names(status)).To(Equal([]string{"run-4", "run-3", "run-2"}))
+
+		By("Not adding a record older than the whole history")
+		Expect(RecordExecution(status, record("run-0", 0), 3)).To(BeFalse())
+		Expect(names(status)).To(Equal([]string{"run-4", "run-3", "run-2"}))
+	})
+
+	It("should replace the record of the same execution", func() {
+		status := &runbookv1alpha1.AutomationStatus{}
+		RecordExecution(status, record("run-1", 1), 10)
+		RecordExecution(status, record("run-2", 2), 10)
+
+		updated := record("run-1", 1)
+		updated.Phase = runbookv1alpha1.ExecutionSucceeded
+		Expect(RecordExecution(status, updated, 10)).To(BeTrue())
+		Expect(names(status)).To(Equal([]string{"run-2", "run-1"}))
+		Expect(FindRecord(status, "run-1").Phase).To(Equal(runbookv1alpha1.ExecutionSucceeded))
+
+		By("Reporting an unchanged record")
+		Expect(RecordExecution(status, updated, 10)).To(BeFalse())
+	})
+
+	It("should find records by execution", func() {
+		status := &runbookv1alpha1.AutomationStatus{}
+		RecordExecution(status, record("run-1", 1), 10)
+
+		Expect(FindRecord(status, "run-1")).NotTo(BeNil())
+		Expect(FindRecord(status, "run-2")).To(BeNil())
+		Expect(FindRecord(nil, "run-1")).To(BeNil())
+	})
+})
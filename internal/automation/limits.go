@@ -0,0 +1,100 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package automation
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// Reasons an execution is held back by the limits of its runbook
+const (
+	ReasonConcurrencyLimited = "ConcurrencyLimited"
+	ReasonCoolingDown        = "CoolingDown"
+	ReasonHourlyLimit        = "HourlyLimitReached"
+)
+
+// HasLimits reports whether the automation limits the executions of the runbook
+func HasLimits(config *runbookv1alpha1.AutomationConfig) bool {
+	if config == nil {
+		return false
+	}
+	return config.MaxConcurrentExecutions > 0 || config.MaxExecutionsPerHour > 0 ||
+		(config.Cooldown != nil && config.Cooldown.Duration > 0)
+}
+
+// CheckLimits returns the reason another execution cannot start at now, or
+// an empty reason when it can
+func CheckLimits(config *runbookv1alpha1.AutomationConfig, status *runbookv1alpha1.AutomationStatus, now time.Time) (string, string) {
+	if config == nil || status == nil {
+		return "", ""
+	}
+
+	if limit := int(config.MaxConcurrentExecutions); limit > 0 && len(status.RunningExecutions) >= limit {
+		return ReasonConcurrencyLimited, fmt.Sprintf("%d of %d executions are running", len(status.RunningExecutions), limit)
+	}
+
+	if config.Cooldown != nil && status.LastStartTime != nil &&
+		now.Before(status.LastStartTime.Add(config.Cooldown.Duration)) {
+		return ReasonCoolingDown, fmt.Sprintf("The last execution started less than %s ago", config.Cooldown.Duration)
+	}
+
+	if limit := int(config.MaxExecutionsPerHour); limit > 0 {
+		if started := len(recentStarts(status.RecentStarts, now)); started >= limit {
+			return ReasonHourlyLimit, fmt.Sprintf("%d executions started in the last hour, the limit is %d", started, limit)
+		}
+	}
+	return "", ""
+}
+
+// RecordStart counts the execution against the limits of the runbook
+func RecordStart(status *runbookv1alpha1.AutomationStatus, execution string, now time.Time) {
+	start := metav1.NewTime(now)
+	if !slices.Contains(status.RunningExecutions, execution) {
+		status.RunningExecutions = append(status.RunningExecutions, execution)
+	}
+	status.RecentStarts = append(recentStarts(status.RecentStarts, now), start)
+	status.LastStartTime = &start
+}
+
+// ReleaseExecution stops counting the execution against MaxConcurrentExecutions
+// and reports whether it was counted
+func ReleaseExecution(status *runbookv1alpha1.AutomationStatus, execution string) bool {
+	if status == nil || !slices.Contains(status.RunningExecutions, execution) {
+		return false
+	}
+	status.RunningExecutions = slices.DeleteFunc(status.RunningExecutions, func(name string) bool {
+		return name == execution
+	})
+	return true
+}
+
+// recentStarts drops the start times older than an hour
+func recentStarts(starts []metav1.Time, now time.Time) []metav1.Time {
+	recent := []metav1.Time{}
+	for _, start := range starts {
+		if now.Sub(start.Time) < time.Hour {
+			recent = append(recent, start)
+		}
+	}
+	return recent
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package automation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CheckServiceAccount reports whether automation steps may run as the
+// ServiceAccount a runbook names. Other ServiceAccounts than the default one
+// must be allowed by the operator, so that runbook authors cannot use it to
+// run commands with the permissions of any ServiceAccount of their namespace.
+func CheckServiceAccount(name, defaultName string, allowed []string) error {
+	if name == "" || name == defaultName || slices.Contains(allowed, name) {
+		return nil
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("automation steps can only run as the default ServiceAccount, not as %s", name)
+	}
+	return fmt.Errorf("automation steps can only run as the default ServiceAccount or as %s, not as %s",
+		strings.Join(allowed, ", "), name)
+}
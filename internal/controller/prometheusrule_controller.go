@@ -0,0 +1,294 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// PrometheusRuleGVK is the GroupVersionKind of the prometheus-operator PrometheusRule
+var PrometheusRuleGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "PrometheusRule",
+}
+
+// PrometheusRuleReconciler generates Runbooks from PrometheusRule objects
+type PrometheusRuleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// alertRule is the subset of a PrometheusRule alerting rule used for generation
+type alertRule struct {
+	Alert       string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules,verbs=get;list;watch
+
+// Reconcile creates or updates one Runbook per alerting rule and removes
+// Runbooks whose alert is no longer part of the PrometheusRule
+func (r *PrometheusRuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	rule := newPrometheusRule()
+	if err := r.Get(ctx, req.NamespacedName, rule); err != nil {
+		if errors.IsNotFound(err) {
+			// Owned Runbooks are garbage collected through their owner reference
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get PrometheusRule")
+		return ctrl.Result{}, err
+	}
+
+	if rule.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	alerts, err := alertRulesFromPrometheusRule(rule)
+	if err != nil {
+		logger.Error(err, "Failed to parse PrometheusRule")
+		return ctrl.Result{}, err
+	}
+
+	wanted := make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		name := runbookNameForAlert(rule.GetName(), alert.Alert)
+		wanted[name] = true
+
+		if err := r.reconcileAlertRunbook(ctx, rule, name, alert); err != nil {
+			logger.Error(err, "Failed to reconcile runbook for alert", "alert", alert.Alert)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.deleteStaleRunbooks(ctx, rule, wanted); err != nil {
+		logger.Error(err, "Failed to delete stale runbooks")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Reconciled PrometheusRule", "rule", rule.GetName(), "alerts", len(alerts))
+	return ctrl.Result{}, nil
+}
+
+func (r *PrometheusRuleReconciler) reconcileAlertRunbook(ctx context.Context, rule *unstructured.Unstructured, name string, alert alertRule) error {
+	logger := log.FromContext(ctx)
+
+	runbook := &runbookv1alpha1.Runbook{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: rule.GetNamespace(),
+		},
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, runbook, func() error {
+		if !runbook.CreationTimestamp.IsZero() && !metav1.IsControlledBy(runbook, rule) {
+			return fmt.Errorf("runbook %s already exists and is not managed by PrometheusRule %s", name, rule.GetName())
+		}
+
+		runbook.Spec.AlertName = alert.Alert
+		runbook.Spec.Severity = severityFromLabels(alert.Labels)
+		runbook.Spec.Team = alert.Labels["team"]
+		if runbook.CreationTimestamp.IsZero() {
+			runbook.Spec.AutoGenerate = true
+		}
+		if runbook.Spec.Content.Impact == "" {
+			runbook.Spec.Content.Impact = impactFromAnnotations(alert.Annotations)
+		}
+
+		return controllerutil.SetControllerReference(rule, runbook, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info("Synced runbook from PrometheusRule", "runbook", name, "operation", result)
+	}
+
+	sourceRule := &runbookv1alpha1.SourceRuleRef{
+		Name:      rule.GetName(),
+		Namespace: rule.GetNamespace(),
+		UID:       string(rule.GetUID()),
+	}
+	if runbook.Status.SourceRule != nil && *runbook.Status.SourceRule == *sourceRule {
+		return nil
+	}
+
+	original := runbook.DeepCopy()
+	runbook.Status.SourceRule = sourceRule
+	if err := r.Status().Patch(ctx, runbook, client.MergeFrom(original)); err != nil {
+		if errors.IsConflict(err) {
+			logger.Info("Status update conflict, will retry on next reconciliation")
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// deleteStaleRunbooks removes Runbooks controlled by the rule whose alert is gone
+func (r *PrometheusRuleReconciler) deleteStaleRunbooks(ctx context.Context, rule *unstructured.Unstructured, wanted map[string]bool) error {
+	logger := log.FromContext(ctx)
+
+	var runbooks runbookv1alpha1.RunbookList
+	if err := r.List(ctx, &runbooks, client.InNamespace(rule.GetNamespace())); err != nil {
+		return err
+	}
+
+	for i := range runbooks.Items {
+		runbook := &runbooks.Items[i]
+		if wanted[runbook.Name] || !metav1.IsControlledBy(runbook, rule) {
+			continue
+		}
+
+		logger.Info("Deleting runbook for removed alert", "runbook", runbook.Name, "alert", runbook.Spec.AlertName)
+		if err := r.Delete(ctx, runbook); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// alertRulesFromPrometheusRule extracts the alerting rules from spec.groups[].rules[]
+func alertRulesFromPrometheusRule(rule *unstructured.Unstructured) ([]alertRule, error) {
+	groups, _, err := unstructured.NestedSlice(rule.Object, "spec", "groups")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec.groups: %w", err)
+	}
+
+	var alerts []alertRule
+	seen := make(map[string]bool)
+	for _, g := range groups {
+		group, ok := g.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		rules, _, err := unstructured.NestedSlice(group, "rules")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules of group %v: %w", group["name"], err)
+		}
+
+		for _, rl := range rules {
+			ruleMap, ok := rl.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			name, _, _ := unstructured.NestedString(ruleMap, "alert")
+			if name == "" || seen[name] {
+				// Recording rules have no alert name, and an alert defined in
+				// several groups only gets a single runbook
+				continue
+			}
+			seen[name] = true
+
+			labels, _, _ := unstructured.NestedStringMap(ruleMap, "labels")
+			annotations, _, _ := unstructured.NestedStringMap(ruleMap, "annotations")
+			alerts = append(alerts, alertRule{
+				Alert:       name,
+				Labels:      labels,
+				Annotations: annotations,
+			})
+		}
+	}
+
+	return alerts, nil
+}
+
+// runbookNameForAlert derives a DNS-compatible Runbook name from the rule and alert names
+func runbookNameForAlert(ruleName, alertName string) string {
+	var b strings.Builder
+	prevDash := false
+	runes := []rune(alertName)
+	for i, c := range runes {
+		switch {
+		case unicode.IsUpper(c):
+			if i > 0 && !prevDash && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteRune('-')
+			}
+			b.WriteRune(unicode.ToLower(c))
+			prevDash = false
+		case unicode.IsLower(c) || unicode.IsDigit(c):
+			b.WriteRune(c)
+			prevDash = false
+		default:
+			if !prevDash && b.Len() > 0 {
+				b.WriteRune('-')
+				prevDash = true
+			}
+		}
+	}
+
+	name := strings.Trim(fmt.Sprintf("%s-%s", ruleName, strings.Trim(b.String(), "-")), "-")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-")
+	}
+	return name
+}
+
+// severityFromLabels maps the alert severity label onto the Runbook severity enum
+func severityFromLabels(labels map[string]string) string {
+	switch severity := strings.ToLower(labels["severity"]); severity {
+	case "critical", "warning", "info":
+		return severity
+	default:
+		return "warning"
+	}
+}
+
+// impactFromAnnotations uses the alert description or summary as the initial impact
+func impactFromAnnotations(annotations map[string]string) string {
+	if description := annotations["description"]; description != "" {
+		return description
+	}
+	return annotations["summary"]
+}
+
+func newPrometheusRule() *unstructured.Unstructured {
+	rule := &unstructured.Unstructured{}
+	rule.SetGroupVersionKind(PrometheusRuleGVK)
+	return rule
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PrometheusRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(newPrometheusRule()).
+		Owns(&runbookv1alpha1.Runbook{}).
+		Named("prometheusrule").
+		Complete(r)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrometheusRule Controller", func() {
+	Context("When parsing a PrometheusRule", func() {
+		It("should extract one alert per alerting rule", func() {
+			rule := newPrometheusRule()
+			rule.SetName("example-alert")
+			rule.SetNamespace("default")
+			rule.Object["spec"] = map[string]interface{}{
+				"groups": []interface{}{
+					map[string]interface{}{
+						"name": "example",
+						"rules": []interface{}{
+							map[string]interface{}{
+								"alert": "HighErrorRate",
+								"expr":  "job:request_errors:rate5m > 0.05",
+								"labels": map[string]interface{}{
+									"severity": "critical",
+									"team":     "platform",
+								},
+								"annotations": map[string]interface{}{
+									"description": "The error rate is above 5% for the last 5 minutes.",
+								},
+							},
+							map[string]interface{}{
+								"record": "job:request_errors:rate5m",
+								"expr":   "rate(request_errors_total[5m])",
+							},
+						},
+					},
+					map[string]interface{}{
+						"name": "duplicates",
+						"rules": []interface{}{
+							map[string]interface{}{
+								"alert": "HighErrorRate",
+								"expr":  "job:request_errors:rate5m > 0.5",
+							},
+						},
+					},
+				},
+			}
+
+			alerts, err := alertRulesFromPrometheusRule(rule)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(alerts).To(HaveLen(1))
+			Expect(alerts[0].Alert).To(Equal("HighErrorRate"))
+			Expect(severityFromLabels(alerts[0].Labels)).To(Equal("critical"))
+			Expect(alerts[0].Labels["team"]).To(Equal("platform"))
+			Expect(impactFromAnnotations(alerts[0].Annotations)).To(ContainSubstring("error rate"))
+		})
+
+		It("should derive DNS-compatible runbook names", func() {
+			Expect(runbookNameForAlert("example-alert", "HighErrorRate")).To(Equal("example-alert-high-error-rate"))
+			Expect(runbookNameForAlert("k8s", "KubePodCrashLooping")).To(Equal("k8s-kube-pod-crash-looping"))
+			Expect(runbookNameForAlert("api", "HTTPErrors_5xx")).To(Equal("api-http-errors-5xx"))
+		})
+
+		It("should default unknown severities to warning", func() {
+			Expect(severityFromLabels(map[string]string{"severity": "page"})).To(Equal("warning"))
+			Expect(severityFromLabels(nil)).To(Equal("warning"))
+		})
+	})
+})
@@ -18,20 +18,37 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"slices"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+	"github.com/guibes/runbook-operator/pkg/audit"
 	"github.com/guibes/runbook-operator/pkg/generator"
 	"github.com/guibes/runbook-operator/pkg/outputs"
+	"github.com/guibes/runbook-operator/pkg/triggers"
+	"github.com/guibes/runbook-operator/pkg/validation"
 )
 
 // RunbookReconciler reconciles a Runbook object
@@ -39,11 +56,50 @@ type RunbookReconciler struct {
 	client.Client
 	Scheme    *runtime.Scheme
 	Generator *generator.RunbookGenerator
+
+	// Outputs resolves output formats, defaulting to outputs.DefaultRegistry
+	Outputs *outputs.Registry
+
+	// Validator checks runbook content, defaulting to the built-in rules
+	Validator *validation.Validator
+
+	// Dispatcher starts the runs requested with the run-now annotation,
+	// defaulting to one that uses the reconciler client and recorder
+	Dispatcher *automation.Dispatcher
+
+	Recorder record.EventRecorder
+
+	// Audit records the manual runs in the audit log
+	Audit audit.Sink
+
+	// APIReader reads the Secrets of outputs without caching every Secret
+	// of the cluster, defaults to Client
+	APIReader client.Reader
+
+	// ConfigMapNamespaces are the namespaces configmap outputs may write to
+	// besides the namespace of the runbook
+	ConfigMapNamespaces []string
+
+	// S3AmbientCredentials lets s3 outputs without a Secret use the
+	// credentials of the operator
+	S3AmbientCredentials bool
 }
 
+// runbookWorkers is how many Runbooks are reconciled at once. Git outputs
+// wait for the push of their batch, so the runbooks regenerated together
+// share a commit instead of waiting for each other.
+const runbookWorkers = 4
+
+// ForceDeleteAnnotation lets a Runbook be deleted even when its generated
+// outputs could not be cleaned up
+const ForceDeleteAnnotation = "runbook.runbook.io/force-delete"
+
 //+kubebuilder:rbac:groups=runbook.runbook.io,resources=runbooks,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=runbook.runbook.io,resources=runbooks/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=runbook.runbook.io,resources=runbooks/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;create;update;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop
 func (r *RunbookReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -79,10 +135,57 @@ func (r *RunbookReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	if err := r.handleRunNow(ctx, &runbook); err != nil {
+		logger.Error(err, "Failed to start manual run")
+		return ctrl.Result{}, err
+	}
+
 	// Reconcile the runbook
 	return r.reconcileRunbook(ctx, &runbook)
 }
 
+// handleRunNow starts an execution for a new value of the run-now annotation.
+// The conditions of manual triggers are evaluated against the runbook labels.
+func (r *RunbookReconciler) handleRunNow(ctx context.Context, runbook *runbookv1alpha1.Runbook) error {
+	nonce := runbook.Annotations[automation.RunNowAnnotation]
+	if nonce == "" {
+		return nil
+	}
+	if automationStatus := runbook.Status.Automation; automationStatus != nil &&
+		automationStatus.ManualRun != nil && automationStatus.ManualRun.Nonce == nonce {
+		return nil
+	}
+
+	trigger, err := automation.MatchTrigger(runbook, automation.TriggerManual, triggers.Labels(runbook.Labels))
+	if err != nil {
+		r.Recorder.Eventf(runbook, corev1.EventTypeWarning, "ManualRunIgnored", "Ignoring %s=%s: %v",
+			automation.RunNowAnnotation, nonce, err)
+		return nil
+	}
+	if trigger == nil {
+		r.Recorder.Eventf(runbook, corev1.EventTypeWarning, "ManualRunIgnored",
+			"Ignoring %s=%s: automation is disabled or no manual trigger matched", automation.RunNowAnnotation, nonce)
+		return nil
+	}
+
+	event := runbookv1alpha1.TriggerEvent{
+		Type:        automation.TriggerManual,
+		Source:      "annotation/" + automation.RunNowAnnotation,
+		Fingerprint: nonce,
+		Labels:      runbook.Labels,
+		TriggeredAt: metav1.NewTime(time.Now().Truncate(time.Second)),
+	}
+	_, err = r.dispatcher().Dispatch(ctx, runbook, event)
+	return err
+}
+
+func (r *RunbookReconciler) dispatcher() *automation.Dispatcher {
+	if r.Dispatcher != nil {
+		return r.Dispatcher
+	}
+	return &automation.Dispatcher{Client: r.Client, Recorder: r.Recorder, Audit: r.Audit}
+}
+
 func (r *RunbookReconciler) reconcileRunbook(ctx context.Context, runbook *runbookv1alpha1.Runbook) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
@@ -99,6 +202,31 @@ func (r *RunbookReconciler) reconcileRunbook(ctx context.Context, runbook *runbo
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// Template versions come from the generator, which is empty until the
+	// template controller loaded the templates after a restart
+	pending, err := r.templatesPending(ctx, runbook)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if pending {
+		logger.V(1).Info("Waiting for the runbook templates to be loaded", "runbook", runbook.Name)
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+
+	// Skip regeneration when neither the spec nor the templates changed
+	contentHash, err := r.contentHash(runbook)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if runbook.Status.Phase == "ready" && runbook.Status.ContentHash == contentHash {
+		logger.V(1).Info("Runbook outputs are up to date", "runbook", runbook.Name)
+		if runbook.Status.ObservedGeneration != runbook.Generation {
+			runbook.Status.ObservedGeneration = runbook.Generation
+			return ctrl.Result{}, r.updateStatus(ctx, runbook, original)
+		}
+		return ctrl.Result{}, nil
+	}
+
 	// Generate runbook content if auto-generate is enabled
 	if runbook.Spec.AutoGenerate {
 		if err := r.generateRunbookContent(ctx, runbook); err != nil {
@@ -108,40 +236,105 @@ func (r *RunbookReconciler) reconcileRunbook(ctx context.Context, runbook *runbo
 	}
 
 	// Validate runbook content
-	if err := r.validateRunbook(ctx, runbook); err != nil {
+	warnings, err := r.validateRunbook(ctx, runbook)
+	if err != nil {
 		logger.Error(err, "Failed to validate runbook")
 		return r.updateStatusWithError(ctx, runbook, err)
 	}
 
 	// Generate outputs
 	if err := r.generateOutputs(ctx, runbook); err != nil {
-		logger.Error(err, "Failed to generate outputs")
-		return r.updateStatusWithError(ctx, runbook, err)
+		var outputErr *outputsError
+		if !stderrors.As(err, &outputErr) {
+			logger.Error(err, "Failed to generate outputs")
+			return r.updateStatusWithError(ctx, runbook, err)
+		}
+		return r.updateStatusWithOutputError(ctx, runbook, original, warnings, outputErr)
 	}
 
 	// Update status to ready
 	runbook.Status.Phase = "ready"
 	runbook.Status.ValidationStatus = "valid"
 	runbook.Status.ValidationErrors = nil // Clear any previous errors
+	runbook.Status.ValidationWarnings = warnings
 	now := metav1.NewTime(time.Now())
 	runbook.Status.LastGenerated = &now
+	runbook.Status.ObservedGeneration = runbook.Generation
+	runbook.Status.ContentHash = contentHash
 
 	// Update condition
-	condition := metav1.Condition{
-		Type:               "Ready",
-		Status:             metav1.ConditionTrue,
-		LastTransitionTime: now,
-		Reason:             "GenerationSuccessful",
-		Message:            "Runbook generation completed successfully",
-	}
-	runbook.Status.Conditions = []metav1.Condition{condition}
+	meta.SetStatusCondition(&runbook.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionTrue,
+		Reason:  "GenerationSuccessful",
+		Message: "Runbook generation completed successfully",
+	})
 
 	if err := r.updateStatus(ctx, runbook, original); err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// Changes to the runbook or its templates trigger the next reconciliation
 	logger.Info("Successfully reconciled runbook", "runbook", runbook.Name)
-	return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+	return ctrl.Result{}, nil
+}
+
+// contentHash fingerprints everything the generated outputs depend on: the
+// spec, which includes the output configuration, and the version of every
+// template the runbook renders with
+func (r *RunbookReconciler) contentHash(runbook *runbookv1alpha1.Runbook) (string, error) {
+	templates := map[string]string{}
+	for _, name := range append([]string{runbook.Spec.Template}, outputTemplates(runbook)...) {
+		if name == "" {
+			name = generator.DefaultTemplateName
+		}
+		templates[name] = r.Generator.TemplateVersion(name)
+	}
+
+	data, err := json.Marshal(struct {
+		Spec      runbookv1alpha1.RunbookSpec `json:"spec"`
+		Templates map[string]string           `json:"templates"`
+	}{runbook.Spec, templates})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash runbook: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// templatesPending reports whether a template the runbook renders with is
+// ready as a RunbookTemplate but not loaded into the generator yet. Missing
+// and invalid templates are not pending, rendering reports them.
+func (r *RunbookReconciler) templatesPending(ctx context.Context, runbook *runbookv1alpha1.Runbook) (bool, error) {
+	var unloaded []string
+	for _, name := range append([]string{runbook.Spec.Template}, outputTemplates(runbook)...) {
+		if name != "" && name != generator.DefaultTemplateName && r.Generator.TemplateVersion(name) == "" {
+			unloaded = append(unloaded, name)
+		}
+	}
+	if len(unloaded) == 0 {
+		return false, nil
+	}
+
+	var templates runbookv1alpha1.RunbookTemplateList
+	if err := r.List(ctx, &templates); err != nil {
+		return false, fmt.Errorf("failed to list runbook templates: %w", err)
+	}
+	for _, tmpl := range templates.Items {
+		if slices.Contains(unloaded, tmpl.Spec.Name) && tmpl.DeletionTimestamp == nil && tmpl.Status.Phase == "ready" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func outputTemplates(runbook *runbookv1alpha1.Runbook) []string {
+	names := make([]string, 0, len(runbook.Spec.Outputs))
+	for _, output := range runbook.Spec.Outputs {
+		names = append(names, output.Template)
+	}
+	return names
 }
 
 // updateStatus safely updates the status with retry logic
@@ -173,19 +366,30 @@ func (r *RunbookReconciler) generateRunbookContent(ctx context.Context, runbook
 	return nil
 }
 
-func (r *RunbookReconciler) validateRunbook(ctx context.Context, runbook *runbookv1alpha1.Runbook) error {
+// validateRunbook runs the validation rules and returns the warnings, or a
+// *validation.Error when the runbook has error level findings
+func (r *RunbookReconciler) validateRunbook(ctx context.Context, runbook *runbookv1alpha1.Runbook) ([]string, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Validating runbook", "runbook", runbook.Name)
 
-	// TODO: Implement actual validation
-	// Basic validation for now
-	if runbook.Spec.AlertName == "" {
-		return fmt.Errorf("alert name is required")
+	result := r.runbookValidator().Validate(runbook)
+	for _, finding := range result.Warnings() {
+		logger.Info("Runbook validation warning", "field", finding.Field, "rule", finding.Rule, "message", finding.Message)
 	}
 
-	return nil
+	return validation.Strings(result.Warnings()), result.Err()
 }
 
+func (r *RunbookReconciler) runbookValidator() *validation.Validator {
+	if r.Validator != nil {
+		return r.Validator
+	}
+	return validation.NewDefaultValidator()
+}
+
+// generateOutputs renders every output. Outputs that fail keep the entry of
+// their previous generation in the status, so that they are still cleaned
+// up, and are returned as an *outputsError once the others were generated.
 func (r *RunbookReconciler) generateOutputs(ctx context.Context, runbook *runbookv1alpha1.Runbook) error {
 	logger := log.FromContext(ctx)
 
@@ -195,43 +399,109 @@ func (r *RunbookReconciler) generateOutputs(ctx context.Context, runbook *runboo
 	}
 
 	var generatedOutputs []runbookv1alpha1.GeneratedOutput
+	failed := &outputsError{}
 
 	for _, output := range runbook.Spec.Outputs {
 		logger.Info("Generating output", "type", output.Format, "runbook", runbook.Name)
 
-		var err error
-		switch output.Format {
-		case "markdown":
-			mardownOut := &outputs.MarkdownOutput{BasePath: output.Destination}
-			err = mardownOut.Generate(runbook, content)
-		case "html":
-			htmlOut := &outputs.HTMLOutput{BasePath: output.Destination}
-			err = htmlOut.Generate(runbook)
-		case "api":
-			apiOut := &outputs.APIOutput{BaseURL: output.Destination}
-			err = apiOut.Generate(runbook, content)
-		default:
+		generated, err := r.generateOutput(ctx, runbook, output, content)
+		if stderrors.Is(err, outputs.ErrUnknownFormat) {
 			logger.Info("Unknown output format, skipping", "format", output.Format)
 			continue
 		}
-
 		if err != nil {
-			logger.Error(err, "Failed to generate output", "type", output.Format)
+			logger.Error(err, "Failed to generate output", "type", output.Format, "destination", output.Destination)
+			failed.errs = append(failed.errs, fmt.Errorf("%s output to %s: %w", output.Format, output.Destination, err))
+			if previous := previousOutput(runbook, output); previous != nil {
+				generatedOutputs = append(generatedOutputs, *previous)
+			}
 			continue
 		}
+		generatedOutputs = append(generatedOutputs, generated)
+	}
+
+	runbook.Status.GeneratedOutputs = generatedOutputs
+	if len(failed.errs) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// generateOutput renders a single output with the shared markdown content,
+// unless the output has its own template or variables
+func (r *RunbookReconciler) generateOutput(ctx context.Context, runbook *runbookv1alpha1.Runbook, output runbookv1alpha1.OutputConfig, content string) (runbookv1alpha1.GeneratedOutput, error) {
+	if output.Template != "" || len(output.Variables) > 0 {
+		var err error
+		content, err = r.Generator.GenerateMarkdownForOutput(ctx, runbook, output)
+		if err != nil {
+			return runbookv1alpha1.GeneratedOutput{}, fmt.Errorf("failed to generate content: %w", err)
+		}
+	}
 
-		generatedOutputs = append(generatedOutputs, runbookv1alpha1.GeneratedOutput{
-			Format:      output.Format,
-			Location:    output.Destination,
-			GeneratedAt: metav1.NewTime(time.Now()),
-		})
+	out, err := r.outputRegistry().New(output, r.outputOptions())
+	if err != nil {
+		return runbookv1alpha1.GeneratedOutput{}, err
+	}
 
-		runbook.Status.GeneratedOutputs = generatedOutputs
+	location, err := out.Generate(ctx, runbook, content)
+	if err != nil {
+		return runbookv1alpha1.GeneratedOutput{}, err
 	}
 
+	generated := runbookv1alpha1.GeneratedOutput{
+		Format:      output.Format,
+		Destination: output.Destination,
+		Location:    location,
+		GeneratedAt: metav1.NewTime(time.Now()),
+	}
+	if versioned, ok := out.(outputs.Versioned); ok {
+		generated.Revision = versioned.Revision(location)
+	}
+	return generated, nil
+}
+
+// previousOutput returns the status entry of the output from an earlier generation
+func previousOutput(runbook *runbookv1alpha1.Runbook, output runbookv1alpha1.OutputConfig) *runbookv1alpha1.GeneratedOutput {
+	for i, generated := range runbook.Status.GeneratedOutputs {
+		if generated.Format == output.Format && generated.Destination == output.Destination {
+			return &runbook.Status.GeneratedOutputs[i]
+		}
+	}
 	return nil
 }
 
+// outputsError collects the errors of the outputs that failed to generate
+type outputsError struct {
+	errs []error
+}
+
+func (e *outputsError) Error() string {
+	return fmt.Sprintf("failed to generate %d output(s): %v", len(e.errs), stderrors.Join(e.errs...))
+}
+
+func (e *outputsError) Unwrap() []error {
+	return e.errs
+}
+
+// outputRegistry returns the registry used to resolve output formats
+func (r *RunbookReconciler) outputRegistry() *outputs.Registry {
+	if r.Outputs != nil {
+		return r.Outputs
+	}
+	return outputs.DefaultRegistry
+}
+
+// outputOptions are the dependencies and operator settings passed to outputs
+func (r *RunbookReconciler) outputOptions() outputs.Options {
+	return outputs.Options{
+		Client:               r.Client,
+		Generator:            r.Generator,
+		APIReader:            r.APIReader,
+		ConfigMapNamespaces:  r.ConfigMapNamespaces,
+		S3AmbientCredentials: r.S3AmbientCredentials,
+	}
+}
+
 func (r *RunbookReconciler) updateStatusWithError(ctx context.Context, runbook *runbookv1alpha1.Runbook, err error) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	original := runbook.DeepCopy()
@@ -240,14 +510,26 @@ func (r *RunbookReconciler) updateStatusWithError(ctx context.Context, runbook *
 	runbook.Status.ValidationStatus = "invalid"
 	runbook.Status.ValidationErrors = []string{err.Error()}
 
-	condition := metav1.Condition{
-		Type:               "Ready",
-		Status:             metav1.ConditionFalse,
-		LastTransitionTime: metav1.NewTime(time.Now()),
-		Reason:             "GenerationFailed",
-		Message:            fmt.Sprintf("Failed to generate runbook: %v", err),
+	// Report each template variable problem as its own validation error
+	var variableErr *generator.VariableError
+	if stderrors.As(err, &variableErr) {
+		runbook.Status.ValidationErrors = variableErr.Problems
+	}
+
+	// Report each failed validation rule with the path of its field
+	var validationErr *validation.Error
+	if stderrors.As(err, &validationErr) {
+		result := validationErr.Result()
+		runbook.Status.ValidationErrors = validation.Strings(result.Errors())
+		runbook.Status.ValidationWarnings = validation.Strings(result.Warnings())
 	}
-	runbook.Status.Conditions = []metav1.Condition{condition}
+
+	meta.SetStatusCondition(&runbook.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  "GenerationFailed",
+		Message: fmt.Sprintf("Failed to generate runbook: %v", err),
+	})
 
 	if updateErr := r.updateStatus(ctx, runbook, original); updateErr != nil {
 		logger.Error(updateErr, "Failed to update error status")
@@ -256,11 +538,42 @@ func (r *RunbookReconciler) updateStatusWithError(ctx context.Context, runbook *
 	return ctrl.Result{RequeueAfter: time.Minute * 2}, nil
 }
 
+// updateStatusWithOutputError records the outputs that were generated and
+// returns the error, so that the failed outputs are retried with backoff.
+// The content hash is left as it was so that the next attempt regenerates.
+func (r *RunbookReconciler) updateStatusWithOutputError(ctx context.Context, runbook *runbookv1alpha1.Runbook, original *runbookv1alpha1.Runbook, warnings []string, err *outputsError) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	runbook.Status.Phase = "error"
+	runbook.Status.ValidationStatus = "valid"
+	runbook.Status.ValidationErrors = nil
+	runbook.Status.ValidationWarnings = warnings
+	meta.SetStatusCondition(&runbook.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  "OutputFailed",
+		Message: err.Error(),
+	})
+
+	if updateErr := r.updateStatus(ctx, runbook, original); updateErr != nil {
+		logger.Error(updateErr, "Failed to update error status")
+	}
+	return ctrl.Result{}, err
+}
+
 func (r *RunbookReconciler) handleDeletion(ctx context.Context, runbook *runbookv1alpha1.Runbook) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
-	// TODO: Cleanup generated files and external resources
 	logger.Info("Cleaning up runbook resources", "runbook", runbook.Name)
+	if failed := r.cleanupOutputs(ctx, runbook); failed > 0 {
+		if runbook.Annotations[ForceDeleteAnnotation] != "true" {
+			logger.Info("Keeping finalizer until generated outputs are cleaned up", "failed", failed)
+			return ctrl.Result{RequeueAfter: time.Second * 30}, nil
+		}
+		logger.Info("Force deleting runbook despite cleanup failures", "failed", failed)
+		r.Recorder.Eventf(runbook, corev1.EventTypeWarning, "ForceDeleted",
+			"Removed finalizer with %d generated outputs left behind", failed)
+	}
 
 	// Remove finalizer
 	original := runbook.DeepCopy()
@@ -277,9 +590,89 @@ func (r *RunbookReconciler) handleDeletion(ctx context.Context, runbook *runbook
 	return ctrl.Result{}, nil
 }
 
+// cleanupOutputs removes every generated output and returns how many could not
+// be cleaned up. Cleaned outputs are dropped from the status so that retries
+// only revisit the failed ones.
+func (r *RunbookReconciler) cleanupOutputs(ctx context.Context, runbook *runbookv1alpha1.Runbook) int {
+	logger := log.FromContext(ctx)
+	original := runbook.DeepCopy()
+
+	var remaining []runbookv1alpha1.GeneratedOutput
+	for _, generated := range runbook.Status.GeneratedOutputs {
+		out, err := r.outputRegistry().New(outputConfigFor(runbook, generated), r.outputOptions())
+		if err == nil {
+			err = out.Cleanup(ctx, runbook, generated.Location)
+		}
+		if err != nil {
+			logger.Error(err, "Failed to clean up output", "type", generated.Format, "location", generated.Location)
+			r.Recorder.Eventf(runbook, corev1.EventTypeWarning, "CleanupFailed",
+				"Failed to clean up %s output at %s: %v", generated.Format, generated.Location, err)
+			remaining = append(remaining, generated)
+			continue
+		}
+
+		logger.Info("Cleaned up output", "type", generated.Format, "location", generated.Location)
+	}
+
+	if len(remaining) != len(runbook.Status.GeneratedOutputs) {
+		runbook.Status.GeneratedOutputs = remaining
+		if err := r.updateStatus(ctx, runbook, original); err != nil {
+			logger.Error(err, "Failed to record cleaned up outputs")
+		}
+	}
+
+	return len(remaining)
+}
+
+// outputConfigFor finds the output configuration a generated output came from,
+// falling back to what was recorded in the status when the spec changed since
+func outputConfigFor(runbook *runbookv1alpha1.Runbook, generated runbookv1alpha1.GeneratedOutput) runbookv1alpha1.OutputConfig {
+	for _, output := range runbook.Spec.Outputs {
+		if output.Format == generated.Format && output.Destination == generated.Destination {
+			return output
+		}
+	}
+	return runbookv1alpha1.OutputConfig{
+		Format:      generated.Format,
+		Destination: generated.Destination,
+	}
+}
+
+// runbooksForTemplate maps a RunbookTemplate to the Runbooks that reference it
+func (r *RunbookReconciler) runbooksForTemplate(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	runbookTemplate, ok := obj.(*runbookv1alpha1.RunbookTemplate)
+	if !ok {
+		return nil
+	}
+
+	var runbooks runbookv1alpha1.RunbookList
+	if err := r.List(ctx, &runbooks); err != nil {
+		logger.Error(err, "Failed to list runbooks for template", "template", runbookTemplate.Spec.Name)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range runbooks.Items {
+		if runbookUsesTemplate(&runbooks.Items[i], runbookTemplate.Spec.Name) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&runbooks.Items[i]),
+			})
+		}
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *RunbookReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&runbookv1alpha1.Runbook{}).
+		// Status updates do not change the generation, so the controller only
+		// reacts to spec changes. Annotations are watched for the force delete
+		// flag and manual runs.
+		For(&runbookv1alpha1.Runbook{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		Watches(&runbookv1alpha1.RunbookTemplate{}, handler.EnqueueRequestsFromMapFunc(r.runbooksForTemplate)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: runbookWorkers}).
 		Complete(r)
 }
@@ -18,18 +18,46 @@ package controller
 
 import (
 	"context"
+	stderrors "errors"
+	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/generator"
+	"github.com/guibes/runbook-operator/pkg/outputs"
 )
 
+// flakyOutput publishes to destination/alertName, or fails with err when set
+type flakyOutput struct {
+	err error
+}
+
+func (f *flakyOutput) Generate(_ context.Context, runbook *runbookv1alpha1.Runbook, _ string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	return "catalog/" + runbook.Spec.AlertName, nil
+}
+
+func (f *flakyOutput) Cleanup(context.Context, *runbookv1alpha1.Runbook, string) error {
+	return nil
+}
+
+func (f *flakyOutput) Describe() string {
+	return "flaky output"
+}
+
 var _ = Describe("Runbook Controller", func() {
 	Context("When reconciling a resource", func() {
 		const resourceName = "test-resource"
@@ -69,8 +97,10 @@ var _ = Describe("Runbook Controller", func() {
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &RunbookReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: generator.NewRunbookGenerator(),
+				Recorder:  record.NewFakeRecorder(10),
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -81,4 +111,463 @@ var _ = Describe("Runbook Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When deleting a resource", func() {
+		const resourceName = "test-cleanup"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should remove generated files before releasing the finalizer", func() {
+			destination := GinkgoT().TempDir()
+			resource := &runbookv1alpha1.Runbook{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: runbookv1alpha1.RunbookSpec{
+					AlertName:    "CleanupTest",
+					AutoGenerate: true,
+					Outputs: []runbookv1alpha1.OutputConfig{
+						{Format: "markdown", Destination: destination},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &RunbookReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: generator.NewRunbookGenerator(),
+				Recorder:  record.NewFakeRecorder(10),
+			}
+
+			By("Reconciling until the outputs are generated")
+			for range 3 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			generatedFile := filepath.Join(destination, "default."+resourceName+".md")
+			Expect(generatedFile).To(BeAnExistingFile())
+
+			By("Deleting the runbook")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(generatedFile).NotTo(BeAnExistingFile())
+			err = k8sClient.Get(ctx, typeNamespacedName, &runbookv1alpha1.Runbook{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("When validating a resource", func() {
+		ctx := context.Background()
+
+		reconcileRunbook := func(resource *runbookv1alpha1.Runbook) *runbookv1alpha1.Runbook {
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, resource))).To(Succeed())
+			})
+
+			controllerReconciler := &RunbookReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: generator.NewRunbookGenerator(),
+				Recorder:  record.NewFakeRecorder(10),
+			}
+			namespacedName := types.NamespacedName{Name: resource.Name, Namespace: resource.Namespace}
+			for range 3 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: namespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			updated := &runbookv1alpha1.Runbook{}
+			Expect(k8sClient.Get(ctx, namespacedName, updated)).To(Succeed())
+			return updated
+		}
+
+		It("should report warnings without failing the runbook", func() {
+			updated := reconcileRunbook(&runbookv1alpha1.Runbook{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-validation-warning", Namespace: "default"},
+				Spec:       runbookv1alpha1.RunbookSpec{AlertName: "ValidationWarning", Severity: "warning"},
+			})
+
+			Expect(updated.Status.Phase).To(Equal("ready"))
+			Expect(updated.Status.ValidationErrors).To(BeEmpty())
+			Expect(updated.Status.ValidationWarnings).To(ConsistOf(
+				"spec.content.investigation: at least one investigation step is recommended"))
+		})
+
+		It("should fail the runbook with field qualified errors", func() {
+			updated := reconcileRunbook(&runbookv1alpha1.Runbook{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-validation-error", Namespace: "default"},
+				Spec: runbookv1alpha1.RunbookSpec{
+					AlertName: "ValidationError",
+					Severity:  "critical",
+					Content: runbookv1alpha1.RunbookContent{
+						Remediation: []runbookv1alpha1.RemediationStep{
+							{Description: "Scale up", Automated: true},
+						},
+					},
+				},
+			})
+
+			Expect(updated.Status.Phase).To(Equal("error"))
+			Expect(updated.Status.ValidationErrors).To(ConsistOf(
+				"spec.content.impact: impact is required for critical alerts",
+				"spec.content.remediation[0].command: automated remediation steps require a command",
+			))
+			Expect(updated.Status.ValidationWarnings).NotTo(BeEmpty())
+		})
+
+		It("should require an example value for every command placeholder", func() {
+			updated := reconcileRunbook(&runbookv1alpha1.Runbook{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-validation-placeholder", Namespace: "default"},
+				Spec: runbookv1alpha1.RunbookSpec{
+					AlertName:     "ValidationPlaceholder",
+					ExampleLabels: map[string]string{"namespace": "payments"},
+					Content: runbookv1alpha1.RunbookContent{
+						Investigation: []runbookv1alpha1.InvestigationStep{
+							{Description: "Describe the pod", Command: "kubectl -n {{ .Labels.namespace }} describe pod {{ .Labels.pod }}"},
+							{Description: "List the events", Command: "kubectl get events -n {{ .Spec.Team }}"},
+						},
+					},
+				},
+			})
+
+			Expect(updated.Status.Phase).To(Equal("error"))
+			Expect(updated.Status.ValidationErrors).To(ConsistOf(
+				"spec.content.investigation[0].command: placeholder {{ .Labels.pod }} has no example value in spec.exampleLabels",
+				"spec.content.investigation[1].command: invalid placeholder .Spec.Team: only .Labels.<name> is supported",
+			))
+		})
+
+		It("should render the command placeholders with the example labels in generated docs", func() {
+			destination := GinkgoT().TempDir()
+			updated := reconcileRunbook(&runbookv1alpha1.Runbook{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-example-labels", Namespace: "default"},
+				Spec: runbookv1alpha1.RunbookSpec{
+					AlertName:     "ExampleLabels",
+					ExampleLabels: map[string]string{"namespace": "payments", "pod": "api-7d9f8"},
+					Content: runbookv1alpha1.RunbookContent{
+						Investigation: []runbookv1alpha1.InvestigationStep{
+							{Description: "Describe the pod", Command: "kubectl -n {{ .Labels.namespace }} describe pod {{ .Labels.pod }}"},
+						},
+					},
+					Outputs: []runbookv1alpha1.OutputConfig{
+						{Format: "markdown", Destination: destination},
+					},
+				},
+			})
+
+			Expect(updated.Status.Phase).To(Equal("ready"))
+			content, err := os.ReadFile(filepath.Join(destination, "default.test-example-labels.md"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("kubectl -n payments describe pod api-7d9f8"))
+		})
+	})
+
+	Context("When the runbook is unchanged", func() {
+		const resourceName = "test-content-hash"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should only regenerate outputs after the spec changes", func() {
+			destination := GinkgoT().TempDir()
+			resource := &runbookv1alpha1.Runbook{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: runbookv1alpha1.RunbookSpec{
+					AlertName: "ContentHash",
+					Outputs: []runbookv1alpha1.OutputConfig{
+						{Format: "markdown", Destination: destination},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, resource))).To(Succeed())
+			})
+
+			controllerReconciler := &RunbookReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: generator.NewRunbookGenerator(),
+				Recorder:  record.NewFakeRecorder(10),
+			}
+			reconcileRunbook := func() {
+				result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeZero())
+			}
+
+			By("Reconciling until the outputs are generated")
+			for range 3 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			generatedFile := filepath.Join(destination, "default."+resourceName+".md")
+			Expect(generatedFile).To(BeAnExistingFile())
+
+			updated := &runbookv1alpha1.Runbook{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.ContentHash).NotTo(BeEmpty())
+			Expect(updated.Status.ObservedGeneration).To(Equal(updated.Generation))
+
+			By("Reconciling again without changes")
+			Expect(os.Remove(generatedFile)).To(Succeed())
+			reconcileRunbook()
+			Expect(generatedFile).NotTo(BeAnExistingFile())
+
+			By("Changing the spec")
+			updated.Spec.Team = "platform"
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			reconcileRunbook()
+			Expect(generatedFile).To(BeAnExistingFile())
+		})
+	})
+
+	Context("When an output fails", func() {
+		const resourceName = "test-output-failure"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should keep the previous output and retry without storing the content hash", func() {
+			destination := GinkgoT().TempDir()
+			resource := &runbookv1alpha1.Runbook{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: runbookv1alpha1.RunbookSpec{
+					AlertName: "OutputFailure",
+					Outputs: []runbookv1alpha1.OutputConfig{
+						{Format: "markdown", Destination: destination},
+						{Format: "flaky", Destination: "catalog"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, resource))).To(Succeed())
+			})
+
+			flaky := &flakyOutput{}
+			registry := outputs.NewRegistry()
+			registry.Register("markdown", func(config runbookv1alpha1.OutputConfig, opts outputs.Options) (outputs.Output, error) {
+				return outputs.DefaultRegistry.New(config, opts)
+			})
+			registry.Register("flaky", func(runbookv1alpha1.OutputConfig, outputs.Options) (outputs.Output, error) {
+				return flaky, nil
+			})
+			controllerReconciler := &RunbookReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: generator.NewRunbookGenerator(),
+				Recorder:  record.NewFakeRecorder(10),
+				Outputs:   registry,
+			}
+
+			By("Reconciling until the outputs are generated")
+			for range 3 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			generated := &runbookv1alpha1.Runbook{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, generated)).To(Succeed())
+			Expect(generated.Status.GeneratedOutputs).To(HaveLen(2))
+
+			By("Failing the output after a spec change")
+			flaky.err = stderrors.New("catalog unavailable")
+			generated.Spec.Team = "platform"
+			Expect(k8sClient.Update(ctx, generated)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).To(MatchError(ContainSubstring("catalog unavailable")))
+
+			failed := &runbookv1alpha1.Runbook{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, failed)).To(Succeed())
+			Expect(failed.Status.Phase).To(Equal("error"))
+			Expect(failed.Status.ContentHash).To(Equal(generated.Status.ContentHash))
+			Expect(failed.Status.GeneratedOutputs).To(HaveLen(2))
+			Expect(failed.Status.GeneratedOutputs[1].Location).To(Equal("catalog/OutputFailure"))
+			Expect(failed.Status.GeneratedOutputs[1].GeneratedAt).To(Equal(generated.Status.GeneratedOutputs[1].GeneratedAt))
+			Expect(meta.FindStatusCondition(failed.Status.Conditions, "Ready").Reason).To(Equal("OutputFailed"))
+
+			By("Regenerating once the output recovers")
+			flaky.err = nil
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, failed)).To(Succeed())
+			Expect(failed.Status.Phase).To(Equal("ready"))
+			Expect(failed.Status.ContentHash).NotTo(Equal(generated.Status.ContentHash))
+		})
+	})
+
+	Context("When the templates are not loaded yet", func() {
+		const resourceName = "test-templates-pending"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should wait for the template controller instead of failing", func() {
+			runbookTemplate := &runbookv1alpha1.RunbookTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "pending-template"},
+				Spec:       runbookv1alpha1.RunbookTemplateSpec{Name: "pending", Template: "# {{ .Spec.AlertName }}"},
+			}
+			Expect(k8sClient.Create(ctx, runbookTemplate)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, runbookTemplate))).To(Succeed())
+			})
+			runbookTemplate.Status.Phase = "ready"
+			Expect(k8sClient.Status().Update(ctx, runbookTemplate)).To(Succeed())
+
+			resource := &runbookv1alpha1.Runbook{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: runbookv1alpha1.RunbookSpec{
+					AlertName: "TemplatesPending",
+					Template:  "pending",
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, resource))).To(Succeed())
+			})
+
+			runbookGenerator := generator.NewRunbookGenerator()
+			controllerReconciler := &RunbookReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: runbookGenerator,
+				Recorder:  record.NewFakeRecorder(10),
+			}
+
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).NotTo(BeZero())
+
+			pending := &runbookv1alpha1.Runbook{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, pending)).To(Succeed())
+			Expect(pending.Status.Phase).To(Equal("generating"))
+
+			By("Generating once the template is loaded")
+			Expect(runbookGenerator.LoadTemplate(&runbookTemplate.Spec)).To(Succeed())
+			result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, pending)).To(Succeed())
+			Expect(pending.Status.Phase).To(Equal("ready"))
+		})
+	})
+
+	Context("When the run-now annotation is set", func() {
+		const resourceName = "test-run-now"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should start one execution per nonce", func() {
+			resource := &runbookv1alpha1.Runbook{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: runbookv1alpha1.RunbookSpec{
+					AlertName: "RunNow",
+					Content: runbookv1alpha1.RunbookContent{
+						Automation: &runbookv1alpha1.AutomationConfig{
+							Enabled:  true,
+							Triggers: []runbookv1alpha1.TriggerConfig{{Type: "manual"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, resource))).To(Succeed())
+				Expect(k8sClient.DeleteAllOf(ctx, &runbookv1alpha1.RunbookExecution{}, client.InNamespace("default"),
+					client.MatchingLabels{"runbook.runbook.io/runbook": resourceName})).To(Succeed())
+			})
+
+			controllerReconciler := &RunbookReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: generator.NewRunbookGenerator(),
+				Recorder:  record.NewFakeRecorder(10),
+			}
+			runNow := func(nonce string) *runbookv1alpha1.Runbook {
+				updated := &runbookv1alpha1.Runbook{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+				updated.Annotations = map[string]string{"runbook.runbook.io/run-now": nonce}
+				Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+
+				for range 2 {
+					_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+					Expect(err).NotTo(HaveOccurred())
+				}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+				return updated
+			}
+			executions := func() []runbookv1alpha1.RunbookExecution {
+				var list runbookv1alpha1.RunbookExecutionList
+				Expect(k8sClient.List(ctx, &list, client.InNamespace("default"),
+					client.MatchingLabels{"runbook.runbook.io/runbook": resourceName})).To(Succeed())
+				return list.Items
+			}
+
+			updated := runNow("first")
+			Expect(updated.Status.Automation).NotTo(BeNil())
+			Expect(updated.Status.Automation.ManualRun).NotTo(BeNil())
+			Expect(updated.Status.Automation.ManualRun.Nonce).To(Equal("first"))
+			Expect(updated.Status.Automation.TriggerCount).To(BeEquivalentTo(1))
+			Expect(executions()).To(HaveLen(1))
+			execution := executions()[0]
+			Expect(execution.Name).To(Equal(updated.Status.Automation.ManualRun.Execution))
+			Expect(execution.Spec.Trigger.Type).To(Equal("manual"))
+
+			By("Reconciling again with the same nonce")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(executions()).To(HaveLen(1))
+
+			By("Setting a new nonce")
+			updated = runNow("second")
+			Expect(updated.Status.Automation.ManualRun.Nonce).To(Equal("second"))
+			Expect(updated.Status.Automation.TriggerCount).To(BeEquivalentTo(2))
+			Expect(executions()).To(HaveLen(2))
+		})
+	})
 })
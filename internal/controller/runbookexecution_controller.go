@@ -0,0 +1,1093 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+	"github.com/guibes/runbook-operator/pkg/audit"
+	"github.com/guibes/runbook-operator/pkg/commands"
+	"github.com/guibes/runbook-operator/pkg/triggers"
+)
+
+// Types of execution steps
+const (
+	StepInvestigation = "investigation"
+	StepRemediation   = "remediation"
+	StepScript        = "script"
+)
+
+const (
+	// stepContainerName is the name of the container that runs a step
+	stepContainerName = "step"
+
+	// scriptsMountPath is where the scripts ConfigMap is mounted in step containers
+	scriptsMountPath = "/scripts"
+
+	// limitedRequeueInterval is how often an execution waiting for a
+	// concurrency slot checks again
+	limitedRequeueInterval = 30 * time.Second
+
+	// maxStepLogBytes bounds the step logs kept in the execution status
+	maxStepLogBytes = 4096
+
+	// defaultApprovalTimeout is how long medium risk steps wait for an approval
+	defaultApprovalTimeout = time.Hour
+
+	// conditionAwaitingApproval is true while a step waits for an approval
+	conditionAwaitingApproval = "AwaitingApproval"
+)
+
+// RunbookExecutionReconciler runs the automated steps of a Runbook as Jobs
+type RunbookExecutionReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Clientset reads step pods and their logs without caching every pod in the cluster
+	Clientset kubernetes.Interface
+
+	Recorder record.EventRecorder
+
+	// DefaultImage is the step image when the runbook does not set one
+	DefaultImage string
+
+	// DefaultServiceAccount is the step ServiceAccount when the runbook does
+	// not set one
+	DefaultServiceAccount string
+
+	// ServiceAccounts are the ServiceAccounts runbooks may run their steps
+	// as besides DefaultServiceAccount
+	ServiceAccounts []string
+
+	// Audit records the steps and outcome of every execution in the audit log
+	Audit audit.Sink
+}
+
+//+kubebuilder:rbac:groups=runbook.runbook.io,resources=runbookexecutions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=runbook.runbook.io,resources=runbookexecutions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=runbook.runbook.io,resources=runbookexecutions/finalizers,verbs=update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// Reconcile runs the steps of the execution one at a time, each in its own
+// Job, and stops at the first step that fails
+func (r *RunbookExecutionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var execution runbookv1alpha1.RunbookExecution
+	if err := r.Get(ctx, req.NamespacedName, &execution); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get RunbookExecution")
+		return ctrl.Result{}, err
+	}
+
+	if execution.DeletionTimestamp != nil || execution.Finished() {
+		return ctrl.Result{}, r.recordExecution(ctx, &execution)
+	}
+
+	var runbook runbookv1alpha1.Runbook
+	runbookKey := types.NamespacedName{Name: execution.Spec.RunbookName, Namespace: execution.Namespace}
+	if err := r.Get(ctx, runbookKey, &runbook); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, r.finishExecution(ctx, &execution, runbookv1alpha1.ExecutionFailed,
+				fmt.Sprintf("Runbook %s not found", execution.Spec.RunbookName))
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Link the execution to its runbook so it is listed with it and garbage collected
+	if !metav1.IsControlledBy(&execution, &runbook) {
+		original := execution.DeepCopy()
+		if err := controllerutil.SetControllerReference(&runbook, &execution, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		if execution.Labels == nil {
+			execution.Labels = map[string]string{}
+		}
+		execution.Labels[automation.RunbookLabel] = runbook.Name
+		if err := r.Patch(ctx, &execution, client.MergeFrom(original)); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if execution.Status.Phase == "" || execution.Status.Phase == runbookv1alpha1.ExecutionPending {
+		result, admitted, err := r.admitExecution(ctx, &execution, &runbook)
+		if err != nil || !admitted {
+			return result, err
+		}
+		return ctrl.Result{}, r.startExecution(ctx, &execution, &runbook)
+	}
+
+	if err := r.recordExecution(ctx, &execution); err != nil {
+		return ctrl.Result{}, err
+	}
+	return r.runNextStep(ctx, &execution, &runbook)
+}
+
+// admitExecution skips executions the automation of the runbook does not
+// allow, e.g. because it is disabled, and enforces the concurrency and rate
+// limits of the runbook before the execution starts. The executions and start
+// times the limits count are kept in the runbook status, so that they survive
+// operator restarts, and updated with optimistic locking, so that two
+// executions never take the same slot.
+func (r *RunbookExecutionReconciler) admitExecution(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, runbook *runbookv1alpha1.Runbook) (ctrl.Result, bool, error) {
+	if reason, message := automation.CheckAllowed(runbook, execution); reason != "" {
+		return ctrl.Result{}, false, r.skipExecution(ctx, execution, reason, message)
+	}
+	if !automation.HasLimits(runbook.Spec.Content.Automation) {
+		return ctrl.Result{}, true, nil
+	}
+
+	var reason, message string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := r.Get(ctx, client.ObjectKeyFromObject(runbook), runbook); err != nil {
+			return err
+		}
+		original := runbook.DeepCopy()
+		if runbook.Status.Automation == nil {
+			runbook.Status.Automation = &runbookv1alpha1.AutomationStatus{}
+		}
+		status := runbook.Status.Automation
+
+		// Admitted before the execution status was updated
+		if slices.Contains(status.RunningExecutions, execution.Name) {
+			reason, message = "", ""
+			return nil
+		}
+
+		running, err := r.runningExecutions(ctx, execution.Namespace, status.RunningExecutions)
+		if err != nil {
+			return err
+		}
+		status.RunningExecutions = running
+
+		now := time.Now()
+		reason, message = automation.CheckLimits(runbook.Spec.Content.Automation, status, now)
+		if reason == "" {
+			automation.RecordStart(status, execution.Name, now)
+		}
+		return r.Status().Patch(ctx, runbook, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+	})
+	if err != nil {
+		return ctrl.Result{}, false, err
+	}
+
+	switch reason {
+	case "":
+		return ctrl.Result{}, true, nil
+	case automation.ReasonConcurrencyLimited:
+		return ctrl.Result{RequeueAfter: limitedRequeueInterval}, false, r.waitForSlot(ctx, execution, message)
+	default:
+		return ctrl.Result{}, false, r.skipExecution(ctx, execution, reason, message)
+	}
+}
+
+// runningExecutions drops the executions that finished or no longer exist
+func (r *RunbookExecutionReconciler) runningExecutions(ctx context.Context, namespace string, names []string) ([]string, error) {
+	running := []string{}
+	for _, name := range names {
+		var execution runbookv1alpha1.RunbookExecution
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &execution); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if !execution.Finished() {
+			running = append(running, name)
+		}
+	}
+	return running, nil
+}
+
+// waitForSlot keeps the execution pending until another execution of the runbook finishes
+func (r *RunbookExecutionReconciler) waitForSlot(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, message string) error {
+	original := execution.DeepCopy()
+	execution.Status.Phase = runbookv1alpha1.ExecutionPending
+	changed := meta.SetStatusCondition(&execution.Status.Conditions, metav1.Condition{
+		Type:    "Succeeded",
+		Status:  metav1.ConditionFalse,
+		Reason:  automation.ReasonConcurrencyLimited,
+		Message: message,
+	})
+	if !changed && original.Status.Phase == runbookv1alpha1.ExecutionPending {
+		return nil
+	}
+
+	r.Recorder.Eventf(execution, corev1.EventTypeNormal, automation.ReasonConcurrencyLimited, "Waiting to start: %s", message)
+	return r.Status().Patch(ctx, execution, client.MergeFrom(original))
+}
+
+// skipExecution finishes an execution that the automation or the rate limits
+// of the runbook do not allow
+func (r *RunbookExecutionReconciler) skipExecution(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, reason string, message string) error {
+	original := execution.DeepCopy()
+	setExecutionFinished(execution, runbookv1alpha1.ExecutionSkipped, message)
+	meta.FindStatusCondition(execution.Status.Conditions, "Succeeded").Reason = reason
+
+	r.Recorder.Eventf(execution, corev1.EventTypeWarning, reason, "Skipped execution: %s", message)
+	return r.Status().Patch(ctx, execution, client.MergeFrom(original))
+}
+
+// recordExecution updates the execution in the history of the runbook and
+// frees the concurrency slot of a finished execution. The outcome of an
+// execution is written to the audit log once, when its record is finished.
+func (r *RunbookExecutionReconciler) recordExecution(ctx context.Context, execution *runbookv1alpha1.RunbookExecution) error {
+	record := automation.NewExecutionRecord(execution)
+
+	finished := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var runbook runbookv1alpha1.Runbook
+		runbookKey := types.NamespacedName{Name: execution.Spec.RunbookName, Namespace: execution.Namespace}
+		if err := r.Get(ctx, runbookKey, &runbook); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		original := runbook.DeepCopy()
+		if runbook.Status.Automation == nil {
+			runbook.Status.Automation = &runbookv1alpha1.AutomationStatus{}
+		}
+		status := runbook.Status.Automation
+
+		previous := automation.FindRecord(status, execution.Name)
+		wasFinished := previous != nil && previous.CompletionTime != nil
+		released := execution.Finished() && automation.ReleaseExecution(status, execution.Name)
+		recorded := automation.RecordExecution(status, record, automation.HistoryLimit(runbook.Spec.Content.Automation))
+		finished = recorded && record.CompletionTime != nil && !wasFinished
+		if !released && !recorded {
+			return nil
+		}
+		return r.Status().Patch(ctx, &runbook, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+	})
+	if err != nil {
+		return err
+	}
+
+	if finished {
+		r.audit(ctx, execution, audit.Event{
+			Action:  audit.ActionFinished,
+			DryRun:  record.DryRun,
+			Result:  record.Phase,
+			Message: record.Message,
+		})
+	}
+	return nil
+}
+
+// audit records an action on the execution in the audit log
+func (r *RunbookExecutionReconciler) audit(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, event audit.Event) {
+	event.Namespace = execution.Namespace
+	event.Runbook = execution.Spec.RunbookName
+	event.Execution = execution.Name
+	event.DryRun = event.DryRun || execution.Spec.DryRun
+	event.RequestedBy = execution.Annotations[automation.RequestedByAnnotation]
+	if trigger := execution.Spec.Trigger; trigger != nil {
+		event.Trigger = trigger.Type
+		event.Source = trigger.Source
+	}
+
+	if err := r.auditSink().Record(event); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to write audit event", "action", event.Action)
+	}
+}
+
+// auditStep records an action on a step in the audit log
+func (r *RunbookExecutionReconciler) auditStep(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, action string, step *runbookv1alpha1.ExecutionStep) {
+	r.audit(ctx, execution, stepEvent(action, step))
+}
+
+func stepEvent(action string, step *runbookv1alpha1.ExecutionStep) audit.Event {
+	return audit.Event{
+		Action:   action,
+		Actor:    step.ApprovedBy,
+		Step:     step.Name,
+		Command:  step.Command,
+		Script:   step.Script,
+		Risk:     step.Risk,
+		Result:   step.Phase,
+		ExitCode: step.ExitCode,
+		Message:  step.Message,
+	}
+}
+
+func (r *RunbookExecutionReconciler) auditSink() audit.Sink {
+	if r.Audit != nil {
+		return r.Audit
+	}
+	return audit.Discard
+}
+
+// startExecution captures the plan from the runbook, so that later runbook
+// changes do not affect an execution in progress
+func (r *RunbookExecutionReconciler) startExecution(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, runbook *runbookv1alpha1.Runbook) error {
+	original := execution.DeepCopy()
+
+	now := metav1.Now()
+	execution.Status.Phase = runbookv1alpha1.ExecutionRunning
+	execution.Status.StartTime = &now
+	execution.Status.Steps = executionPlan(runbook, triggerLabels(execution))
+	meta.SetStatusCondition(&execution.Status.Conditions, metav1.Condition{
+		Type:    "Succeeded",
+		Status:  metav1.ConditionFalse,
+		Reason:  "Running",
+		Message: fmt.Sprintf("Running %d steps", len(execution.Status.Steps)),
+	})
+
+	r.Recorder.Eventf(execution, corev1.EventTypeNormal, "Started",
+		"Started execution of runbook %s with %d steps", runbook.Name, len(execution.Status.Steps))
+	r.audit(ctx, execution, audit.Event{
+		Action: audit.ActionStarted,
+		DryRun: isDryRun(execution, runbook),
+		Result: runbookv1alpha1.ExecutionRunning,
+		Labels: triggerLabels(execution),
+	})
+
+	if isDryRun(execution, runbook) {
+		r.recordDryRun(ctx, execution, runbook)
+		return r.Status().Patch(ctx, execution, client.MergeFrom(original))
+	}
+
+	// Remediation steps whose command could not be rendered fail before
+	// anything runs, investigation steps are only reported as failed checks
+	updateInvestigationSummary(execution)
+	for i, step := range execution.Status.Steps {
+		if step.Phase == runbookv1alpha1.ExecutionFailed && step.Type != StepInvestigation {
+			skipRemainingSteps(execution, i)
+			r.Recorder.Eventf(execution, corev1.EventTypeWarning, "StepFailed", "Step %s failed: %s", step.Name, step.Message)
+			r.auditStep(ctx, execution, audit.ActionStepFinished, &execution.Status.Steps[i])
+			setExecutionFinished(execution, runbookv1alpha1.ExecutionFailed, fmt.Sprintf("Step %s failed", step.Name))
+			break
+		}
+	}
+	return r.Status().Patch(ctx, execution, client.MergeFrom(original))
+}
+
+// executionPlan lists the automated investigation steps, the automated
+// remediation steps and the automation scripts, with their commands rendered
+// with the trigger labels
+func executionPlan(runbook *runbookv1alpha1.Runbook, labels map[string]string) []runbookv1alpha1.ExecutionStep {
+	steps := []runbookv1alpha1.ExecutionStep{}
+	for i, step := range runbook.Spec.Content.Investigation {
+		if !step.Automated || step.Command == "" {
+			continue
+		}
+		steps = append(steps, renderStep(runbookv1alpha1.ExecutionStep{
+			Name:        fmt.Sprintf("investigation-%d", i),
+			Type:        StepInvestigation,
+			Description: step.Description,
+			Command:     step.Command,
+			Risk:        stepRisk(step.Risk),
+			Expectation: step.Expectation.DeepCopy(),
+			Phase:       runbookv1alpha1.ExecutionPending,
+		}, labels))
+	}
+
+	for i, step := range runbook.Spec.Content.Remediation {
+		if !step.Automated {
+			continue
+		}
+		steps = append(steps, renderStep(runbookv1alpha1.ExecutionStep{
+			Name:        fmt.Sprintf("remediation-%d", i),
+			Type:        StepRemediation,
+			Description: step.Description,
+			Command:     step.Command,
+			Script:      step.Script,
+			Risk:        stepRisk(step.Risk),
+			Phase:       runbookv1alpha1.ExecutionPending,
+		}, labels))
+	}
+
+	if automationConfig := runbook.Spec.Content.Automation; automationConfig != nil {
+		for i, script := range automationConfig.Scripts {
+			steps = append(steps, runbookv1alpha1.ExecutionStep{
+				Name:   fmt.Sprintf("script-%d", i),
+				Type:   StepScript,
+				Script: script,
+				Risk:   stepRisk(automationConfig.ScriptsRisk),
+				Phase:  runbookv1alpha1.ExecutionPending,
+			})
+		}
+	}
+	return steps
+}
+
+// renderStep renders the command of the step, failing the step when a
+// placeholder cannot be rendered
+func renderStep(step runbookv1alpha1.ExecutionStep, labels map[string]string) runbookv1alpha1.ExecutionStep {
+	command, err := commands.Render(step.Command, labels)
+	if err != nil {
+		step.Phase = runbookv1alpha1.ExecutionFailed
+		step.Message = fmt.Sprintf("Failed to render command: %v", err)
+		return step
+	}
+	step.Command = command
+	return step
+}
+
+func triggerLabels(execution *runbookv1alpha1.RunbookExecution) map[string]string {
+	if execution.Spec.Trigger == nil {
+		return nil
+	}
+	return execution.Spec.Trigger.Labels
+}
+
+func isDryRun(execution *runbookv1alpha1.RunbookExecution, runbook *runbookv1alpha1.Runbook) bool {
+	automationConfig := runbook.Spec.Content.Automation
+	return execution.Spec.DryRun || (automationConfig != nil && automationConfig.DryRun)
+}
+
+// recordDryRun describes what every step of the plan would do, in the step
+// status and as events, and finishes the execution without creating any Jobs
+func (r *RunbookExecutionReconciler) recordDryRun(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, runbook *runbookv1alpha1.Runbook) {
+	phase := runbookv1alpha1.ExecutionSucceeded
+	for i := range execution.Status.Steps {
+		step := &execution.Status.Steps[i]
+		if step.Phase == runbookv1alpha1.ExecutionFailed {
+			phase = runbookv1alpha1.ExecutionFailed
+		} else {
+			step.Phase = runbookv1alpha1.ExecutionSkipped
+			step.Message = "Dry run: " + r.dryRunOutcome(ctx, execution, runbook, step)
+		}
+		r.Recorder.Eventf(execution, corev1.EventTypeNormal, "DryRunStep", "Step %s: %s", step.Name, step.Message)
+		event := stepEvent(audit.ActionStepFinished, step)
+		event.DryRun = true
+		r.audit(ctx, execution, event)
+	}
+
+	message := fmt.Sprintf("Dry run of %d steps, no Jobs were created", len(execution.Status.Steps))
+	setExecutionFinished(execution, phase, message)
+	// Tell dry runs apart from executions that ran their steps
+	meta.FindStatusCondition(execution.Status.Conditions, "Succeeded").Reason = "DryRun"
+	r.Recorder.Event(execution, corev1.EventTypeNormal, "DryRun", message)
+}
+
+// dryRunOutcome describes how the step would be gated and what it would run
+func (r *RunbookExecutionReconciler) dryRunOutcome(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, runbook *runbookv1alpha1.Runbook, step *runbookv1alpha1.ExecutionStep) string {
+	var action string
+	switch {
+	case step.Script != "":
+		if err := r.checkScript(ctx, runbook, step.Script); err != nil {
+			return fmt.Sprintf("would fail, %v", err)
+		}
+		action = fmt.Sprintf("script %s", step.Script)
+	case step.Command != "":
+		action = fmt.Sprintf("%q", step.Command)
+	default:
+		return "would fail, the step has neither a command nor a script"
+	}
+
+	switch step.Risk {
+	case runbookv1alpha1.RiskLow:
+		return "would run " + action
+	case runbookv1alpha1.RiskHigh:
+		return "would not run " + action + ", high risk steps are never run automatically"
+	}
+	if approval := findApproval(execution, step.Name); approval != nil {
+		return fmt.Sprintf("would run %s, approved by %s", action, approval.User)
+	}
+	return fmt.Sprintf("would run %s once approved within %s", action, approvalTimeout(runbook))
+}
+
+// stepRisk treats steps without a risk level as medium risk, so that they are
+// not run without an approval
+func stepRisk(risk string) string {
+	if risk == "" {
+		return runbookv1alpha1.RiskMedium
+	}
+	return risk
+}
+
+// runNextStep advances the first step that has not finished
+func (r *RunbookExecutionReconciler) runNextStep(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, runbook *runbookv1alpha1.Runbook) (ctrl.Result, error) {
+	for i := range execution.Status.Steps {
+		switch execution.Status.Steps[i].Phase {
+		case runbookv1alpha1.ExecutionSucceeded, runbookv1alpha1.ExecutionSkipped:
+			continue
+		case runbookv1alpha1.ExecutionPending:
+			return r.gateStep(ctx, execution, runbook, i)
+		case runbookv1alpha1.ExecutionRunning:
+			return r.checkStep(ctx, execution, i)
+		default:
+			if execution.Status.Steps[i].Type == StepInvestigation {
+				continue
+			}
+			return ctrl.Result{}, r.finishExecution(ctx, execution, runbookv1alpha1.ExecutionFailed,
+				fmt.Sprintf("Step %s failed", execution.Status.Steps[i].Name))
+		}
+	}
+
+	message := "All steps succeeded"
+	if summary := execution.Status.Investigation; summary != nil && summary.Failed > 0 {
+		message = fmt.Sprintf("All remediation steps succeeded, %s", summary.Summary)
+	}
+	return ctrl.Result{}, r.finishExecution(ctx, execution, runbookv1alpha1.ExecutionSucceeded, message)
+}
+
+// gateStep starts low risk steps, holds medium risk steps until they are
+// approved and never starts high risk steps
+func (r *RunbookExecutionReconciler) gateStep(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, runbook *runbookv1alpha1.Runbook, index int) (ctrl.Result, error) {
+	original := execution.DeepCopy()
+	step := &execution.Status.Steps[index]
+
+	switch step.Risk {
+	case runbookv1alpha1.RiskLow:
+		return ctrl.Result{}, r.startStep(ctx, execution, original, runbook, index)
+	case runbookv1alpha1.RiskHigh:
+		return ctrl.Result{}, r.holdStep(ctx, execution, original, index)
+	}
+
+	if approval := findApproval(execution, step.Name); approval != nil {
+		step.ApprovedBy = approval.User
+		meta.SetStatusCondition(&execution.Status.Conditions, metav1.Condition{
+			Type:    conditionAwaitingApproval,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Approved",
+			Message: fmt.Sprintf("Step %s was approved by %s", step.Name, approval.User),
+		})
+		r.Recorder.Eventf(execution, corev1.EventTypeNormal, "StepApproved", "Step %s was approved by %s", step.Name, approval.User)
+		r.auditStep(ctx, execution, audit.ActionApproved, step)
+		return ctrl.Result{}, r.startStep(ctx, execution, original, runbook, index)
+	}
+
+	timeout := approvalTimeout(runbook)
+	if step.ApprovalRequestedAt == nil {
+		now := metav1.Now()
+		step.ApprovalRequestedAt = &now
+		step.Message = "Waiting for approval"
+		meta.SetStatusCondition(&execution.Status.Conditions, metav1.Condition{
+			Type:    conditionAwaitingApproval,
+			Status:  metav1.ConditionTrue,
+			Reason:  "AwaitingApproval",
+			Message: fmt.Sprintf("Step %s requires an approval within %s", step.Name, timeout),
+		})
+		r.Recorder.Eventf(execution, corev1.EventTypeNormal, "AwaitingApproval",
+			"Step %s requires an approval within %s", step.Name, timeout)
+		return ctrl.Result{RequeueAfter: timeout}, r.Status().Patch(ctx, execution, client.MergeFrom(original))
+	}
+
+	// Approvals update the spec and trigger a reconciliation, only the timeout needs a requeue
+	if remaining := timeout - time.Since(step.ApprovalRequestedAt.Time); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	meta.SetStatusCondition(&execution.Status.Conditions, metav1.Condition{
+		Type:    conditionAwaitingApproval,
+		Status:  metav1.ConditionFalse,
+		Reason:  "ApprovalTimedOut",
+		Message: fmt.Sprintf("Step %s was not approved within %s", step.Name, timeout),
+	})
+	return ctrl.Result{}, r.failStep(ctx, execution, original, index, nil,
+		fmt.Sprintf("Not approved within %s", timeout))
+}
+
+// holdStep stops the execution at a step that must be run manually
+func (r *RunbookExecutionReconciler) holdStep(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, original *runbookv1alpha1.RunbookExecution, index int) error {
+	step := &execution.Status.Steps[index]
+	step.Phase = runbookv1alpha1.ExecutionSkipped
+	step.Message = "High risk steps are never run automatically"
+	skipRemainingSteps(execution, index)
+
+	r.Recorder.Eventf(execution, corev1.EventTypeWarning, "ManualStepRequired",
+		"Step %s is high risk and must be run manually", step.Name)
+	r.auditStep(ctx, execution, audit.ActionStepFinished, step)
+	setExecutionFinished(execution, runbookv1alpha1.ExecutionFailed, fmt.Sprintf("Step %s must be run manually", step.Name))
+	return r.Status().Patch(ctx, execution, client.MergeFrom(original))
+}
+
+func findApproval(execution *runbookv1alpha1.RunbookExecution, stepName string) *runbookv1alpha1.Approval {
+	for i := range execution.Spec.Approvals {
+		if execution.Spec.Approvals[i].Step == stepName {
+			return &execution.Spec.Approvals[i]
+		}
+	}
+	return nil
+}
+
+func approvalTimeout(runbook *runbookv1alpha1.Runbook) time.Duration {
+	if automationConfig := runbook.Spec.Content.Automation; automationConfig != nil &&
+		automationConfig.ApprovalTimeout != nil && automationConfig.ApprovalTimeout.Duration > 0 {
+		return automationConfig.ApprovalTimeout.Duration
+	}
+	return defaultApprovalTimeout
+}
+
+// startStep creates the Job of the step
+func (r *RunbookExecutionReconciler) startStep(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, original *runbookv1alpha1.RunbookExecution, runbook *runbookv1alpha1.Runbook, index int) error {
+	logger := log.FromContext(ctx)
+	step := &execution.Status.Steps[index]
+
+	if step.Script != "" {
+		if err := r.checkScript(ctx, runbook, step.Script); err != nil {
+			return r.failStep(ctx, execution, original, index, nil, err.Error())
+		}
+	} else if step.Command == "" {
+		return r.failStep(ctx, execution, original, index, nil, "Step has neither a command nor a script")
+	}
+	if automationConfig := runbook.Spec.Content.Automation; automationConfig != nil {
+		if err := automation.CheckServiceAccount(automationConfig.ServiceAccountName, r.DefaultServiceAccount, r.ServiceAccounts); err != nil {
+			return r.failStep(ctx, execution, original, index, nil, err.Error())
+		}
+	}
+
+	job := r.jobForStep(execution, runbook, index)
+	if err := controllerutil.SetControllerReference(execution, job, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, job); err != nil && !errors.IsAlreadyExists(err) {
+		logger.Error(err, "Failed to create Job for step", "step", step.Name)
+		return err
+	}
+
+	now := metav1.Now()
+	step.Phase = runbookv1alpha1.ExecutionRunning
+	step.JobName = job.Name
+	step.StartTime = &now
+	step.Message = ""
+
+	logger.Info("Started step", "step", step.Name, "job", job.Name)
+	r.Recorder.Eventf(execution, corev1.EventTypeNormal, "StepStarted", "Started step %s in Job %s", step.Name, job.Name)
+	r.auditStep(ctx, execution, audit.ActionStepStarted, step)
+	return r.Status().Patch(ctx, execution, client.MergeFrom(original))
+}
+
+// checkScript verifies that the scripts ConfigMap holds the script, a missing
+// key would otherwise leave the step pod unable to start
+func (r *RunbookExecutionReconciler) checkScript(ctx context.Context, runbook *runbookv1alpha1.Runbook, script string) error {
+	automationConfig := runbook.Spec.Content.Automation
+	if automationConfig == nil || automationConfig.ScriptsConfigMap == "" {
+		return fmt.Errorf("script %q requires automation.scriptsConfigMap to be set", script)
+	}
+
+	var configMap corev1.ConfigMap
+	key := types.NamespacedName{Name: automationConfig.ScriptsConfigMap, Namespace: runbook.Namespace}
+	if err := r.Get(ctx, key, &configMap); err != nil {
+		return fmt.Errorf("failed to get scripts ConfigMap %s: %v", automationConfig.ScriptsConfigMap, err)
+	}
+	if _, ok := configMap.Data[script]; !ok {
+		return fmt.Errorf("script %q not found in ConfigMap %s", script, automationConfig.ScriptsConfigMap)
+	}
+	return nil
+}
+
+// checkStep records the result of the step once its Job finished
+func (r *RunbookExecutionReconciler) checkStep(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, index int) (ctrl.Result, error) {
+	original := execution.DeepCopy()
+	step := &execution.Status.Steps[index]
+
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Name: step.JobName, Namespace: execution.Namespace}, &job); err != nil {
+		if errors.IsNotFound(err) {
+			if step.StartTime != nil && time.Since(step.StartTime.Time) < time.Minute {
+				// The Job may not be in the cache yet
+				return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+			}
+			return ctrl.Result{}, r.failStep(ctx, execution, original, index, nil, fmt.Sprintf("Job %s not found", step.JobName))
+		}
+		return ctrl.Result{}, err
+	}
+
+	var succeeded bool
+	switch {
+	case jobHasCondition(&job, batchv1.JobComplete):
+		succeeded = true
+	case jobHasCondition(&job, batchv1.JobFailed):
+		succeeded = false
+	default:
+		// Job updates trigger the next reconciliation
+		return ctrl.Result{}, nil
+	}
+
+	exitCode, logs := r.stepResult(ctx, &job)
+	if step.Type == StepInvestigation {
+		r.checkInvestigation(ctx, execution, index, succeeded, exitCode, logs)
+		return ctrl.Result{}, r.Status().Patch(ctx, execution, client.MergeFrom(original))
+	}
+	if !succeeded {
+		return ctrl.Result{}, r.failStep(ctx, execution, original, index, exitCode, logs)
+	}
+
+	now := metav1.Now()
+	step.Phase = runbookv1alpha1.ExecutionSucceeded
+	step.CompletionTime = &now
+	step.ExitCode = exitCode
+	step.Logs = logs
+	r.Recorder.Eventf(execution, corev1.EventTypeNormal, "StepSucceeded", "Step %s succeeded", step.Name)
+	r.auditStep(ctx, execution, audit.ActionStepFinished, step)
+	return ctrl.Result{}, r.Status().Patch(ctx, execution, client.MergeFrom(original))
+}
+
+// checkInvestigation records the output of an investigation step and whether
+// it met its expectation
+func (r *RunbookExecutionReconciler) checkInvestigation(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, index int, succeeded bool, exitCode *int32, logs string) {
+	step := &execution.Status.Steps[index]
+	if exitCode == nil && succeeded {
+		exitCode = ptr.To[int32](0)
+	}
+
+	now := metav1.Now()
+	step.CompletionTime = &now
+	step.ExitCode = exitCode
+	step.Logs = logs
+
+	passed, message := checkExpectation(step.Expectation, exitCode, logs)
+	step.Message = message
+	if passed {
+		step.Phase = runbookv1alpha1.ExecutionSucceeded
+		r.Recorder.Eventf(execution, corev1.EventTypeNormal, "CheckPassed", "Investigation step %s: %s", step.Name, message)
+	} else {
+		step.Phase = runbookv1alpha1.ExecutionFailed
+		r.Recorder.Eventf(execution, corev1.EventTypeWarning, "CheckFailed", "Investigation step %s: %s", step.Name, message)
+	}
+	r.auditStep(ctx, execution, audit.ActionStepFinished, step)
+	updateInvestigationSummary(execution)
+}
+
+// checkExpectation evaluates the expectation against the result of an
+// investigation command. Without an expectation the command must exit with 0.
+func checkExpectation(expectation *runbookv1alpha1.Expectation, exitCode *int32, output string) (bool, string) {
+	if expectation == nil {
+		expectation = &runbookv1alpha1.Expectation{}
+	}
+
+	expectedExitCode := ptr.Deref(expectation.ExitCode, 0)
+	if exitCode == nil {
+		return false, "The exit code of the command is unknown"
+	}
+	if *exitCode != expectedExitCode {
+		return false, fmt.Sprintf("Expected exit code %d, got %d", expectedExitCode, *exitCode)
+	}
+
+	if expectation.Regex != "" {
+		re, err := regexp.Compile(expectation.Regex)
+		if err != nil {
+			return false, fmt.Sprintf("Invalid regex: %v", err)
+		}
+		if !re.MatchString(output) {
+			return false, fmt.Sprintf("Output does not match %q", expectation.Regex)
+		}
+	}
+
+	if expectation.Threshold != "" {
+		value := lastLine(output)
+		condition, err := triggers.Parse(thresholdKey + expectation.Threshold)
+		if err != nil {
+			return false, fmt.Sprintf("Invalid threshold: %v", err)
+		}
+		met, err := condition.Evaluate(triggers.Labels{thresholdKey: value})
+		if err != nil {
+			return false, fmt.Sprintf("Cannot compare %q: %v", value, err)
+		}
+		if !met {
+			return false, fmt.Sprintf("Output %s does not meet threshold %s", value, expectation.Threshold)
+		}
+	}
+
+	return true, "Expectation met"
+}
+
+// thresholdKey names the output in the condition a threshold is evaluated as
+const thresholdKey = "output"
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// updateInvestigationSummary counts the investigation steps that passed and failed
+func updateInvestigationSummary(execution *runbookv1alpha1.RunbookExecution) {
+	var total, passed, failed int32
+	for _, step := range execution.Status.Steps {
+		if step.Type != StepInvestigation {
+			continue
+		}
+		total++
+		switch step.Phase {
+		case runbookv1alpha1.ExecutionSucceeded:
+			passed++
+		case runbookv1alpha1.ExecutionFailed:
+			failed++
+		}
+	}
+	if total == 0 {
+		return
+	}
+
+	execution.Status.Investigation = &runbookv1alpha1.InvestigationSummary{
+		Passed:  passed,
+		Failed:  failed,
+		Summary: fmt.Sprintf("%d of %d checks passed", passed, total),
+	}
+}
+
+// failStep marks the step as failed and skips the remaining steps
+func (r *RunbookExecutionReconciler) failStep(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, original *runbookv1alpha1.RunbookExecution, index int, exitCode *int32, output string) error {
+	now := metav1.Now()
+	step := &execution.Status.Steps[index]
+	step.Phase = runbookv1alpha1.ExecutionFailed
+	step.CompletionTime = &now
+	step.ExitCode = exitCode
+	if step.JobName != "" {
+		step.Logs = output
+		step.Message = "Job failed"
+	} else {
+		step.Message = output
+	}
+
+	skipRemainingSteps(execution, index)
+
+	r.Recorder.Eventf(execution, corev1.EventTypeWarning, "StepFailed", "Step %s failed: %s", step.Name, step.Message)
+	r.auditStep(ctx, execution, audit.ActionStepFinished, step)
+	setExecutionFinished(execution, runbookv1alpha1.ExecutionFailed, fmt.Sprintf("Step %s failed", step.Name))
+	return r.Status().Patch(ctx, execution, client.MergeFrom(original))
+}
+
+// skipRemainingSteps skips the steps after the one that stopped the execution
+func skipRemainingSteps(execution *runbookv1alpha1.RunbookExecution, index int) {
+	for i := index + 1; i < len(execution.Status.Steps); i++ {
+		execution.Status.Steps[i].Phase = runbookv1alpha1.ExecutionSkipped
+		execution.Status.Steps[i].Message = fmt.Sprintf("Skipped after step %s", execution.Status.Steps[index].Name)
+	}
+}
+
+// finishExecution sets the final phase of the execution
+func (r *RunbookExecutionReconciler) finishExecution(ctx context.Context, execution *runbookv1alpha1.RunbookExecution, phase string, message string) error {
+	original := execution.DeepCopy()
+	setExecutionFinished(execution, phase, message)
+
+	eventType := corev1.EventTypeNormal
+	if phase == runbookv1alpha1.ExecutionFailed {
+		eventType = corev1.EventTypeWarning
+	}
+	r.Recorder.Event(execution, eventType, "Execution"+executionReason(phase), message)
+	return r.Status().Patch(ctx, execution, client.MergeFrom(original))
+}
+
+func setExecutionFinished(execution *runbookv1alpha1.RunbookExecution, phase string, message string) {
+	now := metav1.Now()
+	execution.Status.Phase = phase
+	execution.Status.CompletionTime = &now
+
+	status := metav1.ConditionTrue
+	if phase != runbookv1alpha1.ExecutionSucceeded {
+		status = metav1.ConditionFalse
+	}
+	meta.SetStatusCondition(&execution.Status.Conditions, metav1.Condition{
+		Type:    "Succeeded",
+		Status:  status,
+		Reason:  executionReason(phase),
+		Message: message,
+	})
+}
+
+func executionReason(phase string) string {
+	switch phase {
+	case runbookv1alpha1.ExecutionSucceeded:
+		return "Succeeded"
+	case runbookv1alpha1.ExecutionSkipped:
+		return "Skipped"
+	}
+	return "Failed"
+}
+
+// jobForStep builds the Job that runs a single step
+func (r *RunbookExecutionReconciler) jobForStep(execution *runbookv1alpha1.RunbookExecution, runbook *runbookv1alpha1.Runbook, index int) *batchv1.Job {
+	step := execution.Status.Steps[index]
+	automationConfig := runbook.Spec.Content.Automation
+	if automationConfig == nil {
+		automationConfig = &runbookv1alpha1.AutomationConfig{}
+	}
+
+	// The image and ServiceAccount come from the runbook only, so that
+	// creating an execution does not allow choosing what the steps run as.
+	// startStep checked that the operator allows the ServiceAccount.
+	image := firstNonEmpty(automationConfig.Image, r.DefaultImage)
+	serviceAccount := firstNonEmpty(automationConfig.ServiceAccountName, r.DefaultServiceAccount)
+
+	labels := map[string]string{
+		automation.RunbookLabel:   runbook.Name,
+		automation.ExecutionLabel: execution.Name,
+	}
+
+	container := corev1.Container{
+		Name:    stepContainerName,
+		Image:   image,
+		Command: []string{"/bin/sh", "-c", step.Command},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: ptr.To(false),
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		},
+	}
+
+	var volumes []corev1.Volume
+	if step.Script != "" {
+		container.Command = []string{"/bin/sh", scriptsMountPath + "/" + step.Script}
+		container.VolumeMounts = []corev1.VolumeMount{{Name: "scripts", MountPath: scriptsMountPath, ReadOnly: true}}
+		volumes = []corev1.Volume{{
+			Name: "scripts",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: automationConfig.ScriptsConfigMap},
+					Items:                []corev1.KeyToPath{{Key: step.Script, Path: step.Script}},
+				},
+			},
+		}}
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      stepJobName(execution.Name, index),
+			Namespace: execution.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			// A failed step fails the execution, it is never retried
+			BackoffLimit: ptr.To[int32](0),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: serviceAccount,
+					Containers:         []corev1.Container{container},
+					Volumes:            volumes,
+					SecurityContext: &corev1.PodSecurityContext{
+						SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+					},
+				},
+			},
+		},
+	}
+}
+
+// stepJobName derives a Job name that stays within the 63 characters allowed in label values
+func stepJobName(executionName string, index int) string {
+	suffix := fmt.Sprintf("-%d", index)
+	if len(executionName)+len(suffix) <= 63 {
+		return executionName + suffix
+	}
+
+	sum := sha256.Sum256([]byte(executionName))
+	hash := hex.EncodeToString(sum[:])[:8]
+	return executionName[:63-len(suffix)-len(hash)-1] + "-" + hash + suffix
+}
+
+// stepResult reads the exit code and the tail of the logs of the step pod
+func (r *RunbookExecutionReconciler) stepResult(ctx context.Context, job *batchv1.Job) (*int32, string) {
+	logger := log.FromContext(ctx)
+
+	pods, err := r.Clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: batchv1.JobNameLabel + "=" + job.Name,
+	})
+	if err != nil || len(pods.Items) == 0 {
+		logger.Info("No pod found for step Job", "job", job.Name, "error", err)
+		return nil, ""
+	}
+	pod := pods.Items[len(pods.Items)-1]
+
+	var exitCode *int32
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == stepContainerName && status.State.Terminated != nil {
+			exitCode = ptr.To(status.State.Terminated.ExitCode)
+		}
+	}
+
+	stream, err := r.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:  stepContainerName,
+		LimitBytes: ptr.To[int64](4 * maxStepLogBytes),
+	}).Stream(ctx)
+	if err != nil {
+		logger.Info("Failed to read step logs", "pod", pod.Name, "error", err)
+		return exitCode, ""
+	}
+	defer stream.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, stream); err != nil {
+		logger.Info("Failed to read step logs", "pod", pod.Name, "error", err)
+	}
+	return exitCode, tailString(buf.String(), maxStepLogBytes)
+}
+
+// tailString keeps the last maxBytes of s without splitting a UTF-8 character
+func tailString(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	start := len(s) - maxBytes
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return s[start:]
+}
+
+func jobHasCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RunbookExecutionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&runbookv1alpha1.RunbookExecution{}).
+		Owns(&batchv1.Job{}).
+		Named("runbookexecution").
+		Complete(r)
+}
@@ -0,0 +1,536 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+	"github.com/guibes/runbook-operator/pkg/audit"
+)
+
+var _ = Describe("RunbookExecution Controller", func() {
+	Context("When planning an execution", func() {
+		It("should run the automated steps followed by the automation scripts", func() {
+			runbook := &runbookv1alpha1.Runbook{
+				Spec: runbookv1alpha1.RunbookSpec{
+					Content: runbookv1alpha1.RunbookContent{
+						Remediation: []runbookv1alpha1.RemediationStep{
+							{Description: "Scale up", Command: "kubectl scale deploy/api --replicas=3", Risk: "low", Automated: true},
+							{Description: "Page the database team"},
+							{Description: "Flush the cache", Script: "flush-cache.sh", Automated: true},
+						},
+						Automation: &runbookv1alpha1.AutomationConfig{
+							Scripts:          []string{"verify.sh"},
+							ScriptsConfigMap: "api-scripts",
+						},
+					},
+				},
+			}
+
+			steps := executionPlan(runbook, nil)
+			Expect(steps).To(HaveLen(3))
+			Expect(steps[0].Name).To(Equal("remediation-0"))
+			Expect(steps[0].Command).To(Equal("kubectl scale deploy/api --replicas=3"))
+			Expect(steps[1].Name).To(Equal("remediation-2"))
+			Expect(steps[1].Script).To(Equal("flush-cache.sh"))
+			Expect(steps[2].Name).To(Equal("script-0"))
+			Expect(steps[2].Script).To(Equal("verify.sh"))
+
+			By("Treating steps without a risk level as medium risk")
+			Expect(steps[0].Risk).To(Equal(runbookv1alpha1.RiskLow))
+			Expect(steps[1].Risk).To(Equal(runbookv1alpha1.RiskMedium))
+			Expect(steps[2].Risk).To(Equal(runbookv1alpha1.RiskMedium))
+			for _, step := range steps {
+				Expect(step.Phase).To(Equal(runbookv1alpha1.ExecutionPending))
+			}
+		})
+
+		It("should render the commands with the trigger labels", func() {
+			runbook := &runbookv1alpha1.Runbook{
+				Spec: runbookv1alpha1.RunbookSpec{
+					Content: runbookv1alpha1.RunbookContent{
+						Remediation: []runbookv1alpha1.RemediationStep{
+							{Description: "Restart", Command: "kubectl -n {{ .Labels.namespace }} rollout restart deploy/{{ .Labels.deployment }}", Automated: true},
+							{Description: "Delete the pod", Command: "kubectl delete pod {{ .Labels.pod }}", Automated: true},
+						},
+					},
+				},
+			}
+
+			steps := executionPlan(runbook, map[string]string{"namespace": "payments", "deployment": "api"})
+			Expect(steps[0].Command).To(Equal("kubectl -n payments rollout restart deploy/api"))
+			Expect(steps[0].Phase).To(Equal(runbookv1alpha1.ExecutionPending))
+
+			By("Failing steps whose placeholders have no label")
+			Expect(steps[1].Phase).To(Equal(runbookv1alpha1.ExecutionFailed))
+			Expect(steps[1].Message).To(ContainSubstring("pod"))
+		})
+
+		It("should run the automated investigation steps first", func() {
+			runbook := &runbookv1alpha1.Runbook{
+				Spec: runbookv1alpha1.RunbookSpec{
+					Content: runbookv1alpha1.RunbookContent{
+						Investigation: []runbookv1alpha1.InvestigationStep{
+							{Description: "Check the dashboard"},
+							{
+								Description: "Count the restarts",
+								Command:     "kubectl -n {{ .Labels.namespace }} get pods --no-headers | wc -l",
+								Automated:   true,
+								Risk:        "low",
+								Expectation: &runbookv1alpha1.Expectation{Threshold: "<10"},
+							},
+							{Description: "Delete the stuck pods", Command: "kubectl delete pods -l app=api", Automated: true},
+						},
+						Remediation: []runbookv1alpha1.RemediationStep{
+							{Description: "Scale up", Command: "kubectl scale deploy/api --replicas=3", Risk: "low", Automated: true},
+						},
+					},
+				},
+			}
+
+			steps := executionPlan(runbook, map[string]string{"namespace": "payments"})
+			Expect(steps).To(HaveLen(3))
+			Expect(steps[0].Name).To(Equal("investigation-1"))
+			Expect(steps[0].Type).To(Equal(StepInvestigation))
+			Expect(steps[0].Command).To(Equal("kubectl -n payments get pods --no-headers | wc -l"))
+			Expect(steps[0].Risk).To(Equal(runbookv1alpha1.RiskLow))
+			Expect(steps[0].Expectation.Threshold).To(Equal("<10"))
+
+			By("Gating investigation steps without a risk like remediation steps")
+			Expect(steps[1].Name).To(Equal("investigation-2"))
+			Expect(steps[1].Risk).To(Equal(runbookv1alpha1.RiskMedium))
+			Expect(steps[2].Type).To(Equal(StepRemediation))
+		})
+
+		It("should check the output of investigation steps against their expectation", func() {
+			passed, _ := checkExpectation(nil, ptr.To[int32](0), "")
+			Expect(passed).To(BeTrue())
+
+			passed, message := checkExpectation(nil, ptr.To[int32](1), "")
+			Expect(passed).To(BeFalse())
+			Expect(message).To(Equal("Expected exit code 0, got 1"))
+
+			passed, _ = checkExpectation(&runbookv1alpha1.Expectation{ExitCode: ptr.To[int32](1)}, ptr.To[int32](1), "")
+			Expect(passed).To(BeTrue())
+
+			passed, _ = checkExpectation(&runbookv1alpha1.Expectation{Regex: "^Running$"}, ptr.To[int32](0), "Pending\nRunning\n")
+			Expect(passed).To(BeFalse())
+			passed, _ = checkExpectation(&runbookv1alpha1.Expectation{Regex: "(?m)^Running$"}, ptr.To[int32](0), "Pending\nRunning\n")
+			Expect(passed).To(BeTrue())
+
+			By("Comparing the last line of the output with the threshold")
+			passed, _ = checkExpectation(&runbookv1alpha1.Expectation{Threshold: "<80"}, ptr.To[int32](0), "usage\n42\n")
+			Expect(passed).To(BeTrue())
+			passed, message = checkExpectation(&runbookv1alpha1.Expectation{Threshold: "<80"}, ptr.To[int32](0), "usage\n95\n")
+			Expect(passed).To(BeFalse())
+			Expect(message).To(Equal("Output 95 does not meet threshold <80"))
+			passed, _ = checkExpectation(&runbookv1alpha1.Expectation{Threshold: ">=5m"}, ptr.To[int32](0), "10m")
+			Expect(passed).To(BeTrue())
+		})
+
+		It("should keep Job names within the label value limit", func() {
+			Expect(stepJobName("short", 2)).To(Equal("short-2"))
+
+			long := stepJobName("a-very-long-runbook-execution-name-that-does-not-fit-in-a-label-value", 12)
+			Expect(len(long)).To(BeNumerically("<=", 63))
+			Expect(long).To(HaveSuffix("-12"))
+		})
+	})
+
+	Context("When reconciling an execution", func() {
+		const runbookName = "test-execution-runbook"
+
+		ctx := context.Background()
+
+		var (
+			runbook              *runbookv1alpha1.Runbook
+			execution            *runbookv1alpha1.RunbookExecution
+			controllerReconciler *RunbookExecutionReconciler
+		)
+
+		BeforeEach(func() {
+			runbook = &runbookv1alpha1.Runbook{
+				ObjectMeta: metav1.ObjectMeta{Name: runbookName, Namespace: "default"},
+				Spec: runbookv1alpha1.RunbookSpec{
+					AlertName: "ExecutionTest",
+					Content: runbookv1alpha1.RunbookContent{
+						Remediation: []runbookv1alpha1.RemediationStep{
+							{Description: "Restart", Command: "kubectl rollout restart deploy/api", Risk: "low", Automated: true},
+							{Description: "Flush the cache", Script: "flush-cache.sh", Risk: "low", Automated: true},
+							{Description: "Scale up", Command: "kubectl scale deploy/api --replicas=3", Risk: "medium", Automated: true},
+						},
+						Automation: &runbookv1alpha1.AutomationConfig{
+							Enabled:          true,
+							Image:            "registry.example.com/kubectl:1.33",
+							ScriptsConfigMap: "missing-scripts",
+							Triggers:         []runbookv1alpha1.TriggerConfig{{Type: "manual"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, runbook)).To(Succeed())
+
+			execution = &runbookv1alpha1.RunbookExecution{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-execution", Namespace: "default"},
+				Spec:       runbookv1alpha1.RunbookExecutionSpec{RunbookName: runbookName},
+			}
+			Expect(k8sClient.Create(ctx, execution)).To(Succeed())
+
+			controllerReconciler = &RunbookExecutionReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Clientset:    kubefake.NewClientset(),
+				Recorder:     record.NewFakeRecorder(20),
+				DefaultImage: "bitnami/kubectl:1.33.3",
+			}
+		})
+
+		AfterEach(func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, execution))).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, runbook))).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"runbook.runbook.io/runbook": runbookName})).To(Succeed())
+		})
+
+		reconcileExecution := func(times int) *runbookv1alpha1.RunbookExecution {
+			namespacedName := types.NamespacedName{Name: execution.Name, Namespace: execution.Namespace}
+			for range times {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: namespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			updated := &runbookv1alpha1.RunbookExecution{}
+			Expect(k8sClient.Get(ctx, namespacedName, updated)).To(Succeed())
+			return updated
+		}
+
+		It("should run the first step in a Job owned by the execution", func() {
+			updated := reconcileExecution(2)
+			Expect(metav1.IsControlledBy(updated, runbook)).To(BeTrue())
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionRunning))
+			Expect(updated.Status.Steps).To(HaveLen(3))
+			Expect(updated.Status.Steps[0].Phase).To(Equal(runbookv1alpha1.ExecutionRunning))
+
+			job := &batchv1.Job{}
+			jobKey := types.NamespacedName{Name: updated.Status.Steps[0].JobName, Namespace: "default"}
+			Expect(k8sClient.Get(ctx, jobKey, job)).To(Succeed())
+			Expect(metav1.IsControlledBy(job, updated)).To(BeTrue())
+			Expect(*job.Spec.BackoffLimit).To(BeZero())
+
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("registry.example.com/kubectl:1.33"))
+			Expect(container.Command).To(Equal([]string{"/bin/sh", "-c", "kubectl rollout restart deploy/api"}))
+		})
+
+		It("should fail steps that run as a ServiceAccount the operator does not allow", func() {
+			runbook.Spec.Content.Automation.ServiceAccountName = "cluster-admin"
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+			updated := reconcileExecution(2)
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionFailed))
+			Expect(updated.Status.Steps[0].Phase).To(Equal(runbookv1alpha1.ExecutionFailed))
+			Expect(updated.Status.Steps[0].Message).To(ContainSubstring("not as cluster-admin"))
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"runbook.runbook.io/runbook": runbookName})).To(Succeed())
+			Expect(jobs.Items).To(BeEmpty())
+
+			By("Running the steps as a ServiceAccount the operator allows")
+			Expect(k8sClient.Delete(ctx, execution)).To(Succeed())
+			execution = &runbookv1alpha1.RunbookExecution{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-execution-allowed", Namespace: "default"},
+				Spec:       runbookv1alpha1.RunbookExecutionSpec{RunbookName: runbookName},
+			}
+			Expect(k8sClient.Create(ctx, execution)).To(Succeed())
+			controllerReconciler.ServiceAccounts = []string{"cluster-admin"}
+
+			updated = reconcileExecution(2)
+			job := &batchv1.Job{}
+			jobKey := types.NamespacedName{Name: updated.Status.Steps[0].JobName, Namespace: "default"}
+			Expect(k8sClient.Get(ctx, jobKey, job)).To(Succeed())
+			Expect(job.Spec.Template.Spec.ServiceAccountName).To(Equal("cluster-admin"))
+		})
+
+		It("should stop at the first failed step and skip the rest", func() {
+			updated := reconcileExecution(2)
+
+			By("Completing the first step")
+			job := &batchv1.Job{}
+			jobKey := types.NamespacedName{Name: updated.Status.Steps[0].JobName, Namespace: "default"}
+			Expect(k8sClient.Get(ctx, jobKey, job)).To(Succeed())
+			now := metav1.Now()
+			job.Status.StartTime = &now
+			job.Status.CompletionTime = &now
+			job.Status.Succeeded = 1
+			job.Status.Conditions = []batchv1.JobCondition{
+				{Type: batchv1.JobSuccessCriteriaMet, Status: "True", LastTransitionTime: now},
+				{Type: batchv1.JobComplete, Status: "True", LastTransitionTime: now},
+			}
+			Expect(k8sClient.Status().Update(ctx, job)).To(Succeed())
+
+			By("Running the script step whose ConfigMap does not exist")
+			updated = reconcileExecution(2)
+			Expect(updated.Status.Steps[0].Phase).To(Equal(runbookv1alpha1.ExecutionSucceeded))
+			Expect(updated.Status.Steps[1].Phase).To(Equal(runbookv1alpha1.ExecutionFailed))
+			Expect(updated.Status.Steps[1].Message).To(ContainSubstring("missing-scripts"))
+			Expect(updated.Status.Steps[2].Phase).To(Equal(runbookv1alpha1.ExecutionSkipped))
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionFailed))
+			Expect(updated.Status.CompletionTime).NotTo(BeNil())
+		})
+
+		It("should report failed investigation checks without stopping the execution", func() {
+			runbook.Spec.Content.Investigation = []runbookv1alpha1.InvestigationStep{
+				{Description: "Check the rollout", Command: "kubectl rollout status deploy/api", Automated: true, Risk: "low"},
+			}
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+			updated := reconcileExecution(2)
+			Expect(updated.Status.Steps).To(HaveLen(4))
+			Expect(updated.Status.Steps[0].Type).To(Equal(StepInvestigation))
+			Expect(updated.Status.Steps[0].Phase).To(Equal(runbookv1alpha1.ExecutionRunning))
+			Expect(updated.Status.Investigation.Summary).To(Equal("0 of 1 checks passed"))
+
+			By("Failing the investigation Job")
+			job := &batchv1.Job{}
+			jobKey := types.NamespacedName{Name: updated.Status.Steps[0].JobName, Namespace: "default"}
+			Expect(k8sClient.Get(ctx, jobKey, job)).To(Succeed())
+			now := metav1.Now()
+			job.Status.StartTime = &now
+			job.Status.Failed = 1
+			job.Status.Conditions = []batchv1.JobCondition{
+				{Type: batchv1.JobFailureTarget, Status: "True", LastTransitionTime: now},
+				{Type: batchv1.JobFailed, Status: "True", LastTransitionTime: now},
+			}
+			Expect(k8sClient.Status().Update(ctx, job)).To(Succeed())
+
+			updated = reconcileExecution(2)
+			Expect(updated.Status.Steps[0].Phase).To(Equal(runbookv1alpha1.ExecutionFailed))
+			Expect(updated.Status.Investigation.Failed).To(BeEquivalentTo(1))
+			Expect(updated.Status.Steps[1].Phase).To(Equal(runbookv1alpha1.ExecutionRunning))
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionRunning))
+		})
+
+		It("should wait for an approval before running a medium risk step", func() {
+			runbook.Spec.Content.Remediation[0].Risk = runbookv1alpha1.RiskMedium
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+			updated := reconcileExecution(2)
+			step := updated.Status.Steps[0]
+			Expect(step.Phase).To(Equal(runbookv1alpha1.ExecutionPending))
+			Expect(step.JobName).To(BeEmpty())
+			Expect(step.ApprovalRequestedAt).NotTo(BeNil())
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, "AwaitingApproval")).To(BeTrue())
+
+			By("Approving the step")
+			updated.Spec.Approvals = []runbookv1alpha1.Approval{{Step: step.Name, User: "alice"}}
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+
+			updated = reconcileExecution(1)
+			Expect(updated.Status.Steps[0].Phase).To(Equal(runbookv1alpha1.ExecutionRunning))
+			Expect(updated.Status.Steps[0].ApprovedBy).To(Equal("alice"))
+			Expect(updated.Status.Steps[0].JobName).NotTo(BeEmpty())
+			Expect(meta.IsStatusConditionFalse(updated.Status.Conditions, "AwaitingApproval")).To(BeTrue())
+		})
+
+		It("should fail the step when the approval times out", func() {
+			runbook.Spec.Content.Remediation[0].Risk = runbookv1alpha1.RiskMedium
+			runbook.Spec.Content.Automation.ApprovalTimeout = &metav1.Duration{Duration: time.Millisecond}
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+			reconcileExecution(2)
+			time.Sleep(10 * time.Millisecond)
+
+			updated := reconcileExecution(1)
+			Expect(updated.Status.Steps[0].Phase).To(Equal(runbookv1alpha1.ExecutionFailed))
+			Expect(updated.Status.Steps[0].Message).To(ContainSubstring("Not approved within"))
+			Expect(updated.Status.Steps[1].Phase).To(Equal(runbookv1alpha1.ExecutionSkipped))
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionFailed))
+			condition := meta.FindStatusCondition(updated.Status.Conditions, "AwaitingApproval")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("ApprovalTimedOut"))
+		})
+
+		It("should record the plan of a dry run without creating Jobs", func() {
+			runbook.Spec.Content.Remediation[0].Command = "kubectl -n {{ .Labels.namespace }} rollout restart deploy/api"
+			runbook.Spec.Content.Automation.DryRun = true
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+			execution.Spec.Trigger = &runbookv1alpha1.TriggerEvent{
+				Type:        "manual",
+				Labels:      map[string]string{"namespace": "payments"},
+				TriggeredAt: metav1.Now(),
+			}
+			Expect(k8sClient.Update(ctx, execution)).To(Succeed())
+
+			updated := reconcileExecution(2)
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionSucceeded))
+			condition := meta.FindStatusCondition(updated.Status.Conditions, "Succeeded")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("DryRun"))
+
+			Expect(updated.Status.Steps).To(HaveLen(3))
+			Expect(updated.Status.Steps[0].Phase).To(Equal(runbookv1alpha1.ExecutionSkipped))
+			Expect(updated.Status.Steps[0].Command).To(Equal("kubectl -n payments rollout restart deploy/api"))
+			Expect(updated.Status.Steps[0].Message).To(Equal(`Dry run: would run "kubectl -n payments rollout restart deploy/api"`))
+			Expect(updated.Status.Steps[1].Message).To(ContainSubstring("would fail"))
+			Expect(updated.Status.Steps[2].Message).To(ContainSubstring("once approved"))
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"runbook.runbook.io/runbook": runbookName})).To(Succeed())
+			Expect(jobs.Items).To(BeEmpty())
+		})
+
+		It("should record the outcome in the runbook history and the audit log", func() {
+			var auditLog bytes.Buffer
+			controllerReconciler.Audit = audit.NewJSONLines(&auditLog)
+			runbook.Spec.Content.Automation.DryRun = true
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+			updated := reconcileExecution(3)
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionSucceeded))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(runbook), runbook)).To(Succeed())
+			Expect(runbook.Status.Automation.History).To(HaveLen(1))
+			record := runbook.Status.Automation.History[0]
+			Expect(record.Name).To(Equal(execution.Name))
+			Expect(record.Phase).To(Equal(runbookv1alpha1.ExecutionSucceeded))
+			Expect(record.CompletionTime).NotTo(BeNil())
+
+			var actions []string
+			for _, line := range strings.Split(strings.TrimSpace(auditLog.String()), "\n") {
+				var event audit.Event
+				Expect(json.Unmarshal([]byte(line), &event)).To(Succeed())
+				Expect(event.Execution).To(Equal(execution.Name))
+				actions = append(actions, event.Action)
+			}
+			Expect(actions).To(Equal([]string{
+				audit.ActionStarted,
+				audit.ActionStepFinished, audit.ActionStepFinished, audit.ActionStepFinished,
+				audit.ActionFinished,
+			}))
+
+			By("Recording the outcome once")
+			reconcileExecution(1)
+			Expect(strings.Count(auditLog.String(), `"action":"finished"`)).To(Equal(1))
+		})
+
+		It("should never run a high risk step", func() {
+			runbook.Spec.Content.Remediation[0].Risk = runbookv1alpha1.RiskHigh
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+			updated := reconcileExecution(2)
+			Expect(updated.Status.Steps[0].Phase).To(Equal(runbookv1alpha1.ExecutionSkipped))
+			Expect(updated.Status.Steps[0].JobName).To(BeEmpty())
+			Expect(updated.Status.Steps[2].Phase).To(Equal(runbookv1alpha1.ExecutionSkipped))
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionFailed))
+		})
+
+		It("should hold executions beyond the concurrency limit", func() {
+			runbook.Spec.Content.Automation.MaxConcurrentExecutions = 1
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+			updated := reconcileExecution(2)
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionRunning))
+
+			second := &runbookv1alpha1.RunbookExecution{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-execution-second", Namespace: "default"},
+				Spec:       runbookv1alpha1.RunbookExecutionSpec{RunbookName: runbookName},
+			}
+			Expect(k8sClient.Create(ctx, second)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, second))).To(Succeed())
+			})
+
+			secondKey := types.NamespacedName{Name: second.Name, Namespace: second.Namespace}
+			for range 2 {
+				result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: secondKey})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			}
+			Expect(k8sClient.Get(ctx, secondKey, second)).To(Succeed())
+			Expect(second.Status.Phase).To(Equal(runbookv1alpha1.ExecutionPending))
+			condition := meta.FindStatusCondition(second.Status.Conditions, "Succeeded")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(automation.ReasonConcurrencyLimited))
+
+			By("Recording the running execution in the runbook status")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(runbook), runbook)).To(Succeed())
+			Expect(runbook.Status.Automation.RunningExecutions).To(ConsistOf(execution.Name))
+			Expect(runbook.Status.Automation.RecentStarts).To(HaveLen(1))
+		})
+
+		It("should skip executions of runbooks whose automation is disabled", func() {
+			runbook.Spec.Content.Automation.Enabled = false
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+			updated := reconcileExecution(2)
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionSkipped))
+			Expect(updated.Status.Steps).To(BeEmpty())
+			condition := meta.FindStatusCondition(updated.Status.Conditions, "Succeeded")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(automation.ReasonAutomationDisabled))
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"runbook.runbook.io/runbook": runbookName})).To(Succeed())
+			Expect(jobs.Items).To(BeEmpty())
+		})
+
+		It("should skip manual executions when no manual trigger matches", func() {
+			runbook.Spec.Content.Automation.Triggers = []runbookv1alpha1.TriggerConfig{{Type: "manual", Conditions: []string{"team=payments"}}}
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+			updated := reconcileExecution(2)
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionSkipped))
+			condition := meta.FindStatusCondition(updated.Status.Conditions, "Succeeded")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(automation.ReasonNoManualTrigger))
+		})
+
+		It("should skip executions during the cooldown", func() {
+			runbook.Spec.Content.Automation.Cooldown = &metav1.Duration{Duration: time.Hour}
+			Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+			runbook.Status.Automation = &runbookv1alpha1.AutomationStatus{LastStartTime: ptr.To(metav1.Now())}
+			Expect(k8sClient.Status().Update(ctx, runbook)).To(Succeed())
+
+			updated := reconcileExecution(2)
+			Expect(updated.Status.Phase).To(Equal(runbookv1alpha1.ExecutionSkipped))
+			Expect(updated.Status.Steps).To(BeEmpty())
+			condition := meta.FindStatusCondition(updated.Status.Conditions, "Succeeded")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(automation.ReasonCoolingDown))
+		})
+	})
+})
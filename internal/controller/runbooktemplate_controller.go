@@ -18,42 +18,219 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/generator"
 )
 
 // RunbookTemplateReconciler reconciles a RunbookTemplate object
 type RunbookTemplateReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme    *runtime.Scheme
+	Generator *generator.RunbookGenerator
+
+	// mu guards loaded, the template name each RunbookTemplate was
+	// registered under, so a renamed template is unloaded
+	mu     sync.Mutex
+	loaded map[string]string
 }
 
 // +kubebuilder:rbac:groups=runbook.runbook.io,resources=runbooktemplates,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=runbook.runbook.io,resources=runbooktemplates/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=runbook.runbook.io,resources=runbooktemplates/finalizers,verbs=update
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the RunbookTemplate object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.21.0/pkg/reconcile
+// Reconcile parses the template, registers it with the shared generator and
+// reports the validation result in the RunbookTemplate status
 func (r *RunbookTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = logf.FromContext(ctx)
+	logger := log.FromContext(ctx)
+
+	var runbookTemplate runbookv1alpha1.RunbookTemplate
+	if err := r.Get(ctx, req.NamespacedName, &runbookTemplate); err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("RunbookTemplate resource not found. Ignoring since object must be deleted")
+			r.unload(req.Name)
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get RunbookTemplate")
+		return ctrl.Result{}, err
+	}
+
+	// Handle deletion
+	if runbookTemplate.DeletionTimestamp != nil {
+		return r.handleDeletion(ctx, &runbookTemplate)
+	}
+
+	// Add finalizer if not present
+	if !controllerutil.ContainsFinalizer(&runbookTemplate, "runbook.runbook.io/finalizer") {
+		original := runbookTemplate.DeepCopy()
+		controllerutil.AddFinalizer(&runbookTemplate, "runbook.runbook.io/finalizer")
+		if err := r.Patch(ctx, &runbookTemplate, client.MergeFrom(original)); err != nil {
+			logger.Error(err, "Failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+		logger.Info("Added finalizer to runbook template")
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	return r.reconcileTemplate(ctx, &runbookTemplate)
+}
+
+func (r *RunbookTemplateReconciler) reconcileTemplate(ctx context.Context, runbookTemplate *runbookv1alpha1.RunbookTemplate) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	original := runbookTemplate.DeepCopy()
+
+	usageCount, err := r.countUsage(ctx, runbookTemplate.Spec.Name)
+	if err != nil {
+		logger.Error(err, "Failed to count runbooks using template")
+		return ctrl.Result{}, err
+	}
+	runbookTemplate.Status.UsageCount = usageCount
+	runbookTemplate.Status.ObservedGeneration = runbookTemplate.Generation
+
+	// The template is registered under spec.name, drop the previous name when it changed
+	r.track(runbookTemplate.Name, runbookTemplate.Spec.Name)
+
+	if err := r.Generator.LoadTemplate(&runbookTemplate.Spec); err != nil {
+		logger.Error(err, "Failed to load template", "template", runbookTemplate.Spec.Name)
+
+		// Runbooks must not keep rendering with a template that is no longer valid
+		r.Generator.UnloadTemplate(runbookTemplate.Spec.Name)
+
+		runbookTemplate.Status.Phase = "error"
+		runbookTemplate.Status.ValidationStatus = "invalid"
+		runbookTemplate.Status.ValidationErrors = []string{err.Error()}
+		meta.SetStatusCondition(&runbookTemplate.Status.Conditions, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "TemplateInvalid",
+			Message: fmt.Sprintf("Failed to parse template: %v", err),
+		})
+
+		return ctrl.Result{}, r.updateStatus(ctx, runbookTemplate, original)
+	}
+
+	runbookTemplate.Status.Phase = "ready"
+	runbookTemplate.Status.ValidationStatus = "valid"
+	runbookTemplate.Status.ValidationErrors = nil
+	meta.SetStatusCondition(&runbookTemplate.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionTrue,
+		Reason:  "TemplateLoaded",
+		Message: "Template parsed and registered with the generator",
+	})
+
+	if err := r.updateStatus(ctx, runbookTemplate, original); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Successfully reconciled runbook template", "template", runbookTemplate.Spec.Name)
+	return ctrl.Result{}, nil
+}
+
+// updateStatus patches the template status, leaving conflicts to the next reconciliation
+func (r *RunbookTemplateReconciler) updateStatus(ctx context.Context, runbookTemplate *runbookv1alpha1.RunbookTemplate, original *runbookv1alpha1.RunbookTemplate) error {
+	logger := log.FromContext(ctx)
+
+	if err := r.Status().Patch(ctx, runbookTemplate, client.MergeFrom(original)); err != nil {
+		if errors.IsConflict(err) {
+			logger.Info("Status update conflict, will retry on next reconciliation")
+			return nil
+		}
+		logger.Error(err, "Failed to update status")
+		return err
+	}
+	return nil
+}
+
+// countUsage returns how many runbooks reference the template
+func (r *RunbookTemplateReconciler) countUsage(ctx context.Context, templateName string) (int, error) {
+	var runbooks runbookv1alpha1.RunbookList
+	if err := r.List(ctx, &runbooks); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := range runbooks.Items {
+		if runbookUsesTemplate(&runbooks.Items[i], templateName) {
+			count++
+		}
+	}
+	return count, nil
+}
 
-	// TODO(user): your logic here
+func (r *RunbookTemplateReconciler) handleDeletion(ctx context.Context, runbookTemplate *runbookv1alpha1.RunbookTemplate) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
 
+	logger.Info("Unregistering runbook template", "template", runbookTemplate.Spec.Name)
+	r.unload(runbookTemplate.Name)
+	r.Generator.UnloadTemplate(runbookTemplate.Spec.Name)
+
+	// Remove finalizer
+	original := runbookTemplate.DeepCopy()
+	controllerutil.RemoveFinalizer(runbookTemplate, "runbook.runbook.io/finalizer")
+	if err := r.Patch(ctx, runbookTemplate, client.MergeFrom(original)); err != nil {
+		if errors.IsConflict(err) {
+			logger.Info("Conflict while removing finalizer, will retry")
+			return ctrl.Result{Requeue: true}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Successfully deleted runbook template", "template", runbookTemplate.Spec.Name)
 	return ctrl.Result{}, nil
 }
 
+// track records the template name of the RunbookTemplate and unloads the
+// name it was registered under before, if that differs
+func (r *RunbookTemplateReconciler) track(objectName, templateName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded == nil {
+		r.loaded = make(map[string]string)
+	}
+	if previous, ok := r.loaded[objectName]; ok && previous != templateName {
+		r.Generator.UnloadTemplate(previous)
+	}
+	r.loaded[objectName] = templateName
+}
+
+// unload removes the template registered for the RunbookTemplate
+func (r *RunbookTemplateReconciler) unload(objectName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if previous, ok := r.loaded[objectName]; ok {
+		r.Generator.UnloadTemplate(previous)
+		delete(r.loaded, objectName)
+	}
+}
+
+// runbookUsesTemplate reports whether the runbook or any of its outputs reference the template
+func runbookUsesTemplate(runbook *runbookv1alpha1.Runbook, templateName string) bool {
+	if templateName == "" {
+		return false
+	}
+	if runbook.Spec.Template == templateName {
+		return true
+	}
+	for _, output := range runbook.Spec.Outputs {
+		if output.Template == templateName {
+			return true
+		}
+	}
+	return false
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *RunbookTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
@@ -28,6 +28,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/generator"
 )
 
 var _ = Describe("RunbookTemplate Controller", func() {
@@ -37,8 +38,7 @@ var _ = Describe("RunbookTemplate Controller", func() {
 		ctx := context.Background()
 
 		typeNamespacedName := types.NamespacedName{
-			Name:      resourceName,
-			Namespace: "default", // TODO(user):Modify as needed
+			Name: resourceName,
 		}
 		runbooktemplate := &runbookv1alpha1.RunbookTemplate{}
 
@@ -48,10 +48,12 @@ var _ = Describe("RunbookTemplate Controller", func() {
 			if err != nil && errors.IsNotFound(err) {
 				resource := &runbookv1alpha1.RunbookTemplate{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      resourceName,
-						Namespace: "default",
+						Name: resourceName,
+					},
+					Spec: runbookv1alpha1.RunbookTemplateSpec{
+						Name:     "test-template",
+						Template: "# {{ .Spec.AlertName }}",
 					},
-					// TODO(user): Specify other spec details if needed.
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -65,20 +67,92 @@ var _ = Describe("RunbookTemplate Controller", func() {
 
 			By("Cleanup the specific resource instance RunbookTemplate")
 			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			By("Releasing the finalizer so the next spec starts from scratch")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Finalizers = nil
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
+			runbookGenerator := generator.NewRunbookGenerator()
+			controllerReconciler := &RunbookTemplateReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: runbookGenerator,
+			}
+
+			// The first reconciliation adds the finalizer
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			By("Registering the template with the generator")
+			Expect(runbookGenerator.HasTemplate("test-template")).To(BeTrue())
+
+			resource := &runbookv1alpha1.RunbookTemplate{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Phase).To(Equal("ready"))
+			Expect(resource.Status.ValidationStatus).To(Equal("valid"))
+		})
+
+		It("should report templates that fail to parse", func() {
+			resource := &runbookv1alpha1.RunbookTemplate{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Template = "# {{ .Spec.AlertName "
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			runbookGenerator := generator.NewRunbookGenerator()
+			controllerReconciler := &RunbookTemplateReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: runbookGenerator,
+			}
+
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(runbookGenerator.HasTemplate("test-template")).To(BeFalse())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Phase).To(Equal("error"))
+			Expect(resource.Status.ValidationErrors).NotTo(BeEmpty())
+		})
+
+		It("should unload the previous name when the template is renamed", func() {
+			runbookGenerator := generator.NewRunbookGenerator()
 			controllerReconciler := &RunbookTemplateReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: runbookGenerator,
+			}
+
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
 			}
+			Expect(runbookGenerator.HasTemplate("test-template")).To(BeTrue())
+
+			By("Renaming the template")
+			resource := &runbookv1alpha1.RunbookTemplate{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Name = "renamed-template"
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+			Expect(runbookGenerator.HasTemplate("renamed-template")).To(BeTrue())
+			Expect(runbookGenerator.HasTemplate("test-template")).To(BeFalse())
 		})
 	})
 })
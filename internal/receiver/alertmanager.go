@@ -0,0 +1,156 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+	"github.com/guibes/runbook-operator/pkg/triggers"
+)
+
+// maxPayloadSize bounds the webhook payloads the receiver reads
+const maxPayloadSize = 1 << 20
+
+// AlertmanagerPayload is the body Alertmanager sends to webhook receivers
+type AlertmanagerPayload struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// Alert is a single alert of an Alertmanager notification
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// AlertmanagerResponse summarizes how the notification was handled
+type AlertmanagerResponse struct {
+	// Triggered lists the runbooks, as namespace/name, whose automation was triggered
+	Triggered []string `json:"triggered"`
+}
+
+// handleAlertmanager matches the firing alerts of an Alertmanager webhook
+// notification to Runbooks and dispatches the ones whose alert triggers match
+func (r *Receiver) handleAlertmanager(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	logger := log.FromContext(ctx).WithName("alertmanager")
+
+	var payload AlertmanagerPayload
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxPayloadSize)).Decode(&payload); err != nil {
+		http.Error(w, "invalid Alertmanager payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := AlertmanagerResponse{Triggered: []string{}}
+	now := r.clock()
+	for _, alert := range payload.Alerts {
+		if alert.Status != "firing" {
+			continue
+		}
+
+		runbooks, err := r.runbooksForAlert(ctx, alert)
+		if err != nil {
+			logger.Error(err, "Failed to list runbooks for alert", "alert", alert.Labels["alertname"])
+			http.Error(w, "failed to list runbooks", http.StatusInternalServerError)
+			return
+		}
+
+		values := triggers.Alert{Labels: alert.Labels, StartsAt: alert.StartsAt, Now: now}
+		for i := range runbooks {
+			runbook := &runbooks[i]
+
+			trigger, err := automation.MatchTrigger(runbook, automation.TriggerAlert, values)
+			if err != nil {
+				logger.Error(err, "Failed to evaluate trigger conditions", "runbook", runbook.Name)
+				continue
+			}
+			if trigger == nil {
+				continue
+			}
+
+			triggered, err := r.Dispatcher.Dispatch(ctx, runbook, alertTriggerEvent(payload, alert, now))
+			if err != nil {
+				logger.Error(err, "Failed to dispatch automation", "runbook", runbook.Name)
+				http.Error(w, "failed to dispatch automation", http.StatusInternalServerError)
+				return
+			}
+			if triggered {
+				response.Triggered = append(response.Triggered, runbook.Namespace+"/"+runbook.Name)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error(err, "Failed to write response")
+	}
+}
+
+// runbooksForAlert returns the Runbooks for the alert name, restricted to the
+// alert namespace label when the alert has one
+func (r *Receiver) runbooksForAlert(ctx context.Context, alert Alert) ([]runbookv1alpha1.Runbook, error) {
+	alertName := alert.Labels["alertname"]
+	if alertName == "" {
+		return nil, nil
+	}
+
+	opts := []client.ListOption{client.MatchingFields{automation.AlertNameField: alertName}}
+	if namespace := alert.Labels["namespace"]; namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+
+	var runbooks runbookv1alpha1.RunbookList
+	if err := r.Client.List(ctx, &runbooks, opts...); err != nil {
+		return nil, err
+	}
+	return runbooks.Items, nil
+}
+
+func alertTriggerEvent(payload AlertmanagerPayload, alert Alert, now time.Time) runbookv1alpha1.TriggerEvent {
+	// Status timestamps are stored with second precision, truncating keeps
+	// repeated notifications comparable with the recorded trigger
+	startsAt := metav1.NewTime(alert.StartsAt.Truncate(time.Second))
+	return runbookv1alpha1.TriggerEvent{
+		Type:        automation.TriggerAlert,
+		Source:      "alertmanager/" + payload.Receiver,
+		Fingerprint: alert.Fingerprint,
+		Labels:      alert.Labels,
+		StartsAt:    &startsAt,
+		TriggeredAt: metav1.NewTime(now),
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+)
+
+var _ = Describe("Alertmanager receiver", func() {
+	var (
+		k8sClient client.Client
+		server    *httptest.Server
+		now       time.Time
+		runbook   *runbookv1alpha1.Runbook
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		runbook = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: "high-error-rate", Namespace: "payments"},
+			Spec: runbookv1alpha1.RunbookSpec{
+				AlertName: "HighErrorRate",
+				Severity:  "critical",
+				Content: runbookv1alpha1.RunbookContent{
+					Automation: &runbookv1alpha1.AutomationConfig{
+						Enabled: true,
+						Triggers: []runbookv1alpha1.TriggerConfig{
+							{Type: "alert", Conditions: []string{"severity=critical", "duration>5m"}},
+						},
+					},
+				},
+			},
+		}
+
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(runbook).
+			WithStatusSubresource(&runbookv1alpha1.Runbook{}).
+			WithIndex(&runbookv1alpha1.Runbook{}, automation.AlertNameField, automation.AlertNameIndex).
+			Build()
+
+		receiver := &Receiver{
+			Client: k8sClient,
+			Dispatcher: &automation.Dispatcher{
+				Client:   k8sClient,
+				Recorder: record.NewFakeRecorder(10),
+			},
+			AlertmanagerToken: "alertmanager-token",
+			now:               func() time.Time { return now },
+		}
+		server = httptest.NewServer(receiver.Handler())
+		DeferCleanup(server.Close)
+	})
+
+	firingAlert := func(namespace string, startsAt time.Time) Alert {
+		return Alert{
+			Status: "firing",
+			Labels: map[string]string{
+				"alertname": "HighErrorRate",
+				"namespace": namespace,
+				"severity":  "critical",
+			},
+			StartsAt:    startsAt,
+			Fingerprint: "3b2a1c0d",
+		}
+	}
+
+	post := func(authorization string, body []byte) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/alertmanager", bytes.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	notify := func(alerts ...Alert) (int, AlertmanagerResponse) {
+		body, err := json.Marshal(AlertmanagerPayload{
+			Version:  "4",
+			Status:   "firing",
+			Receiver: "runbook-operator",
+			Alerts:   alerts,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp := post("Bearer alertmanager-token", body)
+		defer resp.Body.Close()
+
+		var response AlertmanagerResponse
+		if resp.StatusCode == http.StatusOK {
+			Expect(json.NewDecoder(resp.Body).Decode(&response)).To(Succeed())
+		}
+		return resp.StatusCode, response
+	}
+
+	automationStatus := func() *runbookv1alpha1.AutomationStatus {
+		updated := &runbookv1alpha1.Runbook{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(runbook), updated)).To(Succeed())
+		return updated.Status.Automation
+	}
+
+	It("should record a firing alert that matches the trigger conditions", func() {
+		status, response := notify(firingAlert("payments", now.Add(-10*time.Minute)))
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(response.Triggered).To(ConsistOf("payments/high-error-rate"))
+
+		automation := automationStatus()
+		Expect(automation).NotTo(BeNil())
+		Expect(automation.TriggerCount).To(BeEquivalentTo(1))
+		Expect(automation.LastTrigger.Type).To(Equal("alert"))
+		Expect(automation.LastTrigger.Source).To(Equal("alertmanager/runbook-operator"))
+		Expect(automation.LastTrigger.Labels).To(HaveKeyWithValue("severity", "critical"))
+
+		execution := &runbookv1alpha1.RunbookExecution{}
+		key := client.ObjectKey{Name: automation.LastExecution, Namespace: "payments"}
+		Expect(k8sClient.Get(ctx, key, execution)).To(Succeed())
+		Expect(execution.Spec.RunbookName).To(Equal("high-error-rate"))
+		Expect(execution.Spec.Trigger.Fingerprint).To(Equal("3b2a1c0d"))
+		Expect(metav1.IsControlledBy(execution, runbook)).To(BeTrue())
+
+		Expect(automation.History).To(HaveLen(1))
+		Expect(automation.History[0].Name).To(Equal(execution.Name))
+		Expect(automation.History[0].Trigger).To(Equal("alert"))
+		Expect(automation.History[0].Phase).To(Equal("pending"))
+	})
+
+	It("should record repeated notifications of the same alert once", func() {
+		startsAt := now.Add(-10*time.Minute + 250*time.Millisecond)
+		notify(firingAlert("payments", startsAt))
+
+		now = now.Add(5 * time.Minute)
+		status, response := notify(firingAlert("payments", startsAt))
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(response.Triggered).To(BeEmpty())
+		Expect(automationStatus().TriggerCount).To(BeEquivalentTo(1))
+
+		executions := &runbookv1alpha1.RunbookExecutionList{}
+		Expect(k8sClient.List(ctx, executions, client.InNamespace("payments"))).To(Succeed())
+		Expect(executions.Items).To(HaveLen(1))
+	})
+
+	It("should record each firing of an alert once while other alerts fire in between", func() {
+		startsAt := now.Add(-10 * time.Minute)
+		other := firingAlert("payments", now.Add(-20*time.Minute))
+		other.Fingerprint = "7f6e5d4c"
+
+		notify(firingAlert("payments", startsAt))
+		notify(other)
+		_, response := notify(firingAlert("payments", startsAt), other)
+		Expect(response.Triggered).To(BeEmpty())
+		Expect(automationStatus().TriggerCount).To(BeEquivalentTo(2))
+		Expect(automationStatus().RecentTriggers).To(HaveLen(2))
+
+		By("Recording the alert again once it fires anew")
+		_, response = notify(firingAlert("payments", now.Add(-6*time.Minute)))
+		Expect(response.Triggered).To(ConsistOf("payments/high-error-rate"))
+		Expect(automationStatus().TriggerCount).To(BeEquivalentTo(3))
+	})
+
+	It("should derive the execution from the alert firing only", func() {
+		startsAt := now.Add(-10 * time.Minute)
+		notify(firingAlert("payments", startsAt))
+		first := automationStatus().LastExecution
+
+		By("Losing the recorded triggers")
+		updated := &runbookv1alpha1.Runbook{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(runbook), updated)).To(Succeed())
+		updated.Status.Automation = nil
+		Expect(k8sClient.Status().Update(ctx, updated)).To(Succeed())
+
+		now = now.Add(time.Minute)
+		notify(firingAlert("payments", startsAt))
+		Expect(automationStatus().LastExecution).To(Equal(first))
+
+		executions := &runbookv1alpha1.RunbookExecutionList{}
+		Expect(k8sClient.List(ctx, executions, client.InNamespace("payments"))).To(Succeed())
+		Expect(executions.Items).To(HaveLen(1))
+	})
+
+	It("should require the bearer token", func() {
+		body := []byte(`{"alerts": []}`)
+		for _, authorization := range []string{"", "Bearer wrong-token", "Basic YWxlcnRtYW5hZ2VyLXRva2Vu"} {
+			resp := post(authorization, body)
+			resp.Body.Close() //nolint:errcheck
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		}
+
+		By("Not serving the endpoint without a token")
+		unauthenticated := httptest.NewServer((&Receiver{Client: k8sClient}).Handler())
+		DeferCleanup(unauthenticated.Close)
+		resp, err := http.Post(unauthenticated.URL+"/alertmanager", "application/json", bytes.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("should not trigger before the alert has fired long enough", func() {
+		_, response := notify(firingAlert("payments", now.Add(-time.Minute)))
+		Expect(response.Triggered).To(BeEmpty())
+		Expect(automationStatus()).To(BeNil())
+	})
+
+	It("should only match runbooks in the alert namespace", func() {
+		_, response := notify(firingAlert("checkout", now.Add(-10*time.Minute)))
+		Expect(response.Triggered).To(BeEmpty())
+		Expect(automationStatus()).To(BeNil())
+	})
+
+	It("should ignore resolved alerts", func() {
+		alert := firingAlert("payments", now.Add(-10*time.Minute))
+		alert.Status = "resolved"
+
+		_, response := notify(alert)
+		Expect(response.Triggered).To(BeEmpty())
+		Expect(automationStatus()).To(BeNil())
+	})
+
+	It("should not trigger runbooks with automation disabled", func() {
+		runbook.Spec.Content.Automation.Enabled = false
+		Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+		_, response := notify(firingAlert("payments", now.Add(-10*time.Minute)))
+		Expect(response.Triggered).To(BeEmpty())
+	})
+
+	It("should reject payloads that are not Alertmanager notifications", func() {
+		resp := post("Bearer alertmanager-token", []byte("{"))
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		resp, err := http.Get(server.URL + "/alertmanager")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,129 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/guibes/runbook-operator/internal/automation"
+)
+
+// Receiver serves the inbound HTTP endpoints that trigger runbook automation
+type Receiver struct {
+	Client     client.Client
+	Dispatcher *automation.Dispatcher
+
+	// APIReader reads the webhook Secrets without caching every Secret of
+	// the cluster, defaults to Client
+	APIReader client.Reader
+
+	// AlertmanagerToken is the bearer token Alertmanager authenticates
+	// with. The Alertmanager endpoint is not served without one.
+	AlertmanagerToken string
+
+	// now is replaced in tests to evaluate firing durations deterministically
+	now func() time.Time
+}
+
+// Handler returns the HTTP handler with every receiver endpoint
+func (r *Receiver) Handler() http.Handler {
+	mux := http.NewServeMux()
+	if r.AlertmanagerToken != "" {
+		mux.Handle("POST /alertmanager", requireBearerToken(r.AlertmanagerToken, http.HandlerFunc(r.handleAlertmanager)))
+	}
+	mux.HandleFunc("POST /trigger/{namespace}/{runbook}", r.handleTrigger)
+	return mux
+}
+
+// requireBearerToken rejects requests without the token in their
+// Authorization header, comparing it in constant time
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="runbook-operator"`)
+			unauthorized(w)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (r *Receiver) secretReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+func (r *Receiver) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// Server runs the receiver endpoints inside the manager. Every replica
+// serves requests, so it does not need leader election.
+type Server struct {
+	// BindAddress is the address the server listens on
+	BindAddress string
+
+	Handler http.Handler
+}
+
+var _ manager.LeaderElectionRunnable = &Server{}
+
+// Start serves requests until the context is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("receiver")
+
+	server := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           s.Handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "Failed to shut down automation receiver")
+		}
+	}()
+
+	logger.Info("Starting automation receiver", "address", s.BindAddress)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+	"github.com/guibes/runbook-operator/pkg/triggers"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of the timestamp, the delivery
+	// and the request body, joined by dots, as sha256=<hex>
+	SignatureHeader = "X-Runbook-Signature"
+
+	// TimestampHeader is when the request was signed, in seconds since the epoch
+	TimestampHeader = "X-Runbook-Timestamp"
+
+	// DeliveryHeader optionally identifies a request, retries of the same
+	// delivery start a single execution
+	DeliveryHeader = "X-Runbook-Delivery"
+
+	// maxSignatureAge is how far the timestamp of a request may be from the
+	// receiver clock, so that captured requests cannot be replayed later
+	maxSignatureAge = 5 * time.Minute
+
+	// maxTriggerLabels bounds the body values recorded with a webhook trigger
+	maxTriggerLabels = 32
+)
+
+// errNoWebhookSecret is returned for runbooks that do not accept webhook triggers
+var errNoWebhookSecret = errors.New("webhook triggers are not configured for this runbook")
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// TriggerResponse reports whether a webhook trigger started an execution
+type TriggerResponse struct {
+	// Triggered is false when no webhook trigger matched or the delivery was already handled
+	Triggered bool `json:"triggered"`
+
+	// Execution is the name of the RunbookExecution that was started
+	Execution string `json:"execution,omitempty"`
+}
+
+// handleTrigger starts an execution of a runbook for a signed request, e.g.
+// from a CI system or a chatops bot, whose JSON body matches one of the
+// webhook triggers of the runbook
+func (r *Receiver) handleTrigger(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	key := types.NamespacedName{Namespace: req.PathValue("namespace"), Name: req.PathValue("runbook")}
+	logger := log.FromContext(ctx).WithName("trigger").WithValues("runbook", key)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Unknown runbooks, runbooks without a webhook secret and bad signatures
+	// get the same answer, so that callers cannot probe which runbooks exist
+	var runbook runbookv1alpha1.Runbook
+	if err := r.Client.Get(ctx, key, &runbook); err != nil {
+		if apierrors.IsNotFound(err) {
+			unauthorized(w)
+			return
+		}
+		logger.Error(err, "Failed to get runbook")
+		http.Error(w, "failed to get runbook", http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := r.webhookSecret(ctx, &runbook)
+	if err != nil {
+		if !errors.Is(err, errNoWebhookSecret) {
+			logger.Error(err, "Failed to read webhook secret")
+		}
+		unauthorized(w)
+		return
+	}
+	timestamp, delivery, signature := req.Header.Get(TimestampHeader), req.Header.Get(DeliveryHeader), req.Header.Get(SignatureHeader)
+	payload := signedPayload(timestamp, delivery, body)
+	if !r.recent(timestamp) || !validSignature(secret, payload, signature) {
+		unauthorized(w)
+		return
+	}
+
+	var document any
+	if len(bytes.TrimSpace(body)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	values := triggers.JSON{Document: document}
+	response := TriggerResponse{}
+	trigger, err := automation.MatchTrigger(&runbook, automation.TriggerWebhook, values)
+	if err != nil {
+		logger.Error(err, "Failed to evaluate trigger conditions")
+		http.Error(w, "failed to evaluate trigger conditions", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if trigger != nil {
+		// Without a delivery ID, replays of the same signed request are
+		// deduplicated by its MAC, which unlike the signature header has a
+		// single encoding
+		fingerprint := delivery
+		if fingerprint == "" {
+			fingerprint = hex.EncodeToString(payloadMAC(secret, payload))
+		}
+		event := runbookv1alpha1.TriggerEvent{
+			Type:        automation.TriggerWebhook,
+			Source:      "webhook",
+			Fingerprint: fingerprint,
+			Labels:      values.Flatten(maxTriggerLabels),
+			TriggeredAt: metav1.NewTime(r.clock()),
+		}
+		triggered, err := r.Dispatcher.Dispatch(ctx, &runbook, event)
+		if err != nil {
+			logger.Error(err, "Failed to dispatch automation")
+			http.Error(w, "failed to dispatch automation", http.StatusInternalServerError)
+			return
+		}
+		if triggered {
+			response.Triggered = true
+			response.Execution = runbook.Status.Automation.LastExecution
+			status = http.StatusAccepted
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error(err, "Failed to write response")
+	}
+}
+
+// webhookSecret reads the HMAC key of the runbook webhook triggers
+func (r *Receiver) webhookSecret(ctx context.Context, runbook *runbookv1alpha1.Runbook) ([]byte, error) {
+	automationConfig := runbook.Spec.Content.Automation
+	if automationConfig == nil || !automationConfig.Enabled || automationConfig.WebhookSecretRef == nil {
+		return nil, errNoWebhookSecret
+	}
+	ref := automationConfig.WebhookSecretRef
+
+	var secret corev1.Secret
+	if err := r.secretReader().Get(ctx, types.NamespacedName{Namespace: runbook.Namespace, Name: ref.Name}, &secret); err != nil {
+		return nil, err
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok || len(value) == 0 {
+		return nil, fmt.Errorf("key %q not found in Secret %s", ref.Key, ref.Name)
+	}
+	return value, nil
+}
+
+// recent reports whether the timestamp header is within maxSignatureAge of the receiver clock
+func (r *Receiver) recent(header string) bool {
+	seconds, err := strconv.ParseInt(header, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := r.clock().Sub(time.Unix(seconds, 0))
+	return age <= maxSignatureAge && age >= -maxSignatureAge
+}
+
+// signedPayload is what the signature of a request covers
+func signedPayload(timestamp, delivery string, body []byte) []byte {
+	return append([]byte(timestamp+"."+delivery+"."), body...)
+}
+
+// validSignature compares the signature header to the HMAC-SHA256 of the payload in constant time
+func validSignature(secret, payload []byte, header string) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, payloadMAC(secret, payload))
+}
+
+// payloadMAC is the HMAC-SHA256 of the payload
+func payloadMAC(secret, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func unauthorized(w http.ResponseWriter) {
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
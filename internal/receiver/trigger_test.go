@@ -0,0 +1,255 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+)
+
+var _ = Describe("Webhook trigger receiver", func() {
+	const secretKey = "s3cr3t"
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var (
+		k8sClient client.Client
+		server    *httptest.Server
+		runbook   *runbookv1alpha1.Runbook
+	)
+
+	BeforeEach(func() {
+		runbook = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: "deploy-rollback", Namespace: "payments"},
+			Spec: runbookv1alpha1.RunbookSpec{
+				AlertName: "DeployFailed",
+				Content: runbookv1alpha1.RunbookContent{
+					Automation: &runbookv1alpha1.AutomationConfig{
+						Enabled: true,
+						Triggers: []runbookv1alpha1.TriggerConfig{
+							{Type: "webhook", Conditions: []string{"pipeline.status=failed", "pipeline.attempt>=2"}},
+						},
+						WebhookSecretRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "ci-webhook"},
+							Key:                  "hmac",
+						},
+					},
+				},
+			},
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ci-webhook", Namespace: "payments"},
+			Data:       map[string][]byte{"hmac": []byte(secretKey)},
+		}
+
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(runbook, secret).
+			WithStatusSubresource(&runbookv1alpha1.Runbook{}).
+			Build()
+
+		receiver := &Receiver{
+			Client: k8sClient,
+			Dispatcher: &automation.Dispatcher{
+				Client:   k8sClient,
+				Recorder: record.NewFakeRecorder(10),
+			},
+			now: func() time.Time { return now },
+		}
+		server = httptest.NewServer(receiver.Handler())
+		DeferCleanup(server.Close)
+	})
+
+	// request is a webhook delivery, signed with the key at the timestamp
+	type request struct {
+		path      string
+		body      []byte
+		key       string
+		timestamp time.Time
+		delivery  string
+		signature string
+	}
+
+	newRequest := func(path string, body []byte, delivery string) request {
+		return request{path: path, body: body, key: secretKey, timestamp: now, delivery: delivery}
+	}
+
+	sign := func(r request) string {
+		mac := hmac.New(sha256.New, []byte(r.key))
+		mac.Write([]byte(strconv.FormatInt(r.timestamp.Unix(), 10) + "." + r.delivery + "."))
+		mac.Write(r.body)
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	send := func(r request, delivery string) (int, TriggerResponse) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+r.path, bytes.NewReader(r.body))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(SignatureHeader, r.signature)
+		req.Header.Set(TimestampHeader, strconv.FormatInt(r.timestamp.Unix(), 10))
+		if delivery != "" {
+			req.Header.Set(DeliveryHeader, delivery)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		var response TriggerResponse
+		if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusAccepted {
+			Expect(json.NewDecoder(resp.Body).Decode(&response)).To(Succeed())
+		}
+		return resp.StatusCode, response
+	}
+
+	trigger := func(r request) (int, TriggerResponse) {
+		if r.signature == "" {
+			r.signature = sign(r)
+		}
+		return send(r, r.delivery)
+	}
+
+	failedPipeline := []byte(`{"pipeline": {"status": "failed", "attempt": 3, "ref": "main"}, "jobs": [{"name": "deploy"}]}`)
+
+	It("should start an execution for a signed request that matches the conditions", func() {
+		status, response := trigger(newRequest("/trigger/payments/deploy-rollback", failedPipeline, "delivery-1"))
+		Expect(status).To(Equal(http.StatusAccepted))
+		Expect(response.Triggered).To(BeTrue())
+		Expect(response.Execution).NotTo(BeEmpty())
+
+		execution := &runbookv1alpha1.RunbookExecution{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: response.Execution, Namespace: "payments"}, execution)).To(Succeed())
+		Expect(execution.Spec.Trigger.Type).To(Equal("webhook"))
+		Expect(execution.Spec.Trigger.Fingerprint).To(Equal("delivery-1"))
+		Expect(execution.Spec.Trigger.Labels).To(HaveKeyWithValue("pipeline.ref", "main"))
+		Expect(execution.Spec.Trigger.Labels).To(HaveKeyWithValue("jobs.0.name", "deploy"))
+
+		By("Retrying the same delivery")
+		status, response = trigger(newRequest("/trigger/payments/deploy-rollback", failedPipeline, "delivery-1"))
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(response.Triggered).To(BeFalse())
+	})
+
+	It("should start a single execution for replays of a request without a delivery", func() {
+		replayed := newRequest("/trigger/payments/deploy-rollback", failedPipeline, "")
+		status, _ := trigger(replayed)
+		Expect(status).To(Equal(http.StatusAccepted))
+
+		status, response := trigger(replayed)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(response.Triggered).To(BeFalse())
+
+		By("Replaying it with the signature in upper case")
+		replayed.signature = "sha256=" + strings.ToUpper(strings.TrimPrefix(sign(replayed), "sha256="))
+		status, response = trigger(replayed)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(response.Triggered).To(BeFalse())
+
+		executions := &runbookv1alpha1.RunbookExecutionList{}
+		Expect(k8sClient.List(ctx, executions, client.InNamespace("payments"))).To(Succeed())
+		Expect(executions.Items).To(HaveLen(1))
+	})
+
+	It("should not start an execution when the conditions do not hold", func() {
+		body := []byte(`{"pipeline": {"status": "failed", "attempt": 1}}`)
+		status, response := trigger(newRequest("/trigger/payments/deploy-rollback", body, ""))
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(response.Triggered).To(BeFalse())
+
+		executions := &runbookv1alpha1.RunbookExecutionList{}
+		Expect(k8sClient.List(ctx, executions, client.InNamespace("payments"))).To(Succeed())
+		Expect(executions.Items).To(BeEmpty())
+	})
+
+	It("should reject requests with an invalid signature", func() {
+		wrongKey := newRequest("/trigger/payments/deploy-rollback", failedPipeline, "")
+		wrongKey.key = "wrong"
+		status, _ := trigger(wrongKey)
+		Expect(status).To(Equal(http.StatusUnauthorized))
+
+		unsigned := newRequest("/trigger/payments/deploy-rollback", failedPipeline, "")
+		unsigned.signature = "sha256="
+		status, _ = trigger(unsigned)
+		Expect(status).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("should reject replays with a new delivery or timestamp", func() {
+		captured := newRequest("/trigger/payments/deploy-rollback", failedPipeline, "delivery-1")
+		captured.signature = sign(captured)
+
+		status, _ := send(captured, "delivery-2")
+		Expect(status).To(Equal(http.StatusUnauthorized))
+
+		replayed := captured
+		replayed.timestamp = now.Add(time.Second)
+		status, _ = send(replayed, "delivery-1")
+		Expect(status).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("should reject stale and future timestamps", func() {
+		stale := newRequest("/trigger/payments/deploy-rollback", failedPipeline, "")
+		stale.timestamp = now.Add(-6 * time.Minute)
+		status, _ := trigger(stale)
+		Expect(status).To(Equal(http.StatusUnauthorized))
+
+		future := newRequest("/trigger/payments/deploy-rollback", failedPipeline, "")
+		future.timestamp = now.Add(6 * time.Minute)
+		status, _ = trigger(future)
+		Expect(status).To(Equal(http.StatusUnauthorized))
+
+		late := newRequest("/trigger/payments/deploy-rollback", failedPipeline, "")
+		late.timestamp = now.Add(-4 * time.Minute)
+		status, _ = trigger(late)
+		Expect(status).To(Equal(http.StatusAccepted))
+	})
+
+	It("should answer unknown runbooks and runbooks without a webhook secret like bad signatures", func() {
+		status, _ := trigger(newRequest("/trigger/payments/missing", failedPipeline, ""))
+		Expect(status).To(Equal(http.StatusUnauthorized))
+
+		runbook.Spec.Content.Automation.WebhookSecretRef = nil
+		Expect(k8sClient.Update(ctx, runbook)).To(Succeed())
+
+		status, _ = trigger(newRequest("/trigger/payments/deploy-rollback", failedPipeline, ""))
+		Expect(status).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("should reject bodies that are not JSON", func() {
+		body := []byte("pipeline failed")
+		status, _ := trigger(newRequest("/trigger/payments/deploy-rollback", body, ""))
+		Expect(status).To(Equal(http.StatusBadRequest))
+	})
+})
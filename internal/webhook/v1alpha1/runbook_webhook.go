@@ -0,0 +1,398 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+	"github.com/guibes/runbook-operator/pkg/generator"
+	"github.com/guibes/runbook-operator/pkg/outputs"
+	"github.com/guibes/runbook-operator/pkg/validation"
+)
+
+// log is for logging in this package.
+var runbooklog = logf.Log.WithName("runbook-resource")
+
+// SetupRunbookWebhookWithManager registers the webhook for Runbook in the
+// manager. configMapNamespaces are the namespaces configmap outputs may write
+// to besides the namespace of the runbook, and serviceAccounts the
+// ServiceAccounts automation steps may run as besides defaultServiceAccount.
+func SetupRunbookWebhookWithManager(mgr ctrl.Manager, configMapNamespaces []string, defaultServiceAccount string, serviceAccounts []string) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&runbookv1alpha1.Runbook{}).
+		WithValidator(&RunbookCustomValidator{
+			Client:                mgr.GetClient(),
+			ConfigMapNamespaces:   configMapNamespaces,
+			DefaultServiceAccount: defaultServiceAccount,
+			ServiceAccounts:       serviceAccounts,
+		}).
+		WithDefaulter(&RunbookCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-runbook-runbook-io-v1alpha1-runbook,mutating=true,failurePolicy=fail,sideEffects=None,groups=runbook.runbook.io,resources=runbooks,verbs=create;update,versions=v1alpha1,name=mrunbook-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RunbookCustomDefaulter records the user that set the run-now annotation,
+// so that the audit log names who started a manual run. The recorded user
+// can only change together with the run-now annotation.
+type RunbookCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &RunbookCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type Runbook.
+func (d *RunbookCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	runbook, ok := obj.(*runbookv1alpha1.Runbook)
+	if !ok {
+		return fmt.Errorf("expected a Runbook object but got %T", obj)
+	}
+	runbooklog.Info("Defaulting for Runbook", "name", runbook.GetName())
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	old := &runbookv1alpha1.Runbook{}
+	if len(req.OldObject.Raw) > 0 {
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return fmt.Errorf("failed to decode the previous Runbook: %w", err)
+		}
+	}
+
+	nonce := runbook.Annotations[automation.RunNowAnnotation]
+	user := old.Annotations[automation.RunNowByAnnotation]
+	if nonce != "" && nonce != old.Annotations[automation.RunNowAnnotation] {
+		user = req.UserInfo.Username
+	}
+	if user == "" {
+		delete(runbook.Annotations, automation.RunNowByAnnotation)
+		return nil
+	}
+	if runbook.Annotations == nil {
+		runbook.Annotations = map[string]string{}
+	}
+	runbook.Annotations[automation.RunNowByAnnotation] = user
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-runbook-runbook-io-v1alpha1-runbook,mutating=false,failurePolicy=fail,sideEffects=None,groups=runbook.runbook.io,resources=runbooks,verbs=create;update,versions=v1alpha1,name=vrunbook-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RunbookCustomValidator validates Runbooks when they are created or updated
+type RunbookCustomValidator struct {
+	Client client.Reader
+
+	// Outputs lists the output formats the operator can generate, defaults to outputs.DefaultRegistry
+	Outputs *outputs.Registry
+
+	// ConfigMapNamespaces are the namespaces configmap outputs may write to
+	// besides the namespace of the runbook
+	ConfigMapNamespaces []string
+
+	// DefaultServiceAccount is the ServiceAccount automation steps run as
+	// when the runbook does not set one
+	DefaultServiceAccount string
+
+	// ServiceAccounts are the ServiceAccounts automation steps may run as
+	// besides DefaultServiceAccount
+	ServiceAccounts []string
+}
+
+var _ webhook.CustomValidator = &RunbookCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Runbook.
+func (v *RunbookCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	runbook, ok := obj.(*runbookv1alpha1.Runbook)
+	if !ok {
+		return nil, fmt.Errorf("expected a Runbook object but got %T", obj)
+	}
+	runbooklog.Info("Validation for Runbook upon creation", "name", runbook.GetName())
+
+	return v.validateRunbook(ctx, runbook)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Runbook.
+func (v *RunbookCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	runbook, ok := newObj.(*runbookv1alpha1.Runbook)
+	if !ok {
+		return nil, fmt.Errorf("expected a Runbook object for the newObj but got %T", newObj)
+	}
+	runbooklog.Info("Validation for Runbook upon update", "name", runbook.GetName())
+
+	// Deleting a runbook only removes its finalizer, which must not be blocked
+	// by a template or format that disappeared in the meantime
+	if runbook.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	return v.validateRunbook(ctx, runbook)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Runbook.
+func (v *RunbookCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateRunbook denies runbooks the operator cannot act on and runbooks
+// with error level findings of the validation rules. Warning level findings
+// are returned as warnings, the controller reports them in the Runbook status.
+func (v *RunbookCustomValidator) validateRunbook(ctx context.Context, runbook *runbookv1alpha1.Runbook) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+
+	generated, err := v.generatedFromPrometheusRule(ctx, runbook)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	warnings, allErrs := validateRuleFindings(runbook, generated)
+	allErrs = append(allErrs, validateRunbookContent(runbook, specPath)...)
+	allErrs = append(allErrs, v.validateOutputFormats(runbook, specPath)...)
+	allErrs = append(allErrs, v.validateOutputDestinations(runbook, specPath)...)
+	allErrs = append(allErrs, v.validateServiceAccount(runbook, specPath)...)
+
+	templateErrs, err := v.validateTemplates(ctx, runbook, specPath)
+	if err != nil {
+		return warnings, apierrors.NewInternalError(err)
+	}
+	allErrs = append(allErrs, templateErrs...)
+
+	duplicateErrs, err := v.validateUniqueAlertName(ctx, runbook, generated, specPath)
+	if err != nil {
+		return warnings, apierrors.NewInternalError(err)
+	}
+	allErrs = append(allErrs, duplicateErrs...)
+
+	if len(allErrs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(runbookv1alpha1.GroupVersion.WithKind("Runbook").GroupKind(), runbook.Name, allErrs)
+}
+
+// validateRuleFindings runs the validation rules. Runbooks generated from
+// PrometheusRules only carry what the alert rule defines, so their error
+// findings are returned as warnings until someone fills in the content.
+func validateRuleFindings(runbook *runbookv1alpha1.Runbook, generated bool) (admission.Warnings, field.ErrorList) {
+	result := validation.NewDefaultValidator().Validate(runbook)
+
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+	for _, finding := range result.Findings {
+		if finding.Level == validation.LevelError && !generated {
+			allErrs = append(allErrs, field.Invalid(field.NewPath(finding.Field), field.OmitValueType{}, finding.Message))
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s %s", finding.Level, finding))
+	}
+	return warnings, allErrs
+}
+
+// validateRunbookContent checks the investigation and remediation steps and references
+func validateRunbookContent(runbook *runbookv1alpha1.Runbook, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	contentPath := specPath.Child("content")
+
+	for i, step := range runbook.Spec.Content.Investigation {
+		if step.Automated && step.Risk == runbookv1alpha1.RiskHigh {
+			allErrs = append(allErrs, field.Forbidden(contentPath.Child("investigation").Index(i).Child("automated"),
+				"high risk investigation steps cannot be automated"))
+		}
+	}
+
+	for i, step := range runbook.Spec.Content.Remediation {
+		if step.Automated && step.Risk == runbookv1alpha1.RiskHigh {
+			allErrs = append(allErrs, field.Forbidden(contentPath.Child("remediation").Index(i).Child("automated"),
+				"high risk remediation steps cannot be automated"))
+		}
+	}
+
+	for i, ref := range runbook.Spec.Content.References {
+		if err := validateReferenceURL(ref.URL); err != nil {
+			allErrs = append(allErrs, field.Invalid(contentPath.Child("references").Index(i).Child("url"), ref.URL, err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+// validateReferenceURL accepts absolute http and https URLs only
+func validateReferenceURL(rawURL string) error {
+	parsed, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return fmt.Errorf("must be a valid URL")
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("must use the http or https scheme")
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("must include a host")
+	}
+	return nil
+}
+
+// validateOutputFormats rejects outputs the operator has no implementation for
+func (v *RunbookCustomValidator) validateOutputFormats(runbook *runbookv1alpha1.Runbook, specPath *field.Path) field.ErrorList {
+	registry := v.Outputs
+	if registry == nil {
+		registry = outputs.DefaultRegistry
+	}
+
+	var allErrs field.ErrorList
+	for i, output := range runbook.Spec.Outputs {
+		if !registry.Has(output.Format) {
+			allErrs = append(allErrs, field.NotSupported(specPath.Child("outputs").Index(i).Child("format"),
+				output.Format, registry.Formats()))
+		}
+	}
+	return allErrs
+}
+
+// validateOutputDestinations rejects configmap outputs to namespaces the
+// operator does not allow
+func (v *RunbookCustomValidator) validateOutputDestinations(runbook *runbookv1alpha1.Runbook, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, output := range runbook.Spec.Outputs {
+		if output.Format != "configmap" {
+			continue
+		}
+		if err := outputs.CheckConfigMapNamespace(runbook.Namespace, output.Destination, v.ConfigMapNamespaces); err != nil {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("outputs").Index(i).Child("destination"), err.Error()))
+		}
+	}
+	return allErrs
+}
+
+// validateServiceAccount rejects automation running as a ServiceAccount the
+// operator does not allow
+func (v *RunbookCustomValidator) validateServiceAccount(runbook *runbookv1alpha1.Runbook, specPath *field.Path) field.ErrorList {
+	automationConfig := runbook.Spec.Content.Automation
+	if automationConfig == nil {
+		return nil
+	}
+	if err := automation.CheckServiceAccount(automationConfig.ServiceAccountName, v.DefaultServiceAccount, v.ServiceAccounts); err != nil {
+		return field.ErrorList{field.Forbidden(specPath.Child("content", "automation", "serviceAccountName"), err.Error())}
+	}
+	return nil
+}
+
+// validateTemplates checks that every referenced template exists as a RunbookTemplate
+func (v *RunbookCustomValidator) validateTemplates(ctx context.Context, runbook *runbookv1alpha1.Runbook, specPath *field.Path) (field.ErrorList, error) {
+	if runbook.Spec.Template == "" || runbook.Spec.Template == generator.DefaultTemplateName {
+		if !outputsReferenceTemplates(runbook) {
+			return nil, nil
+		}
+	}
+
+	var templates runbookv1alpha1.RunbookTemplateList
+	if err := v.Client.List(ctx, &templates); err != nil {
+		return nil, fmt.Errorf("failed to list runbook templates: %w", err)
+	}
+
+	known := make(map[string]bool, len(templates.Items))
+	for _, tmpl := range templates.Items {
+		known[tmpl.Spec.Name] = true
+	}
+	isKnown := func(name string) bool {
+		return name == "" || name == generator.DefaultTemplateName || known[name]
+	}
+
+	var allErrs field.ErrorList
+	if !isKnown(runbook.Spec.Template) {
+		allErrs = append(allErrs, field.NotFound(specPath.Child("template"), runbook.Spec.Template))
+	}
+	for i, output := range runbook.Spec.Outputs {
+		if !isKnown(output.Template) {
+			allErrs = append(allErrs, field.NotFound(specPath.Child("outputs").Index(i).Child("template"), output.Template))
+		}
+	}
+	return allErrs, nil
+}
+
+func outputsReferenceTemplates(runbook *runbookv1alpha1.Runbook) bool {
+	for _, output := range runbook.Spec.Outputs {
+		if output.Template != "" && output.Template != generator.DefaultTemplateName {
+			return true
+		}
+	}
+	return false
+}
+
+// validateUniqueAlertName rejects a second manually created runbook for the
+// same alert in a namespace. Runbooks generated from PrometheusRules are
+// exempt, since several rules commonly define the same alert.
+func (v *RunbookCustomValidator) validateUniqueAlertName(ctx context.Context, runbook *runbookv1alpha1.Runbook, generated bool, specPath *field.Path) (field.ErrorList, error) {
+	if generated {
+		return nil, nil
+	}
+
+	var runbooks runbookv1alpha1.RunbookList
+	if err := v.Client.List(ctx, &runbooks, client.InNamespace(runbook.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list runbooks: %w", err)
+	}
+
+	for _, existing := range runbooks.Items {
+		if existing.Name == runbook.Name || existing.Spec.AlertName != runbook.Spec.AlertName {
+			continue
+		}
+		existingGenerated, err := v.generatedFromPrometheusRule(ctx, &existing)
+		if err != nil {
+			return nil, err
+		}
+		if existingGenerated {
+			continue
+		}
+		return field.ErrorList{field.Duplicate(specPath.Child("alertName"), runbook.Spec.AlertName)}, nil
+	}
+	return nil, nil
+}
+
+// generatedFromPrometheusRule reports whether the runbook is controlled by a
+// PrometheusRule. Anyone writing the runbook can set its owner references, so
+// the PrometheusRule must exist with the UID the reference names.
+func (v *RunbookCustomValidator) generatedFromPrometheusRule(ctx context.Context, runbook *runbookv1alpha1.Runbook) (bool, error) {
+	owner := metav1.GetControllerOf(runbook)
+	if owner == nil || owner.Kind != "PrometheusRule" || owner.UID == "" {
+		return false, nil
+	}
+	groupVersion, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil || groupVersion.Group != "monitoring.coreos.com" {
+		return false, nil
+	}
+
+	rule := &unstructured.Unstructured{}
+	rule.SetGroupVersionKind(groupVersion.WithKind(owner.Kind))
+	if err := v.Client.Get(ctx, client.ObjectKey{Namespace: runbook.Namespace, Name: owner.Name}, rule); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get PrometheusRule %s: %w", owner.Name, err)
+	}
+	return rule.GetUID() == owner.UID, nil
+}
@@ -0,0 +1,350 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+)
+
+var _ = Describe("Runbook Webhook", func() {
+	var (
+		obj       *runbookv1alpha1.Runbook
+		validator RunbookCustomValidator
+	)
+
+	newValidator := func(objects ...client.Object) RunbookCustomValidator {
+		return RunbookCustomValidator{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		}
+	}
+
+	// prometheusRule is the PrometheusRule api-rules that generated runbooks reference
+	prometheusRule := func(uid types.UID) client.Object {
+		rule := &unstructured.Unstructured{}
+		rule.SetAPIVersion("monitoring.coreos.com/v1")
+		rule.SetKind("PrometheusRule")
+		rule.SetNamespace("default")
+		rule.SetName("api-rules")
+		rule.SetUID(uid)
+		return rule
+	}
+	const ruleUID = types.UID("6d1f5a8e-3c1b-4f7e-9a51-2f0f2b3c4d5e")
+
+	BeforeEach(func() {
+		obj = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: "high-error-rate", Namespace: "default"},
+			Spec: runbookv1alpha1.RunbookSpec{
+				AlertName: "HighErrorRate",
+				Severity:  "critical",
+				Content: runbookv1alpha1.RunbookContent{
+					Impact: "Checkout requests fail",
+					Investigation: []runbookv1alpha1.InvestigationStep{
+						{Description: "Check the error rate", Command: "kubectl logs deploy/api"},
+					},
+					Remediation: []runbookv1alpha1.RemediationStep{
+						{Description: "Restart the pods", Command: "kubectl rollout restart deploy/api", Risk: "low", Automated: true},
+					},
+					References: []runbookv1alpha1.Reference{
+						{Title: "Dashboard", URL: "https://grafana.example.com/d/api"},
+					},
+				},
+				Outputs: []runbookv1alpha1.OutputConfig{
+					{Format: "markdown", Destination: "/tmp/runbooks"},
+				},
+			},
+		}
+		validator = newValidator()
+	})
+
+	Context("When creating a Runbook under the validating webhook", func() {
+		It("Should admit a valid runbook", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should return warning level findings of the validation rules as warnings", func() {
+			obj.Spec.Content.Investigation = nil
+
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(
+				"warning spec.content.investigation: at least one investigation step is recommended",
+			))
+		})
+
+		It("Should deny error level findings of the validation rules", func() {
+			obj.Spec.Content.Impact = ""
+			obj.Spec.Content.References[0].Type = "ticket"
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.content.impact: Invalid value: impact is required for critical alerts"))
+			Expect(err.Error()).To(ContainSubstring("spec.content.references[0].type"))
+
+			By("Reporting them as warnings for runbooks generated from PrometheusRules")
+			controller := true
+			obj.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: "monitoring.coreos.com/v1",
+				Kind:       "PrometheusRule",
+				Name:       "api-rules",
+				UID:        ruleUID,
+				Controller: &controller,
+			}}
+			validator = newValidator(prometheusRule(ruleUID))
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ContainElement("error spec.content.impact: impact is required for critical alerts"))
+
+			By("Denying them when the PrometheusRule does not exist with the referenced UID")
+			validator = newValidator(prometheusRule("4b2e8c1d-7a3f-4e5b-8c9d-0e1f2a3b4c5d"))
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+			validator = newValidator()
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+
+		It("Should deny unknown template names", func() {
+			obj.Spec.Template = "missing"
+			obj.Spec.Outputs[0].Template = "also-missing"
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.template"))
+			Expect(err.Error()).To(ContainSubstring("spec.outputs[0].template"))
+		})
+
+		It("Should admit templates that exist as RunbookTemplates", func() {
+			validator = newValidator(&runbookv1alpha1.RunbookTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "compact"},
+				Spec:       runbookv1alpha1.RunbookTemplateSpec{Name: "compact", Template: "# {{ .Spec.AlertName }}"},
+			})
+			obj.Spec.Template = "compact"
+
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny output formats without an implementation", func() {
+			obj.Spec.Outputs[0].Format = "docx"
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.outputs[0].format"))
+		})
+
+		It("Should deny automated high risk remediation steps", func() {
+			obj.Spec.Content.Remediation[0].Risk = "high"
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.content.remediation[0].automated"))
+		})
+
+		It("Should deny automated high risk investigation steps", func() {
+			obj.Spec.Content.Investigation = append(obj.Spec.Content.Investigation, runbookv1alpha1.InvestigationStep{
+				Description: "Delete the stuck pods", Command: "kubectl delete pods -l app=api", Automated: true, Risk: "high",
+			})
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.content.investigation[1].automated"))
+		})
+
+		It("Should deny malformed reference URLs", func() {
+			obj.Spec.Content.References = append(obj.Spec.Content.References,
+				runbookv1alpha1.Reference{Title: "Wiki", URL: "wiki/api"},
+				runbookv1alpha1.Reference{Title: "Share", URL: "ftp://files.example.com/api"},
+			)
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.content.references[1].url"))
+			Expect(err.Error()).To(ContainSubstring("spec.content.references[2].url"))
+			Expect(err.Error()).NotTo(ContainSubstring("spec.content.references[0].url"))
+		})
+
+		It("Should deny a second runbook for the same alert in a namespace", func() {
+			existing := obj.DeepCopy()
+			existing.Name = "existing"
+			validator = newValidator(existing)
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.alertName"))
+
+			existing.Namespace = "other"
+			validator = newValidator(existing)
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should admit runbooks for the same alert generated from PrometheusRules", func() {
+			controller := true
+			generated := obj.DeepCopy()
+			generated.Name = "api-rules-higherrorrate"
+			generated.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: "monitoring.coreos.com/v1",
+				Kind:       "PrometheusRule",
+				Name:       "api-rules",
+				UID:        ruleUID,
+				Controller: &controller,
+			}}
+
+			By("Admitting a manual runbook next to a generated one")
+			validator = newValidator(generated, prometheusRule(ruleUID))
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			By("Admitting a generated runbook next to a manual one")
+			validator = newValidator(obj.DeepCopy(), prometheusRule(ruleUID))
+			Expect(validator.ValidateCreate(ctx, generated)).Error().NotTo(HaveOccurred())
+
+			By("Denying runbooks that only claim to be generated")
+			validator = newValidator(obj.DeepCopy())
+			_, err := validator.ValidateCreate(ctx, generated)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.alertName"))
+
+			validator = newValidator(generated)
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+
+	Context("When a Runbook automation names a ServiceAccount under the validating webhook", func() {
+		It("Should only admit the ServiceAccounts the operator allows", func() {
+			obj.Spec.Content.Automation = &runbookv1alpha1.AutomationConfig{Enabled: true}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Content.Automation.ServiceAccountName = "cluster-admin"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(
+				"spec.content.automation.serviceAccountName: Forbidden: automation steps can only run as the default ServiceAccount, not as cluster-admin"))
+
+			validator.DefaultServiceAccount = "runbook-steps"
+			validator.ServiceAccounts = []string{"api-remediation"}
+			obj.Spec.Content.Automation.ServiceAccountName = "runbook-steps"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+			obj.Spec.Content.Automation.ServiceAccountName = "api-remediation"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+			obj.Spec.Content.Automation.ServiceAccountName = "cluster-admin"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("or as api-remediation, not as cluster-admin")))
+		})
+	})
+
+	Context("When a Runbook writes ConfigMaps under the validating webhook", func() {
+		It("Should only admit the namespaces the operator allows", func() {
+			validator.ConfigMapNamespaces = []string{"runbooks"}
+			obj.Spec.Outputs = []runbookv1alpha1.OutputConfig{
+				{Format: "configmap"},
+				{Format: "configmap", Destination: "default"},
+				{Format: "configmap", Destination: "runbooks"},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Outputs = append(obj.Spec.Outputs, runbookv1alpha1.OutputConfig{Format: "configmap", Destination: "kube-system"})
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.outputs[3].destination: Forbidden: configmap outputs can only write to " +
+				"the namespace of the runbook or to runbooks, not to kube-system"))
+		})
+	})
+
+	Context("When updating a Runbook under the validating webhook", func() {
+		It("Should not treat the runbook itself as a duplicate", func() {
+			validator = newValidator(obj.DeepCopy())
+			Expect(validator.ValidateUpdate(ctx, obj, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should admit runbooks that are being deleted", func() {
+			obj.Spec.Template = "missing"
+			now := metav1.Now()
+			obj.DeletionTimestamp = &now
+
+			Expect(validator.ValidateUpdate(ctx, obj, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When setting the run-now annotation under the defaulting webhook", func() {
+		var defaulter RunbookCustomDefaulter
+
+		// requestBy returns the admission context of a request by the user,
+		// with the runbook as it was before when old is not nil
+		requestBy := func(username string, old *runbookv1alpha1.Runbook) context.Context {
+			req := admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: username}}
+			if old != nil {
+				raw, err := json.Marshal(old)
+				Expect(err).NotTo(HaveOccurred())
+				req.OldObject = runtime.RawExtension{Raw: raw}
+			}
+			return admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: req})
+		}
+
+		It("Should record the user that set a new run-now value", func() {
+			obj.Annotations = map[string]string{automation.RunNowAnnotation: "1"}
+			Expect(defaulter.Default(requestBy("alice", nil), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(automation.RunNowByAnnotation, "alice"))
+
+			old := obj.DeepCopy()
+			obj.Annotations[automation.RunNowAnnotation] = "2"
+			Expect(defaulter.Default(requestBy("bob", old), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(automation.RunNowByAnnotation, "bob"))
+		})
+
+		It("Should keep the recorded user when the run-now value is unchanged", func() {
+			obj.Annotations = map[string]string{
+				automation.RunNowAnnotation:   "1",
+				automation.RunNowByAnnotation: "alice",
+			}
+			old := obj.DeepCopy()
+
+			By("Restoring a user set by hand")
+			obj.Annotations[automation.RunNowByAnnotation] = "mallory"
+			Expect(defaulter.Default(requestBy("mallory", old), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(automation.RunNowByAnnotation, "alice"))
+
+			By("Restoring a removed user")
+			delete(obj.Annotations, automation.RunNowByAnnotation)
+			Expect(defaulter.Default(requestBy("mallory", old), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(automation.RunNowByAnnotation, "alice"))
+		})
+
+		It("Should not admit a user without a run-now value", func() {
+			obj.Annotations = map[string]string{automation.RunNowByAnnotation: "alice"}
+			Expect(defaulter.Default(requestBy("mallory", nil), obj)).To(Succeed())
+			Expect(obj.Annotations).NotTo(HaveKey(automation.RunNowByAnnotation))
+		})
+	})
+})
@@ -0,0 +1,341 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+)
+
+const (
+	// approveVerb is the RBAC verb on runbookexecutions that allows users to approve steps
+	approveVerb = "approve"
+
+	// dispatchVerb is the RBAC verb on runbookexecutions that allows creating
+	// executions requested by another user
+	dispatchVerb = "dispatch"
+)
+
+// log is for logging in this package.
+var runbookexecutionlog = logf.Log.WithName("runbookexecution-resource")
+
+// SetupRunbookExecutionWebhookWithManager registers the webhook for RunbookExecution in the manager.
+func SetupRunbookExecutionWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&runbookv1alpha1.RunbookExecution{}).
+		WithValidator(&RunbookExecutionCustomValidator{Client: mgr.GetClient()}).
+		WithDefaulter(&RunbookExecutionCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-runbook-runbook-io-v1alpha1-runbookexecution,mutating=true,failurePolicy=fail,sideEffects=None,groups=runbook.runbook.io,resources=runbookexecutions,verbs=create,versions=v1alpha1,name=mrunbookexecution-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RunbookExecutionCustomDefaulter records the user that created an execution,
+// so that the audit log names who requested it
+type RunbookExecutionCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &RunbookExecutionCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type RunbookExecution.
+func (d *RunbookExecutionCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	execution, ok := obj.(*runbookv1alpha1.RunbookExecution)
+	if !ok {
+		return fmt.Errorf("expected a RunbookExecution object but got %T", obj)
+	}
+	runbookexecutionlog.Info("Defaulting for RunbookExecution", "name", execution.GetName())
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	// Another user may only be named by those allowed to dispatch, which the validator checks
+	if execution.Annotations[automation.RequestedByAnnotation] == "" {
+		if execution.Annotations == nil {
+			execution.Annotations = map[string]string{}
+		}
+		execution.Annotations[automation.RequestedByAnnotation] = req.UserInfo.Username
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-runbook-runbook-io-v1alpha1-runbookexecution,mutating=false,failurePolicy=fail,sideEffects=None,groups=runbook.runbook.io,resources=runbookexecutions,verbs=create;update,versions=v1alpha1,name=vrunbookexecution-v1alpha1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// RunbookExecutionCustomValidator keeps the spec of an execution as it was
+// created, except for approvals, which are only admitted when added by the
+// user they name and when that user is allowed to approve the execution. The
+// user an execution was requested by cannot change either. New executions
+// must be allowed by the automation of their runbook.
+type RunbookExecutionCustomValidator struct {
+	// Client reads runbooks and creates the SubjectAccessReviews of approvers
+	Client client.Client
+}
+
+var _ webhook.CustomValidator = &RunbookExecutionCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type RunbookExecution.
+func (v *RunbookExecutionCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	execution, ok := obj.(*runbookv1alpha1.RunbookExecution)
+	if !ok {
+		return nil, fmt.Errorf("expected a RunbookExecution object but got %T", obj)
+	}
+	runbookexecutionlog.Info("Validation for RunbookExecution upon creation", "name", execution.GetName())
+
+	if err := v.validateRequestedBy(ctx, execution); err != nil {
+		return nil, err
+	}
+	if err := v.validateTrigger(ctx, execution); err != nil {
+		return nil, err
+	}
+	return nil, v.validateApprovals(ctx, &runbookv1alpha1.RunbookExecution{}, execution)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type RunbookExecution.
+func (v *RunbookExecutionCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldExecution, ok := oldObj.(*runbookv1alpha1.RunbookExecution)
+	if !ok {
+		return nil, fmt.Errorf("expected a RunbookExecution object for the oldObj but got %T", oldObj)
+	}
+	execution, ok := newObj.(*runbookv1alpha1.RunbookExecution)
+	if !ok {
+		return nil, fmt.Errorf("expected a RunbookExecution object for the newObj but got %T", newObj)
+	}
+	runbookexecutionlog.Info("Validation for RunbookExecution upon update", "name", execution.GetName())
+
+	if execution.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	if err := validateSpecUnchanged(oldExecution, execution); err != nil {
+		return nil, err
+	}
+	if execution.Annotations[automation.RequestedByAnnotation] != oldExecution.Annotations[automation.RequestedByAnnotation] {
+		return nil, apierrors.NewInvalid(runbookv1alpha1.GroupVersion.WithKind("RunbookExecution").GroupKind(), execution.Name,
+			field.ErrorList{field.Forbidden(requestedByPath, "the user an execution was requested by cannot be changed")})
+	}
+	return nil, v.validateApprovals(ctx, oldExecution, execution)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type RunbookExecution.
+func (v *RunbookExecutionCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+var requestedByPath = field.NewPath("metadata", "annotations").Key(automation.RequestedByAnnotation)
+
+// validateRequestedBy only admits executions requested by the user that
+// creates them, unless that user is allowed to dispatch executions for others
+func (v *RunbookExecutionCustomValidator) validateRequestedBy(ctx context.Context, execution *runbookv1alpha1.RunbookExecution) error {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	user := execution.Annotations[automation.RequestedByAnnotation]
+	if user == "" || user == req.UserInfo.Username {
+		return nil
+	}
+
+	allowed, err := v.allowed(ctx, req, execution, dispatchVerb)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	if allowed {
+		return nil
+	}
+	return apierrors.NewInvalid(runbookv1alpha1.GroupVersion.WithKind("RunbookExecution").GroupKind(), execution.Name,
+		field.ErrorList{field.Forbidden(requestedByPath,
+			fmt.Sprintf("user %q is not allowed to %s runbookexecutions requested by %q", req.UserInfo.Username, dispatchVerb, user))})
+}
+
+// validateTrigger only admits executions the automation of their runbook
+// allows. Executions for alerts and webhooks can only be created by users
+// allowed to dispatch, like the operator, since the trigger conditions were
+// checked against an event the webhook does not see.
+func (v *RunbookExecutionCustomValidator) validateTrigger(ctx context.Context, execution *runbookv1alpha1.RunbookExecution) error {
+	var runbook runbookv1alpha1.Runbook
+	if err := v.Client.Get(ctx, client.ObjectKey{Namespace: execution.Namespace, Name: execution.Spec.RunbookName}, &runbook); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The controller fails executions of missing runbooks
+			return nil
+		}
+		return apierrors.NewInternalError(err)
+	}
+
+	if reason, message := automation.CheckAllowed(&runbook, execution); reason != "" {
+		return apierrors.NewInvalid(runbookv1alpha1.GroupVersion.WithKind("RunbookExecution").GroupKind(), execution.Name,
+			field.ErrorList{field.Forbidden(field.NewPath("spec", "runbookName"), message)})
+	}
+
+	trigger := execution.Spec.Trigger
+	if trigger == nil || trigger.Type == automation.TriggerManual {
+		return nil
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	allowed, err := v.allowed(ctx, req, execution, dispatchVerb)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	if allowed {
+		return nil
+	}
+	return apierrors.NewInvalid(runbookv1alpha1.GroupVersion.WithKind("RunbookExecution").GroupKind(), execution.Name,
+		field.ErrorList{field.Forbidden(field.NewPath("spec", "trigger", "type"),
+			fmt.Sprintf("user %q is not allowed to %s runbookexecutions with %s triggers", req.UserInfo.Username, dispatchVerb, trigger.Type))})
+}
+
+// validateSpecUnchanged rejects changes to the spec other than approvals, so
+// that the steps run with what the approvers signed off
+func validateSpecUnchanged(oldExecution, execution *runbookv1alpha1.RunbookExecution) error {
+	oldSpec, spec := oldExecution.Spec.DeepCopy(), execution.Spec.DeepCopy()
+	oldSpec.Approvals, spec.Approvals = nil, nil
+	if equality.Semantic.DeepEqual(oldSpec, spec) {
+		return nil
+	}
+	return apierrors.NewInvalid(runbookv1alpha1.GroupVersion.WithKind("RunbookExecution").GroupKind(), execution.Name,
+		field.ErrorList{field.Forbidden(field.NewPath("spec"), "only approvals can be added to the spec of an execution")})
+}
+
+// validateApprovals keeps existing approvals as they are and checks that the
+// requesting user may add the new ones
+func (v *RunbookExecutionCustomValidator) validateApprovals(ctx context.Context, oldExecution, execution *runbookv1alpha1.RunbookExecution) error {
+	approvalsPath := field.NewPath("spec", "approvals")
+
+	previous := map[string]runbookv1alpha1.Approval{}
+	for _, approval := range oldExecution.Spec.Approvals {
+		previous[approval.Step] = approval
+	}
+
+	var allErrs field.ErrorList
+	current := map[string]bool{}
+	for i, approval := range execution.Spec.Approvals {
+		current[approval.Step] = true
+		if old, ok := previous[approval.Step]; ok {
+			if old != approval {
+				allErrs = append(allErrs, field.Forbidden(approvalsPath.Index(i), "approvals cannot be changed"))
+			}
+			continue
+		}
+
+		errs, err := v.validateNewApproval(ctx, oldExecution, execution, approval, approvalsPath.Index(i))
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		allErrs = append(allErrs, errs...)
+	}
+
+	for step := range previous {
+		if !current[step] {
+			allErrs = append(allErrs, field.Forbidden(approvalsPath, fmt.Sprintf("the approval of step %s cannot be removed", step)))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(runbookv1alpha1.GroupVersion.WithKind("RunbookExecution").GroupKind(), execution.Name, allErrs)
+}
+
+func (v *RunbookExecutionCustomValidator) validateNewApproval(ctx context.Context, oldExecution, execution *runbookv1alpha1.RunbookExecution, approval runbookv1alpha1.Approval, approvalPath *field.Path) (field.ErrorList, error) {
+	var allErrs field.ErrorList
+
+	if oldExecution.Finished() {
+		return append(allErrs, field.Forbidden(approvalPath, "the execution has already finished")), nil
+	}
+
+	// Steps are only known once the controller captured the plan
+	if len(oldExecution.Status.Steps) > 0 {
+		step := findStep(oldExecution, approval.Step)
+		switch {
+		case step == nil:
+			allErrs = append(allErrs, field.NotFound(approvalPath.Child("step"), approval.Step))
+		case step.Risk == runbookv1alpha1.RiskHigh:
+			allErrs = append(allErrs, field.Forbidden(approvalPath.Child("step"), "high risk steps are never run automatically"))
+		}
+	}
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if approval.User != req.UserInfo.Username {
+		return append(allErrs, field.Forbidden(approvalPath.Child("user"),
+			fmt.Sprintf("approvals can only be added by the user they name, the request was made by %q", req.UserInfo.Username))), nil
+	}
+
+	allowed, err := v.allowed(ctx, req, execution, approveVerb)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		allErrs = append(allErrs, field.Forbidden(approvalPath.Child("user"),
+			fmt.Sprintf("user %q is not allowed to %s runbookexecutions in namespace %s", approval.User, approveVerb, execution.Namespace)))
+	}
+	return allErrs, nil
+}
+
+// allowed asks the API server whether the requesting user has the verb on the execution
+func (v *RunbookExecutionCustomValidator) allowed(ctx context.Context, req admission.Request, execution *runbookv1alpha1.RunbookExecution, verb string) (bool, error) {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range req.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			Groups: req.UserInfo.Groups,
+			UID:    req.UserInfo.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: execution.Namespace,
+				Verb:      verb,
+				Group:     runbookv1alpha1.GroupVersion.Group,
+				Resource:  "runbookexecutions",
+				Name:      execution.Name,
+			},
+		},
+	}
+	if err := v.Client.Create(ctx, review); err != nil {
+		return false, fmt.Errorf("failed to review the access of %s: %w", req.UserInfo.Username, err)
+	}
+	return review.Status.Allowed, nil
+}
+
+func findStep(execution *runbookv1alpha1.RunbookExecution, name string) *runbookv1alpha1.ExecutionStep {
+	for i := range execution.Status.Steps {
+		if execution.Status.Steps[i].Name == name {
+			return &execution.Status.Steps[i]
+		}
+	}
+	return nil
+}
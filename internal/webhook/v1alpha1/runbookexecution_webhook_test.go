@@ -0,0 +1,287 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/internal/automation"
+)
+
+var _ = Describe("RunbookExecution Webhook", func() {
+	var (
+		oldObj      *runbookv1alpha1.RunbookExecution
+		obj         *runbookv1alpha1.RunbookExecution
+		validator   RunbookExecutionCustomValidator
+		approvers   map[string]bool
+		dispatchers map[string]bool
+		reviews     []authorizationv1.SubjectAccessReview
+		runbook     *runbookv1alpha1.Runbook
+	)
+
+	requestBy := func(username string) context.Context {
+		return admission.NewContextWithRequest(ctx, admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: username, Groups: []string{"sre"}},
+			},
+		})
+	}
+
+	BeforeEach(func() {
+		approvers = map[string]bool{"alice": true}
+		dispatchers = map[string]bool{"system:serviceaccount:runbook-operator-system:controller-manager": true}
+		reviews = nil
+		runbook = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: "high-error-rate", Namespace: "default"},
+			Spec: runbookv1alpha1.RunbookSpec{
+				Content: runbookv1alpha1.RunbookContent{
+					Automation: &runbookv1alpha1.AutomationConfig{
+						Enabled:  true,
+						Triggers: []runbookv1alpha1.TriggerConfig{{Type: "manual"}},
+					},
+				},
+			},
+		}
+		validator = RunbookExecutionCustomValidator{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(runbook).WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+						granted := approvers
+						if review.Spec.ResourceAttributes.Verb == "dispatch" {
+							granted = dispatchers
+						}
+						review.Status.Allowed = granted[review.Spec.User]
+						reviews = append(reviews, *review)
+						return nil
+					}
+					return c.Create(ctx, obj, opts...)
+				},
+			}).Build(),
+		}
+
+		oldObj = &runbookv1alpha1.RunbookExecution{
+			ObjectMeta: metav1.ObjectMeta{Name: "high-error-rate-1", Namespace: "default"},
+			Spec:       runbookv1alpha1.RunbookExecutionSpec{RunbookName: "high-error-rate"},
+			Status: runbookv1alpha1.RunbookExecutionStatus{
+				Phase: runbookv1alpha1.ExecutionRunning,
+				Steps: []runbookv1alpha1.ExecutionStep{
+					{Name: "remediation-0", Risk: runbookv1alpha1.RiskMedium, Phase: runbookv1alpha1.ExecutionPending},
+					{Name: "remediation-1", Risk: runbookv1alpha1.RiskHigh, Phase: runbookv1alpha1.ExecutionPending},
+				},
+			},
+		}
+		obj = oldObj.DeepCopy()
+	})
+
+	Context("When approving a step", func() {
+		It("Should admit an approval by an allowed user", func() {
+			obj.Spec.Approvals = []runbookv1alpha1.Approval{{Step: "remediation-0", User: "alice"}}
+
+			Expect(validator.ValidateUpdate(requestBy("alice"), oldObj, obj)).Error().NotTo(HaveOccurred())
+			Expect(reviews).To(HaveLen(1))
+			attributes := reviews[0].Spec.ResourceAttributes
+			Expect(attributes.Verb).To(Equal("approve"))
+			Expect(attributes.Resource).To(Equal("runbookexecutions"))
+			Expect(attributes.Name).To(Equal("high-error-rate-1"))
+			Expect(reviews[0].Spec.Groups).To(ConsistOf("sre"))
+		})
+
+		It("Should deny approvals on behalf of another user", func() {
+			obj.Spec.Approvals = []runbookv1alpha1.Approval{{Step: "remediation-0", User: "alice"}}
+
+			_, err := validator.ValidateUpdate(requestBy("bob"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("can only be added by the user they name"))
+		})
+
+		It("Should deny users without the approve verb", func() {
+			obj.Spec.Approvals = []runbookv1alpha1.Approval{{Step: "remediation-0", User: "bob"}}
+
+			_, err := validator.ValidateUpdate(requestBy("bob"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`user "bob" is not allowed to approve`))
+		})
+
+		It("Should deny approvals of high risk or unknown steps", func() {
+			obj.Spec.Approvals = []runbookv1alpha1.Approval{
+				{Step: "remediation-1", User: "alice"},
+				{Step: "remediation-7", User: "alice"},
+			}
+
+			_, err := validator.ValidateUpdate(requestBy("alice"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("high risk steps are never run automatically"))
+			Expect(err.Error()).To(ContainSubstring(`spec.approvals[1].step: Not found: "remediation-7"`))
+		})
+
+		It("Should deny approvals of finished executions", func() {
+			oldObj.Status.Phase = runbookv1alpha1.ExecutionFailed
+			obj.Spec.Approvals = []runbookv1alpha1.Approval{{Step: "remediation-0", User: "alice"}}
+
+			_, err := validator.ValidateUpdate(requestBy("alice"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("the execution has already finished"))
+		})
+	})
+
+	Context("When updating an approved execution", func() {
+		BeforeEach(func() {
+			oldObj.Spec.Approvals = []runbookv1alpha1.Approval{{Step: "remediation-0", User: "alice"}}
+			obj = oldObj.DeepCopy()
+		})
+
+		It("Should admit updates by other users that keep the approvals", func() {
+			obj.Labels = map[string]string{"team": "api"}
+
+			Expect(validator.ValidateUpdate(requestBy("system:serviceaccount:runbook-operator-system:controller-manager"), oldObj, obj)).
+				Error().NotTo(HaveOccurred())
+			Expect(reviews).To(BeEmpty())
+		})
+
+		It("Should deny changing or removing approvals", func() {
+			obj.Spec.Approvals[0].User = "mallory"
+			_, err := validator.ValidateUpdate(requestBy("mallory"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("approvals cannot be changed"))
+
+			obj.Spec.Approvals = nil
+			_, err = validator.ValidateUpdate(requestBy("mallory"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("the approval of step remediation-0 cannot be removed"))
+		})
+
+		It("Should deny changes to the rest of the spec", func() {
+			obj.Spec.RunbookName = "scale-down"
+			_, err := validator.ValidateUpdate(requestBy("alice"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("only approvals can be added to the spec of an execution"))
+
+			obj = oldObj.DeepCopy()
+			obj.Spec.Trigger = &runbookv1alpha1.TriggerEvent{Type: "manual"}
+			_, err = validator.ValidateUpdate(requestBy("alice"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+
+			obj = oldObj.DeepCopy()
+			obj.Spec.DryRun = true
+			_, err = validator.ValidateUpdate(requestBy("alice"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When creating an execution", func() {
+		It("Should admit executions without approvals", func() {
+			obj.Status = runbookv1alpha1.RunbookExecutionStatus{}
+
+			Expect(validator.ValidateCreate(requestBy("bob"), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should record the user that created the execution", func() {
+			defaulter := RunbookExecutionCustomDefaulter{}
+			Expect(defaulter.Default(requestBy("bob"), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(automation.RequestedByAnnotation, "bob"))
+			Expect(validator.ValidateCreate(requestBy("bob"), obj)).Error().NotTo(HaveOccurred())
+			Expect(reviews).To(BeEmpty())
+		})
+
+		It("Should only admit executions requested by another user from users allowed to dispatch", func() {
+			obj.Status = runbookv1alpha1.RunbookExecutionStatus{}
+			obj.Annotations = map[string]string{automation.RequestedByAnnotation: "alice"}
+
+			defaulter := RunbookExecutionCustomDefaulter{}
+			Expect(defaulter.Default(requestBy("mallory"), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(automation.RequestedByAnnotation, "alice"))
+
+			_, err := validator.ValidateCreate(requestBy("mallory"), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`user "mallory" is not allowed to dispatch runbookexecutions requested by "alice"`))
+
+			By("Admitting the operator, which creates executions for the user that started a manual run")
+			Expect(validator.ValidateCreate(requestBy("system:serviceaccount:runbook-operator-system:controller-manager"), obj)).
+				Error().NotTo(HaveOccurred())
+			Expect(reviews[len(reviews)-1].Spec.ResourceAttributes.Verb).To(Equal("dispatch"))
+		})
+	})
+
+	Context("When creating an execution for a runbook", func() {
+		BeforeEach(func() {
+			obj.Status = runbookv1alpha1.RunbookExecutionStatus{}
+		})
+
+		It("Should deny executions of runbooks whose automation is disabled", func() {
+			runbook.Spec.Content.Automation.Enabled = false
+			Expect(validator.Client.Update(ctx, runbook)).To(Succeed())
+
+			_, err := validator.ValidateCreate(requestBy("bob"), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Automation is disabled for runbook high-error-rate"))
+		})
+
+		It("Should deny manual executions of runbooks without a matching manual trigger", func() {
+			runbook.Spec.Content.Automation.Triggers = []runbookv1alpha1.TriggerConfig{{Type: "manual", Conditions: []string{"team=payments"}}}
+			Expect(validator.Client.Update(ctx, runbook)).To(Succeed())
+
+			obj.Spec.Trigger = &runbookv1alpha1.TriggerEvent{Type: "manual"}
+			_, err := validator.ValidateCreate(requestBy("bob"), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("No manual trigger of runbook high-error-rate matches its labels"))
+
+			runbook.Labels = map[string]string{"team": "payments"}
+			Expect(validator.Client.Update(ctx, runbook)).To(Succeed())
+			Expect(validator.ValidateCreate(requestBy("bob"), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should only admit alert executions from users allowed to dispatch", func() {
+			obj.Spec.Trigger = &runbookv1alpha1.TriggerEvent{Type: "alert"}
+
+			_, err := validator.ValidateCreate(requestBy("bob"), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`user "bob" is not allowed to dispatch runbookexecutions with alert triggers`))
+
+			Expect(validator.ValidateCreate(requestBy("system:serviceaccount:runbook-operator-system:controller-manager"), obj)).
+				Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When updating the requester of an execution", func() {
+		It("Should deny changing or removing it", func() {
+			oldObj.Annotations = map[string]string{automation.RequestedByAnnotation: "alice"}
+			obj = oldObj.DeepCopy()
+			obj.Annotations[automation.RequestedByAnnotation] = "bob"
+
+			_, err := validator.ValidateUpdate(requestBy("bob"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("the user an execution was requested by cannot be changed"))
+
+			delete(obj.Annotations, automation.RequestedByAnnotation)
+			_, err = validator.ValidateUpdate(requestBy("bob"), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,120 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/generator"
+)
+
+// log is for logging in this package.
+var runbooktemplatelog = logf.Log.WithName("runbooktemplate-resource")
+
+// SetupRunbookTemplateWebhookWithManager registers the webhook for RunbookTemplate in the manager.
+func SetupRunbookTemplateWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&runbookv1alpha1.RunbookTemplate{}).
+		WithValidator(&RunbookTemplateCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-runbook-runbook-io-v1alpha1-runbooktemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=runbook.runbook.io,resources=runbooktemplates,verbs=create;update,versions=v1alpha1,name=vrunbooktemplate-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RunbookTemplateCustomValidator rejects RunbookTemplates that the generator
+// cannot load or that register a template name another RunbookTemplate uses
+type RunbookTemplateCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &RunbookTemplateCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type RunbookTemplate.
+func (v *RunbookTemplateCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	runbookTemplate, ok := obj.(*runbookv1alpha1.RunbookTemplate)
+	if !ok {
+		return nil, fmt.Errorf("expected a RunbookTemplate object but got %T", obj)
+	}
+	runbooktemplatelog.Info("Validation for RunbookTemplate upon creation", "name", runbookTemplate.GetName())
+
+	return nil, v.validateRunbookTemplate(ctx, runbookTemplate)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type RunbookTemplate.
+func (v *RunbookTemplateCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	runbookTemplate, ok := newObj.(*runbookv1alpha1.RunbookTemplate)
+	if !ok {
+		return nil, fmt.Errorf("expected a RunbookTemplate object for the newObj but got %T", newObj)
+	}
+	runbooktemplatelog.Info("Validation for RunbookTemplate upon update", "name", runbookTemplate.GetName())
+
+	if runbookTemplate.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	return nil, v.validateRunbookTemplate(ctx, runbookTemplate)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type RunbookTemplate.
+func (v *RunbookTemplateCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *RunbookTemplateCustomValidator) validateRunbookTemplate(ctx context.Context, runbookTemplate *runbookv1alpha1.RunbookTemplate) error {
+	var allErrs field.ErrorList
+	if err := generator.ValidateTemplate(&runbookTemplate.Spec); err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "template"), field.OmitValueType{}, err.Error()))
+	}
+
+	duplicateErrs, err := v.validateUniqueName(ctx, runbookTemplate)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	allErrs = append(allErrs, duplicateErrs...)
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(runbookv1alpha1.GroupVersion.WithKind("RunbookTemplate").GroupKind(), runbookTemplate.Name, allErrs)
+}
+
+// validateUniqueName rejects a second RunbookTemplate registering the same
+// template name, which would replace the other one in the generator
+func (v *RunbookTemplateCustomValidator) validateUniqueName(ctx context.Context, runbookTemplate *runbookv1alpha1.RunbookTemplate) (field.ErrorList, error) {
+	var templates runbookv1alpha1.RunbookTemplateList
+	if err := v.Client.List(ctx, &templates); err != nil {
+		return nil, fmt.Errorf("failed to list runbook templates: %w", err)
+	}
+
+	for _, existing := range templates.Items {
+		if existing.Name == runbookTemplate.Name || existing.Spec.Name != runbookTemplate.Spec.Name {
+			continue
+		}
+		return field.ErrorList{field.Duplicate(field.NewPath("spec", "name"), runbookTemplate.Spec.Name)}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2025 Geovane Guibes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+var _ = Describe("RunbookTemplate Webhook", func() {
+	var (
+		obj       *runbookv1alpha1.RunbookTemplate
+		validator RunbookTemplateCustomValidator
+	)
+
+	newValidator := func(objects ...client.Object) RunbookTemplateCustomValidator {
+		return RunbookTemplateCustomValidator{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		}
+	}
+
+	BeforeEach(func() {
+		obj = &runbookv1alpha1.RunbookTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "compact"},
+			Spec: runbookv1alpha1.RunbookTemplateSpec{
+				Name:     "compact",
+				Template: "# {{ .Spec.AlertName }}\n{{ range $i, $step := .Spec.Content.Investigation }}{{ add $i 1 }}. {{ $step.Description }}\n{{ end }}",
+			},
+		}
+		validator = newValidator()
+	})
+
+	Context("When creating or updating a RunbookTemplate under the validating webhook", func() {
+		It("Should admit a template that parses", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+			Expect(validator.ValidateUpdate(ctx, obj, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a template that fails to parse", func() {
+			obj.Spec.Template = "# {{ .Spec.AlertName "
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.template"))
+
+			_, err = validator.ValidateUpdate(ctx, obj, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+
+		It("Should deny variable defaults that do not match their type", func() {
+			obj.Spec.Variables = map[string]runbookv1alpha1.TemplateVariable{
+				"threshold": {Type: "number", Default: "high"},
+			}
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+
+		It("Should deny a template name registered by another RunbookTemplate", func() {
+			existing := obj.DeepCopy()
+			existing.Name = "compact-v1"
+			validator = newValidator(existing)
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.name"))
+
+			By("Admitting updates of the RunbookTemplate that registered it")
+			validator = newValidator(obj.DeepCopy())
+			Expect(validator.ValidateUpdate(ctx, obj, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+})
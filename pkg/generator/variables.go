@@ -0,0 +1,163 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// VariableError reports every problem found while resolving template variables
+type VariableError struct {
+	// Template is the name of the template the variables belong to
+	Template string
+
+	// Problems lists one message per invalid or missing variable
+	Problems []string
+}
+
+func (e *VariableError) Error() string {
+	return fmt.Sprintf("template %q: %s", e.Template, strings.Join(e.Problems, "; "))
+}
+
+// ResolveVariables applies defaults to the supplied values, coerces them to the
+// declared types and checks that every required variable is set. Values that
+// are not declared by the template are passed through as strings.
+func ResolveVariables(templateName string, declared map[string]runbookv1alpha1.TemplateVariable, values map[string]string) (map[string]interface{}, error) {
+	vars := make(map[string]interface{}, len(declared)+len(values))
+	for name, value := range values {
+		if _, ok := declared[name]; !ok {
+			vars[name] = value
+		}
+	}
+
+	var problems []string
+	for _, name := range sortedVariableNames(declared) {
+		variable := declared[name]
+
+		value, ok := values[name]
+		if !ok || value == "" {
+			value = variable.Default
+		}
+		if value == "" && variable.Required {
+			problems = append(problems, fmt.Sprintf("missing required variable %q", name))
+			continue
+		}
+
+		coerced, err := coerceVariable(variable.Type, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("variable %q: %v", name, err))
+			continue
+		}
+		vars[name] = coerced
+	}
+
+	if len(problems) > 0 {
+		return nil, &VariableError{Template: templateName, Problems: problems}
+	}
+	return vars, nil
+}
+
+// ValidateVariables checks that the variable declarations and their defaults are consistent
+func ValidateVariables(declared map[string]runbookv1alpha1.TemplateVariable) error {
+	var problems []string
+	for _, name := range sortedVariableNames(declared) {
+		variable := declared[name]
+		if variable.Default == "" {
+			continue
+		}
+		if _, err := coerceVariable(variable.Type, variable.Default); err != nil {
+			problems = append(problems, fmt.Sprintf("default of variable %q: %v", name, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// coerceVariable converts a raw value to the declared variable type. Empty
+// values yield the zero value of the type so templates can test them with if.
+func coerceVariable(variableType string, value string) (interface{}, error) {
+	switch variableType {
+	case "", "string":
+		return value, nil
+	case "number":
+		if value == "" {
+			return float64(0), nil
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return number, nil
+	case "boolean":
+		if value == "" {
+			return false, nil
+		}
+		boolean, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", value)
+		}
+		return boolean, nil
+	case "array":
+		return parseArray(value)
+	default:
+		return nil, fmt.Errorf("unsupported variable type %q", variableType)
+	}
+}
+
+// parseArray accepts either a JSON array or a comma separated list
+func parseArray(value string) ([]interface{}, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return []interface{}{}, nil
+	}
+
+	if strings.HasPrefix(value, "[") {
+		var items []interface{}
+		if err := json.Unmarshal([]byte(value), &items); err != nil {
+			return nil, fmt.Errorf("%q is not a valid JSON array: %v", value, err)
+		}
+		return items, nil
+	}
+
+	parts := strings.Split(value, ",")
+	items := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items, nil
+}
+
+// mergeVariables overlays the output variables on top of the runbook variables
+func mergeVariables(base, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return base
+	}
+
+	merged := make(map[string]string, len(base)+len(overrides))
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range overrides {
+		merged[name] = value
+	}
+	return merged
+}
+
+func sortedVariableNames(declared map[string]runbookv1alpha1.TemplateVariable) []string {
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
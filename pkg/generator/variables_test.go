@@ -0,0 +1,83 @@
+package generator
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+var _ = Describe("ResolveVariables", func() {
+	declared := map[string]runbookv1alpha1.TemplateVariable{
+		"threshold": {Type: "number", Default: "0.5"},
+		"paging":    {Type: "boolean"},
+		"regions":   {Type: "array", Default: "eu-west-1, us-east-1"},
+		"service":   {Type: "string", Required: true},
+	}
+
+	It("should coerce values to their declared types", func() {
+		vars, err := ResolveVariables("compact", declared, map[string]string{
+			"threshold": " 0.95 ",
+			"paging":    "true",
+			"regions":   `["eu-west-1", 3]`,
+			"service":   "checkout",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vars).To(Equal(map[string]interface{}{
+			"threshold": 0.95,
+			"paging":    true,
+			"regions":   []interface{}{"eu-west-1", float64(3)},
+			"service":   "checkout",
+		}))
+	})
+
+	It("should apply defaults and zero values to unset variables", func() {
+		vars, err := ResolveVariables("compact", declared, map[string]string{"service": "checkout", "threshold": ""})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vars).To(HaveKeyWithValue("threshold", 0.5))
+		Expect(vars).To(HaveKeyWithValue("paging", false))
+		Expect(vars).To(HaveKeyWithValue("regions", []interface{}{"eu-west-1", "us-east-1"}))
+	})
+
+	It("should pass undeclared values through as strings", func() {
+		vars, err := ResolveVariables("compact", nil, map[string]string{"dashboard": "https://grafana.example.com"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vars).To(Equal(map[string]interface{}{"dashboard": "https://grafana.example.com"}))
+	})
+
+	It("should report every missing or invalid variable", func() {
+		_, err := ResolveVariables("compact", declared, map[string]string{"threshold": "high", "paging": "maybe"})
+
+		var variableErr *VariableError
+		Expect(err).To(BeAssignableToTypeOf(variableErr))
+		variableErr = err.(*VariableError)
+		Expect(variableErr.Template).To(Equal("compact"))
+		Expect(variableErr.Problems).To(ConsistOf(
+			`variable "paging": "maybe" is not a boolean`,
+			`missing required variable "service"`,
+			`variable "threshold": "high" is not a number`,
+		))
+	})
+
+	It("should reject unsupported types and invalid JSON arrays", func() {
+		_, err := ResolveVariables("compact", map[string]runbookv1alpha1.TemplateVariable{
+			"owner":   {Type: "object", Default: "team"},
+			"regions": {Type: "array", Default: "[eu-west-1"},
+		}, nil)
+		Expect(err).To(MatchError(ContainSubstring(`unsupported variable type "object"`)))
+		Expect(err).To(MatchError(ContainSubstring(`is not a valid JSON array`)))
+	})
+})
+
+var _ = Describe("ValidateVariables", func() {
+	It("should check defaults against their types", func() {
+		Expect(ValidateVariables(map[string]runbookv1alpha1.TemplateVariable{
+			"threshold": {Type: "number", Default: "0.5"},
+			"service":   {Type: "string", Required: true},
+		})).To(Succeed())
+
+		Expect(ValidateVariables(map[string]runbookv1alpha1.TemplateVariable{
+			"threshold": {Type: "number", Default: "high"},
+		})).To(MatchError(`default of variable "threshold": "high" is not a number`))
+	})
+})
@@ -2,17 +2,64 @@ package outputs
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
 	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
 )
 
+func init() {
+	Register("api", func(config runbookv1alpha1.OutputConfig, opts Options) (Output, error) {
+		if _, err := url.ParseRequestURI(config.Destination); err != nil {
+			return nil, fmt.Errorf("api output destination must be the base URL of the API: %w", err)
+		}
+		return &APIOutput{
+			BaseURL: config.Destination,
+			Config:  config,
+			Secrets: opts.secretReader(),
+		}, nil
+	})
+}
+
+// DefaultAPIRetry is how often and how fast API requests are retried on
+// 5xx and 429 responses or connection errors
+var DefaultAPIRetry = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    4,
+	Cap:      10 * time.Second,
+}
+
+// APIOutput publishes runbooks to a runbook catalog API. Runbooks are
+// upserted with PUT {BaseURL}/runbooks/{id}, where the ID is derived from
+// the runbook namespace and name, and deleted on cleanup.
+//
+// The Secret keys are token for bearer authentication, username and
+// password for basic authentication, and tls.crt, tls.key and ca.crt for
+// mutual TLS, so that a kubernetes.io/tls Secret can be referenced.
 type APIOutput struct {
 	BaseURL string
-	ApiKey  string
+	Config  runbookv1alpha1.OutputConfig
+	Secrets client.Reader
+
+	// Retry overrides DefaultAPIRetry
+	Retry *wait.Backoff
 }
 
 type RunbookAPI struct {
@@ -25,45 +72,231 @@ type RunbookAPI struct {
 	GeneratedAt time.Time              `json:"generated_at"`
 }
 
-func (a *APIOutput) Generate(runbook *runbookv1alpha1.Runbook, content string) error {
+func (a *APIOutput) Generate(ctx context.Context, runbook *runbookv1alpha1.Runbook, content string) (string, error) {
 	apiData := RunbookAPI{
-		ID:        fmt.Sprintf("%s-%s", runbook.Namespace, runbook.Name),
+		ID:        runbookID(runbook),
 		AlertName: runbook.Spec.AlertName,
 		Severity:  runbook.Spec.Severity,
 		Team:      runbook.Spec.Team,
 		Content:   content,
 		Metadata: map[string]interface{}{
-			"namespace": runbook.Namespace,
-			"outputs":   runbook.Spec.Outputs,
+			"namespace":  runbook.Namespace,
+			"generation": runbook.Generation,
+			"outputs":    runbook.Spec.Outputs,
 		},
 		GeneratedAt: time.Now(),
 	}
 
 	jsonData, err := json.Marshal(apiData)
+	if err != nil {
+		return "", err
+	}
+
+	location := a.runbookURL(apiData.ID)
+	if err := a.do(ctx, runbook.Namespace, http.MethodPut, location, jsonData); err != nil {
+		return "", err
+	}
+	return location, nil
+}
+
+// Cleanup removes the runbook from the remote catalog
+func (a *APIOutput) Cleanup(ctx context.Context, runbook *runbookv1alpha1.Runbook, location string) error {
+	err := a.do(ctx, runbook.Namespace, http.MethodDelete, location, nil)
+	var statusErr *apiStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		// Already gone from the catalog
+		return nil
+	}
+	return err
+}
+
+func (a *APIOutput) Describe() string {
+	return fmt.Sprintf("runbook catalog API at %s", a.BaseURL)
+}
+
+func (a *APIOutput) runbookURL(id string) string {
+	return fmt.Sprintf("%s/runbooks/%s", a.BaseURL, url.PathEscape(id))
+}
+
+// do sends the request, retrying it with backoff while the API is
+// unavailable or rate limits the operator
+func (a *APIOutput) do(ctx context.Context, namespace, method, location string, body []byte) error {
+	data, err := readSecret(ctx, a.Secrets, a.Config, namespace)
+	if err != nil {
+		return err
+	}
+	httpClient, err := apiClient(a.Config, namespace, data)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/runbooks", a.BaseURL), bytes.NewBuffer(jsonData))
+	backoff := DefaultAPIRetry
+	if a.Retry != nil {
+		backoff = *a.Retry
+	}
+	// Step stops counting once the delay reaches the cap, so attempts are counted here
+	attempts := max(backoff.Steps, 1)
+	for attempt := 1; ; attempt++ {
+		err = a.send(ctx, httpClient, data, method, location, body)
+		if !retryable(err) || attempt >= attempts {
+			return err
+		}
+
+		delay := backoff.Step()
+		var statusErr *apiStatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+			if backoff.Cap > 0 {
+				delay = min(delay, backoff.Cap)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (a *APIOutput) send(ctx context.Context, httpClient *http.Client, data map[string][]byte, method, location string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, location, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
-	req.Header.Set("Content-Type", "application/json")
-	if a.ApiKey != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.ApiKey))
+	switch {
+	case len(data["token"]) > 0:
+		req.Header.Set("Authorization", "Bearer "+string(data["token"]))
+	case len(data["username"]) > 0:
+		req.SetBasicAuth(string(data["username"]), string(data["password"]))
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer resp.Body.Close() //nolint:errcheck
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		statusErr := &apiStatusError{StatusCode: resp.StatusCode}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			statusErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return statusErr
 	}
 
 	return nil
 }
+
+// retryable reports whether the request may succeed when sent again
+func retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *apiStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	if tlsError(err) {
+		return false
+	}
+	// Connection errors are transient
+	return true
+}
+
+// tlsError reports whether the TLS handshake failed, e.g. on an untrusted
+// certificate, which sending the request again does not fix
+func tlsError(err error) bool {
+	var (
+		verificationErr  *tls.CertificateVerificationError
+		recordErr        tls.RecordHeaderError
+		unknownAuthority x509.UnknownAuthorityError
+		invalidErr       x509.CertificateInvalidError
+		hostnameErr      x509.HostnameError
+		opErr            *net.OpError
+	)
+	if errors.As(err, &verificationErr) || errors.As(err, &recordErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &invalidErr) || errors.As(err, &hostnameErr) {
+		return true
+	}
+	// Alerts sent by the server, e.g. when it rejects the client certificate
+	return errors.As(err, &opErr) && opErr.Op == "remote error"
+}
+
+// apiStatusError is returned when the API answers with a non-2xx status
+type apiStatusError struct {
+	StatusCode int
+
+	// RetryAfter is how long the API asked to wait before retrying
+	RetryAfter time.Duration
+}
+
+func (e *apiStatusError) Error() string {
+	return fmt.Sprintf("API request failed with status: %d", e.StatusCode)
+}
+
+// defaultAPIClient is shared by the API outputs without client certificates
+var defaultAPIClient = &http.Client{Timeout: 30 * time.Second}
+
+// apiClients caches the clients of the Secrets with TLS settings, so that
+// connections are reused across reconciles. A client is replaced when the
+// certificates of its Secret change.
+var apiClients = struct {
+	sync.Mutex
+	bySecret map[string]cachedAPIClient
+}{bySecret: make(map[string]cachedAPIClient)}
+
+type cachedAPIClient struct {
+	fingerprint string
+	client      *http.Client
+}
+
+// apiClient returns the client for the TLS settings of the Secret data
+func apiClient(config runbookv1alpha1.OutputConfig, namespace string, data map[string][]byte) (*http.Client, error) {
+	cert, key, ca := data["tls.crt"], data["tls.key"], data["ca.crt"]
+	if len(cert) == 0 && len(key) == 0 && len(ca) == 0 {
+		return defaultAPIClient, nil
+	}
+
+	sum := sha256.New()
+	for _, value := range [][]byte{cert, key, ca} {
+		sum.Write(value)
+		sum.Write([]byte{0})
+	}
+	fingerprint := hex.EncodeToString(sum.Sum(nil))
+	secret := namespace + "/" + config.SecretRef.Name
+
+	apiClients.Lock()
+	defer apiClients.Unlock()
+	if cached, ok := apiClients.bySecret[secret]; ok && cached.fingerprint == fingerprint {
+		return cached.client, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(cert) > 0 || len(key) > 0 {
+		certificate, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate in Secret %s: %w", config.SecretRef.Name, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	if len(ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("invalid ca.crt in Secret %s", config.SecretRef.Name)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	httpClient := &http.Client{Timeout: 30 * time.Second, Transport: transport}
+	if cached, ok := apiClients.bySecret[secret]; ok {
+		cached.client.CloseIdleConnections()
+	}
+	apiClients.bySecret[secret] = cachedAPIClient{fingerprint: fingerprint, client: httpClient}
+	return httpClient, nil
+}
@@ -0,0 +1,255 @@
+package outputs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// apiRequest is a request received by the catalog stub
+type apiRequest struct {
+	Method        string
+	Path          string
+	Authorization string
+	Body          RunbookAPI
+}
+
+// fakeCatalog stands in for a runbook catalog API. The first failures
+// responses are answered with their status before the request is handled.
+type fakeCatalog struct {
+	mu       sync.Mutex
+	requests []apiRequest
+	runbooks map[string]RunbookAPI
+	failures []int
+}
+
+func (f *fakeCatalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer GinkgoRecover()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	request := apiRequest{Method: r.Method, Path: r.URL.Path, Authorization: r.Header.Get("Authorization")}
+	if r.Method == http.MethodPut {
+		Expect(json.NewDecoder(r.Body).Decode(&request.Body)).To(Succeed())
+	}
+	f.requests = append(f.requests, request)
+
+	if len(f.failures) > 0 {
+		status := f.failures[0]
+		f.failures = f.failures[1:]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		w.WriteHeader(status)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		f.runbooks[r.URL.Path] = request.Body
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		if _, ok := f.runbooks[r.URL.Path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.runbooks, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// selfSignedCertificate returns a PEM encoded certificate and key for the loopback address
+func selfSignedCertificate(commonName string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+var _ = Describe("APIOutput", func() {
+	ctx := context.Background()
+
+	var (
+		catalog *fakeCatalog
+		runbook *runbookv1alpha1.Runbook
+	)
+
+	BeforeEach(func() {
+		catalog = &fakeCatalog{runbooks: make(map[string]RunbookAPI)}
+		runbook = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-errors", Namespace: "payments", Generation: 4},
+			Spec:       runbookv1alpha1.RunbookSpec{AlertName: "APIErrors", Severity: "critical"},
+		}
+	})
+
+	newOutput := func(baseURL string, data map[string][]byte) *APIOutput {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "catalog-credentials", Namespace: "payments"},
+			Data:       data,
+		}
+		output, err := DefaultRegistry.New(runbookv1alpha1.OutputConfig{
+			Format:      "api",
+			Destination: baseURL,
+			SecretRef:   &corev1.LocalObjectReference{Name: "catalog-credentials"},
+		}, Options{APIReader: fake.NewClientBuilder().WithObjects(secret).Build()})
+		Expect(err).NotTo(HaveOccurred())
+
+		api := output.(*APIOutput)
+		api.Retry = &wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3, Cap: 10 * time.Millisecond}
+		return api
+	}
+
+	It("should upsert the runbook with a bearer token and delete it on cleanup", func() {
+		server := httptest.NewServer(catalog)
+		DeferCleanup(server.Close)
+		output := newOutput(server.URL, map[string][]byte{"token": []byte("catalog-token")})
+
+		location, err := output.Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal(server.URL + "/runbooks/payments.api-errors"))
+		_, err = output.Generate(ctx, runbook, "# API errors, revised")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(catalog.runbooks).To(HaveLen(1))
+		stored := catalog.runbooks["/runbooks/payments.api-errors"]
+		Expect(stored.ID).To(Equal("payments.api-errors"))
+		Expect(stored.Content).To(Equal("# API errors, revised"))
+		Expect(stored.Metadata).To(HaveKeyWithValue("generation", BeNumerically("==", 4)))
+
+		Expect(output.Cleanup(ctx, runbook, location)).To(Succeed())
+		Expect(catalog.runbooks).To(BeEmpty())
+		Expect(output.Cleanup(ctx, runbook, location)).To(Succeed())
+
+		for _, request := range catalog.requests {
+			Expect(request.Authorization).To(Equal("Bearer catalog-token"))
+		}
+
+		By("Keeping runbooks apart whose namespace and name join to the same string")
+		first := &runbookv1alpha1.Runbook{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "a-b"}}
+		second := &runbookv1alpha1.Runbook{ObjectMeta: metav1.ObjectMeta{Name: "b-c", Namespace: "a"}}
+		output = &APIOutput{BaseURL: server.URL}
+		firstLocation, err := output.Generate(ctx, first, "# First")
+		Expect(err).NotTo(HaveOccurred())
+		secondLocation, err := output.Generate(ctx, second, "# Second")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(firstLocation).NotTo(Equal(secondLocation))
+		Expect(catalog.runbooks).To(HaveLen(2))
+	})
+
+	It("should retry on server errors and rate limits", func() {
+		server := httptest.NewServer(catalog)
+		DeferCleanup(server.Close)
+		output := newOutput(server.URL, map[string][]byte{"username": []byte("runbooks"), "password": []byte("secret")})
+		output.Retry.Cap = time.Millisecond
+
+		catalog.failures = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+		_, err := output.Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(catalog.requests).To(HaveLen(3))
+		Expect(catalog.requests[2].Authorization).To(HavePrefix("Basic "))
+
+		By("Giving up once the attempts are exhausted")
+		catalog.requests = nil
+		catalog.failures = []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}
+		_, err = output.Generate(ctx, runbook, "# API errors")
+		Expect(err).To(MatchError("API request failed with status: 502"))
+		Expect(catalog.requests).To(HaveLen(3))
+
+		By("Not retrying client errors")
+		catalog.requests = nil
+		catalog.failures = []int{http.StatusBadRequest}
+		_, err = output.Generate(ctx, runbook, "# API errors")
+		Expect(err).To(MatchError("API request failed with status: 400"))
+		Expect(catalog.requests).To(HaveLen(1))
+	})
+
+	It("should authenticate with a client certificate", func() {
+		serverCert, serverKey := selfSignedCertificate("catalog")
+		clientCert, clientKey := selfSignedCertificate("runbook-operator")
+
+		certificate, err := tls.X509KeyPair(serverCert, serverKey)
+		Expect(err).NotTo(HaveOccurred())
+		clientCAs := x509.NewCertPool()
+		Expect(clientCAs.AppendCertsFromPEM(clientCert)).To(BeTrue())
+
+		var connections atomic.Int32
+		server := httptest.NewUnstartedServer(catalog)
+		server.TLS = &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		}
+		server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				connections.Add(1)
+			}
+		}
+		server.StartTLS()
+		DeferCleanup(server.Close)
+
+		output := newOutput(server.URL, map[string][]byte{
+			"tls.crt": clientCert,
+			"tls.key": clientKey,
+			"ca.crt":  serverCert,
+		})
+		_, err = output.Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(catalog.runbooks).To(HaveKey("/runbooks/payments.api-errors"))
+
+		By("Failing without the client certificate, without retrying")
+		connections.Store(0)
+		output = newOutput(server.URL, map[string][]byte{"ca.crt": serverCert})
+		_, err = output.Generate(ctx, runbook, "# API errors")
+		Expect(err).To(HaveOccurred())
+		Expect(connections.Load()).To(BeEquivalentTo(1))
+
+		By("Failing on an untrusted server certificate, without retrying")
+		connections.Store(0)
+		otherCA, _ := selfSignedCertificate("other")
+		output = newOutput(server.URL, map[string][]byte{"tls.crt": clientCert, "tls.key": clientKey, "ca.crt": otherCA})
+		_, err = output.Generate(ctx, runbook, "# API errors")
+		var unknownAuthority x509.UnknownAuthorityError
+		Expect(errors.As(err, &unknownAuthority)).To(BeTrue())
+		Expect(connections.Load()).To(BeEquivalentTo(1))
+	})
+})
@@ -0,0 +1,258 @@
+package outputs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/generator"
+)
+
+func init() {
+	Register("configmap", func(config runbookv1alpha1.OutputConfig, opts Options) (Output, error) {
+		if opts.Client == nil {
+			return nil, errors.New("configmap output requires a client")
+		}
+		content := firstValue(config.Parameters["content"], generator.FormatMarkdown)
+		if content != generator.FormatMarkdown && content != generator.FormatHTML {
+			return nil, fmt.Errorf("configmap output cannot store %q content, use markdown or html", content)
+		}
+		if content == generator.FormatHTML && opts.Generator == nil {
+			return nil, errors.New("configmap output with html content requires a generator")
+		}
+		return &ConfigMapOutput{
+			Namespace:         config.Destination,
+			AllowedNamespaces: opts.ConfigMapNamespaces,
+			Content:           content,
+			Template:          config.Template,
+			Variables:         config.Variables,
+			Client:            opts.Client,
+			Reader:            opts.secretReader(),
+			Generator:         opts.Generator,
+		}, nil
+	})
+}
+
+// Labels and annotations of the ConfigMaps written by configmap outputs
+const (
+	ConfigMapRunbookLabel          = "runbook.runbook.io/runbook"
+	ConfigMapRunbookNamespaceLabel = "runbook.runbook.io/runbook-namespace"
+	ConfigMapPartAnnotation        = "runbook.runbook.io/part"
+)
+
+// maxConfigMapData is how much content a single ConfigMap holds. It stays
+// below the 1MiB object limit to leave room for the metadata.
+const maxConfigMapData = 1<<20 - 64<<10
+
+// CheckConfigMapNamespace reports whether a configmap output of a runbook in
+// runbookNamespace may write to namespace. Other namespaces than the one of
+// the runbook must be allowed by the operator, so that runbook authors cannot
+// use its permissions to write ConfigMaps anywhere in the cluster.
+func CheckConfigMapNamespace(runbookNamespace, namespace string, allowed []string) error {
+	if namespace == "" || namespace == runbookNamespace || slices.Contains(allowed, namespace) {
+		return nil
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("configmap outputs can only write to the namespace of the runbook, not to %s", namespace)
+	}
+	return fmt.Errorf("configmap outputs can only write to the namespace of the runbook or to %s, not to %s",
+		strings.Join(allowed, ", "), namespace)
+}
+
+// ConfigMapOutput stores rendered runbooks in ConfigMaps so that they live
+// inside the cluster. Content larger than a ConfigMap can hold is split
+// across several ConfigMaps, annotated with their part number.
+//
+// ConfigMaps in the runbook namespace are owned by the Runbook and garbage
+// collected with it. Owner references cannot cross namespaces, so in other
+// namespaces they are only removed by Cleanup.
+type ConfigMapOutput struct {
+	// Namespace the ConfigMaps are written to, the runbook namespace when empty
+	Namespace string
+
+	// AllowedNamespaces are the namespaces besides the runbook namespace
+	// that the ConfigMaps may be written to
+	AllowedNamespaces []string
+
+	// Content is the format stored, markdown or html
+	Content string
+
+	// Template and Variables render html content
+	Template  string
+	Variables map[string]string
+
+	Client client.Client
+
+	// Reader looks up existing ConfigMaps without caching every ConfigMap
+	// of the cluster
+	Reader client.Reader
+
+	Generator *generator.RunbookGenerator
+}
+
+func (c *ConfigMapOutput) Generate(ctx context.Context, runbook *runbookv1alpha1.Runbook, content string) (string, error) {
+	rendered, err := renderContent(ctx, c.Generator, runbook, runbookv1alpha1.OutputConfig{
+		Format:    "configmap",
+		Template:  c.Template,
+		Variables: c.Variables,
+	}, c.Content, content)
+	if err != nil {
+		return "", err
+	}
+
+	namespace := firstValue(c.Namespace, runbook.Namespace)
+	if err := CheckConfigMapNamespace(runbook.Namespace, namespace, c.AllowedNamespaces); err != nil {
+		return "", err
+	}
+	key := outputFilename(runbook, contentExtensions[c.Content])
+	parts := splitContent(string(rendered), maxConfigMapData)
+
+	refs := make([]string, 0, len(parts))
+	keep := make(map[string]bool, len(parts))
+	for i, part := range parts {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configMapName(runbook, namespace, i),
+				Namespace: namespace,
+				Labels: map[string]string{
+					ConfigMapRunbookLabel:          runbook.Name,
+					ConfigMapRunbookNamespaceLabel: runbook.Namespace,
+				},
+				Annotations: map[string]string{
+					ConfigMapPartAnnotation: fmt.Sprintf("%d/%d", i+1, len(parts)),
+				},
+			},
+			Data: map[string]string{key: part},
+		}
+		if namespace == runbook.Namespace {
+			if err := controllerutil.SetControllerReference(runbook, configMap, c.Client.Scheme()); err != nil {
+				return "", err
+			}
+		}
+		if err := c.apply(ctx, configMap); err != nil {
+			return "", err
+		}
+
+		refs = append(refs, namespace+"/"+configMap.Name)
+		keep[configMap.Name] = true
+	}
+
+	// Content that shrank leaves parts behind
+	if err := c.deleteParts(ctx, runbook, namespace, keep); err != nil {
+		return "", err
+	}
+	return strings.Join(refs, ","), nil
+}
+
+// Cleanup removes every ConfigMap of the runbook in the namespace of location
+func (c *ConfigMapOutput) Cleanup(ctx context.Context, runbook *runbookv1alpha1.Runbook, location string) error {
+	namespace, _, ok := strings.Cut(location, "/")
+	if !ok || namespace == "" {
+		return fmt.Errorf("invalid configmap location %q", location)
+	}
+	if err := CheckConfigMapNamespace(runbook.Namespace, namespace, c.AllowedNamespaces); err != nil {
+		return err
+	}
+	return c.deleteParts(ctx, runbook, namespace, nil)
+}
+
+func (c *ConfigMapOutput) Describe() string {
+	if c.Namespace == "" {
+		return fmt.Sprintf("%s ConfigMap in the runbook namespace", c.Content)
+	}
+	return fmt.Sprintf("%s ConfigMap in namespace %s", c.Content, c.Namespace)
+}
+
+// apply creates the ConfigMap or replaces the one written before
+func (c *ConfigMapOutput) apply(ctx context.Context, configMap *corev1.ConfigMap) error {
+	var existing corev1.ConfigMap
+	err := c.Reader.Get(ctx, client.ObjectKeyFromObject(configMap), &existing)
+	if apierrors.IsNotFound(err) {
+		return c.Client.Create(ctx, configMap)
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.Labels[ConfigMapRunbookLabel] != configMap.Labels[ConfigMapRunbookLabel] ||
+		existing.Labels[ConfigMapRunbookNamespaceLabel] != configMap.Labels[ConfigMapRunbookNamespaceLabel] {
+		return fmt.Errorf("ConfigMap %s/%s already exists and is not managed by this runbook", configMap.Namespace, configMap.Name)
+	}
+	configMap.ResourceVersion = existing.ResourceVersion
+	return c.Client.Update(ctx, configMap)
+}
+
+// deleteParts removes the ConfigMaps of the runbook in namespace that are not kept
+func (c *ConfigMapOutput) deleteParts(ctx context.Context, runbook *runbookv1alpha1.Runbook, namespace string, keep map[string]bool) error {
+	var configMaps corev1.ConfigMapList
+	if err := c.Reader.List(ctx, &configMaps, client.InNamespace(namespace), client.MatchingLabels{
+		ConfigMapRunbookLabel:          runbook.Name,
+		ConfigMapRunbookNamespaceLabel: runbook.Namespace,
+	}); err != nil {
+		return err
+	}
+
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		if keep[configMap.Name] {
+			continue
+		}
+		if err := c.Client.Delete(ctx, configMap); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configMapName names a part of the runbook. The runbook namespace is part of
+// the name in other namespaces, and long names are shortened with a hash.
+func configMapName(runbook *runbookv1alpha1.Runbook, namespace string, part int) string {
+	name := "runbook-" + runbook.Name
+	if namespace != runbook.Namespace {
+		name = "runbook-" + runbookID(runbook)
+	}
+
+	suffix := ""
+	if part > 0 {
+		suffix = "-" + strconv.Itoa(part)
+	}
+	if maxLength := validation.DNS1123SubdomainMaxLength - len(suffix); len(name) > maxLength {
+		sum := sha256.Sum256([]byte(name))
+		hash := hex.EncodeToString(sum[:])[:10]
+		name = strings.TrimRight(name[:maxLength-len(hash)-1], ".-") + "-" + hash
+	}
+	return name + suffix
+}
+
+// splitContent cuts content into parts of at most size bytes without
+// splitting UTF-8 characters
+func splitContent(content string, size int) []string {
+	if len(content) <= size {
+		return []string{content}
+	}
+
+	var parts []string
+	for len(content) > size {
+		end := size
+		for end > 0 && !utf8.RuneStart(content[end]) {
+			end--
+		}
+		parts = append(parts, content[:end])
+		content = content[end:]
+	}
+	return append(parts, content)
+}
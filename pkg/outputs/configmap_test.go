@@ -0,0 +1,122 @@
+package outputs
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+var _ = Describe("ConfigMapOutput", func() {
+	ctx := context.Background()
+
+	var (
+		k8sClient client.Client
+		runbook   *runbookv1alpha1.Runbook
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(runbookv1alpha1.AddToScheme(scheme)).To(Succeed())
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		runbook = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-errors", Namespace: "payments", UID: "runbook-uid"},
+			Spec:       runbookv1alpha1.RunbookSpec{AlertName: "APIErrors"},
+		}
+	})
+
+	newOutput := func(namespace string) Output {
+		output, err := DefaultRegistry.New(runbookv1alpha1.OutputConfig{
+			Format:      "configmap",
+			Destination: namespace,
+		}, Options{Client: k8sClient, ConfigMapNamespaces: []string{"runbooks"}})
+		Expect(err).NotTo(HaveOccurred())
+		return output
+	}
+
+	listConfigMaps := func(namespace string) []corev1.ConfigMap {
+		var configMaps corev1.ConfigMapList
+		Expect(k8sClient.List(ctx, &configMaps, client.InNamespace(namespace))).To(Succeed())
+		return configMaps.Items
+	}
+
+	It("should store the runbook in a ConfigMap owned by the runbook", func() {
+		location, err := newOutput("").Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal("payments/runbook-api-errors"))
+
+		var configMap corev1.ConfigMap
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "runbook-api-errors", Namespace: "payments"}, &configMap)).To(Succeed())
+		Expect(configMap.Data).To(Equal(map[string]string{"payments.api-errors.md": "# API errors"}))
+		Expect(configMap.Labels).To(HaveKeyWithValue(ConfigMapRunbookLabel, "api-errors"))
+		Expect(configMap.Annotations).To(HaveKeyWithValue(ConfigMapPartAnnotation, "1/1"))
+		Expect(metav1.IsControlledBy(&configMap, runbook)).To(BeTrue())
+
+		By("Updating the ConfigMap when the runbook changes")
+		_, err = newOutput("").Generate(ctx, runbook, "# API errors, revised")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(&configMap), &configMap)).To(Succeed())
+		Expect(configMap.Data).To(HaveKeyWithValue("payments.api-errors.md", "# API errors, revised"))
+	})
+
+	It("should split large runbooks across ConfigMaps and remove the parts left behind", func() {
+		content := strings.Repeat("é", maxConfigMapData/2) + "tail"
+
+		location, err := newOutput("runbooks").Generate(ctx, runbook, content)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal("runbooks/runbook-payments.api-errors,runbooks/runbook-payments.api-errors-1"))
+
+		configMaps := listConfigMaps("runbooks")
+		Expect(configMaps).To(HaveLen(2))
+		var joined strings.Builder
+		for _, configMap := range configMaps {
+			Expect(len(configMap.Data["payments.api-errors.md"])).To(BeNumerically("<=", maxConfigMapData))
+			Expect(configMap.OwnerReferences).To(BeEmpty())
+			joined.WriteString(configMap.Data["payments.api-errors.md"])
+		}
+		Expect(joined.String()).To(Equal(content))
+
+		By("Removing the second part once the content fits a single ConfigMap")
+		location, err = newOutput("runbooks").Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal("runbooks/runbook-payments.api-errors"))
+		Expect(listConfigMaps("runbooks")).To(HaveLen(1))
+
+		By("Removing every part on cleanup")
+		Expect(newOutput("runbooks").Cleanup(ctx, runbook, location)).To(Succeed())
+		Expect(listConfigMaps("runbooks")).To(BeEmpty())
+	})
+
+	It("should only write to the namespaces the operator allows", func() {
+		_, err := newOutput("kube-system").Generate(ctx, runbook, "# API errors")
+		Expect(err).To(MatchError("configmap outputs can only write to the namespace of the runbook or to runbooks, not to kube-system"))
+		Expect(listConfigMaps("kube-system")).To(BeEmpty())
+
+		Expect(newOutput("kube-system").Cleanup(ctx, runbook, "kube-system/runbook-payments.api-errors")).
+			To(MatchError(ContainSubstring("not to kube-system")))
+
+		By("Allowing the namespace of the runbook")
+		_, err = newOutput("payments").Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should not overwrite ConfigMaps it does not manage", func() {
+		Expect(k8sClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "runbook-api-errors", Namespace: "payments"},
+		})).To(Succeed())
+
+		_, err := newOutput("").Generate(ctx, runbook, "# API errors")
+		Expect(err).To(MatchError(ContainSubstring("not managed by this runbook")))
+	})
+})
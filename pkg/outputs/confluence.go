@@ -0,0 +1,321 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+func init() {
+	Register("confluence", func(config runbookv1alpha1.OutputConfig, opts Options) (Output, error) {
+		if _, err := url.ParseRequestURI(config.Destination); err != nil {
+			return nil, fmt.Errorf("confluence output destination must be the base URL of the wiki: %w", err)
+		}
+		if config.Parameters["space"] == "" {
+			return nil, errors.New("confluence output requires the space parameter")
+		}
+		return &ConfluenceOutput{
+			Config:  config,
+			Secrets: opts.secretReader(),
+		}, nil
+	})
+}
+
+// defaultConfluenceTitle keeps page titles unique within a space
+const defaultConfluenceTitle = "{alertName} ({namespace}/{name})"
+
+// ConfluenceOutput publishes runbooks as pages of a Confluence space. The
+// Destination is the base URL of the wiki, e.g. https://example.atlassian.net/wiki.
+// Pages are labeled with an ID derived from the runbook namespace and name,
+// so that they are found again when the status is lost or the title changes.
+//
+// The Secret keys are token, with username for basic authentication or
+// alone as a bearer token. The parameters are space, parentID, the page the
+// runbooks are created under, and title, which may contain {alertName},
+// {namespace}, {name}, {team} and {severity} placeholders.
+type ConfluenceOutput struct {
+	Config  runbookv1alpha1.OutputConfig
+	Secrets client.Reader
+
+	// HTTPClient sends the API requests, a client with a 30 second timeout
+	// is used when nil
+	HTTPClient *http.Client
+
+	// versions are the page versions published by Generate, by location
+	versions map[string]int
+}
+
+// confluencePage is the content resource of the Confluence REST API
+type confluencePage struct {
+	ID        string               `json:"id,omitempty"`
+	Type      string               `json:"type"`
+	Title     string               `json:"title"`
+	Space     *confluenceSpace     `json:"space,omitempty"`
+	Ancestors []confluenceAncestor `json:"ancestors,omitempty"`
+	Version   *confluenceVersion   `json:"version,omitempty"`
+	Body      *confluenceBody      `json:"body,omitempty"`
+	Metadata  *confluenceMetadata  `json:"metadata,omitempty"`
+}
+
+type confluenceSpace struct {
+	Key string `json:"key"`
+}
+
+type confluenceAncestor struct {
+	ID string `json:"id"`
+}
+
+type confluenceVersion struct {
+	Number  int    `json:"number"`
+	Message string `json:"message,omitempty"`
+}
+
+type confluenceBody struct {
+	Storage confluenceStorage `json:"storage"`
+}
+
+type confluenceStorage struct {
+	Value          string `json:"value"`
+	Representation string `json:"representation"`
+}
+
+type confluenceMetadata struct {
+	Labels []confluenceLabel `json:"labels"`
+}
+
+type confluenceLabel struct {
+	Prefix string `json:"prefix"`
+	Name   string `json:"name"`
+}
+
+func (c *ConfluenceOutput) Generate(ctx context.Context, runbook *runbookv1alpha1.Runbook, content string) (string, error) {
+	body, err := ConfluenceStorageFormat(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert runbook to storage format: %w", err)
+	}
+	auth, err := c.authorization(ctx, runbook.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	label := confluencePageLabel(runbook)
+	existing, err := c.findPage(ctx, auth, label)
+	if err != nil {
+		return "", err
+	}
+
+	page := confluencePage{
+		Type:  "page",
+		Title: confluenceTitle(firstValue(c.Config.Parameters["title"], defaultConfluenceTitle), runbook),
+		Space: &confluenceSpace{Key: c.Config.Parameters["space"]},
+		Body: &confluenceBody{Storage: confluenceStorage{
+			Value:          body,
+			Representation: "storage",
+		}},
+	}
+	if parent := c.Config.Parameters["parentID"]; parent != "" {
+		page.Ancestors = []confluenceAncestor{{ID: parent}}
+	}
+
+	var published confluencePage
+	if existing == nil {
+		page.Metadata = &confluenceMetadata{Labels: []confluenceLabel{{Prefix: "global", Name: label}}}
+		err = c.do(ctx, auth, http.MethodPost, c.apiURL("content"), page, &published)
+	} else {
+		page.ID = existing.ID
+		page.Version = &confluenceVersion{
+			Number:  existing.Version.Number + 1,
+			Message: fmt.Sprintf("Runbook %s/%s generation %d", runbook.Namespace, runbook.Name, runbook.Generation),
+		}
+		err = c.do(ctx, auth, http.MethodPut, c.apiURL("content", existing.ID), page, &published)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to publish page %q: %w", page.Title, err)
+	}
+
+	location := c.pageLocation(published.ID)
+	if published.Version != nil {
+		if c.versions == nil {
+			c.versions = make(map[string]int)
+		}
+		c.versions[location] = published.Version.Number
+	}
+	return location, nil
+}
+
+// Revision returns the version of the page Generate published at location
+func (c *ConfluenceOutput) Revision(location string) string {
+	if version, ok := c.versions[location]; ok {
+		return strconv.Itoa(version)
+	}
+	return ""
+}
+
+// Cleanup deletes the page, a page that is already gone is not an error
+func (c *ConfluenceOutput) Cleanup(ctx context.Context, runbook *runbookv1alpha1.Runbook, location string) error {
+	id, err := confluencePageID(location)
+	if err != nil {
+		return err
+	}
+	auth, err := c.authorization(ctx, runbook.Namespace)
+	if err != nil {
+		return err
+	}
+
+	err = c.do(ctx, auth, http.MethodDelete, c.apiURL("content", id), nil, nil)
+	var apiErr *confluenceError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+func (c *ConfluenceOutput) Describe() string {
+	return fmt.Sprintf("Confluence page in space %s at %s", c.Config.Parameters["space"], c.Config.Destination)
+}
+
+// findPage looks up the page of the runbook by its label, or returns nil
+func (c *ConfluenceOutput) findPage(ctx context.Context, auth, label string) (*confluencePage, error) {
+	query := url.Values{
+		"cql":    {fmt.Sprintf("type = page and space = %s and label = %s", cqlQuote(c.Config.Parameters["space"]), cqlQuote(label))},
+		"expand": {"version"},
+	}
+	var result struct {
+		Results []confluencePage `json:"results"`
+	}
+	if err := c.do(ctx, auth, http.MethodGet, c.apiURL("content", "search")+"?"+query.Encode(), nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to look up page labeled %s: %w", label, err)
+	}
+	if len(result.Results) == 0 {
+		return nil, nil
+	}
+
+	page := result.Results[0]
+	if page.Version == nil {
+		return nil, fmt.Errorf("page %s has no version", page.ID)
+	}
+	return &page, nil
+}
+
+// authorization builds the Authorization header from the output Secret
+func (c *ConfluenceOutput) authorization(ctx context.Context, namespace string) (string, error) {
+	data, err := readSecret(ctx, c.Secrets, c.Config, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	token := firstValue(string(data["token"]), string(data["password"]))
+	if token == "" {
+		return "", errors.New("confluence output requires a token in its Secret")
+	}
+	if username := string(data["username"]); username != "" {
+		request := &http.Request{Header: http.Header{}}
+		request.SetBasicAuth(username, token)
+		return request.Header.Get("Authorization"), nil
+	}
+	return "Bearer " + token, nil
+}
+
+func (c *ConfluenceOutput) do(ctx context.Context, auth, method, url string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", auth)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &confluenceError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *ConfluenceOutput) apiURL(segments ...string) string {
+	return strings.TrimRight(c.Config.Destination, "/") + "/rest/api/" + strings.Join(segments, "/")
+}
+
+// pageLocation is the address of the page in the wiki, which also carries its ID
+func (c *ConfluenceOutput) pageLocation(id string) string {
+	return fmt.Sprintf("%s/pages/viewpage.action?pageId=%s", strings.TrimRight(c.Config.Destination, "/"), url.QueryEscape(id))
+}
+
+func confluencePageID(location string) (string, error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid confluence location %q: %w", location, err)
+	}
+	id := parsed.Query().Get("pageId")
+	if id == "" {
+		return "", fmt.Errorf("invalid confluence location %q", location)
+	}
+	return id, nil
+}
+
+// confluencePageLabel is the stable ID of the runbook page. Labels cannot
+// contain slashes, so the namespace and name are hashed.
+func confluencePageLabel(runbook *runbookv1alpha1.Runbook) string {
+	sum := sha256.Sum256([]byte(runbook.Namespace + "/" + runbook.Name))
+	return "runbook-" + hex.EncodeToString(sum[:])[:16]
+}
+
+func confluenceTitle(layout string, runbook *runbookv1alpha1.Runbook) string {
+	return expandLayout(strings.ReplaceAll(layout, "{alertName}", runbook.Spec.AlertName), runbook)
+}
+
+// cqlQuote quotes a value of a CQL query
+func cqlQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// confluenceError is returned when the API answers with a non-2xx status
+type confluenceError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *confluenceError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("confluence API request failed with status: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("confluence API request failed with status %d: %s", e.StatusCode, e.Message)
+}
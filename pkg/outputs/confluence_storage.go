@@ -0,0 +1,66 @@
+package outputs
+
+import (
+	"bytes"
+	"html"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// confluenceMarkdown converts markdown to the XHTML based Confluence storage
+// format. Raw HTML is dropped since it is rarely valid XHTML, and code
+// blocks become code macros.
+var confluenceMarkdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(
+		goldmarkhtml.WithXHTML(),
+		renderer.WithNodeRenderers(util.Prioritized(codeMacroRenderer{}, 100)),
+	),
+)
+
+// ConfluenceStorageFormat converts markdown content to the Confluence storage format
+func ConfluenceStorageFormat(markdown string) (string, error) {
+	var buf bytes.Buffer
+	if err := confluenceMarkdown.Convert([]byte(markdown), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// codeMacroRenderer renders code blocks as Confluence code macros
+type codeMacroRenderer struct{}
+
+func (codeMacroRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, renderCodeMacro)
+	reg.Register(ast.KindCodeBlock, renderCodeMacro)
+}
+
+func renderCodeMacro(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString(`<ac:structured-macro ac:name="code">`)
+	if fenced, ok := node.(*ast.FencedCodeBlock); ok {
+		if language := fenced.Language(source); len(language) > 0 {
+			_, _ = w.WriteString(`<ac:parameter ac:name="language">`)
+			_, _ = w.WriteString(html.EscapeString(string(language)))
+			_, _ = w.WriteString(`</ac:parameter>`)
+		}
+	}
+
+	// A CDATA section cannot contain its own terminator, so it is split
+	_, _ = w.WriteString(`<ac:plain-text-body><![CDATA[`)
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		_, _ = w.Write(bytes.ReplaceAll(line.Value(source), []byte("]]>"), []byte("]]]]><![CDATA[>")))
+	}
+	_, _ = w.WriteString("]]></ac:plain-text-body></ac:structured-macro>\n")
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,196 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// fakeConfluence stands in for the content API of Confluence
+type fakeConfluence struct {
+	mu     sync.Mutex
+	pages  map[string]confluencePage
+	labels map[string]string
+	nextID int
+	auth   []string
+}
+
+var cqlLabel = regexp.MustCompile(`label = "([^"]+)"`)
+
+func (f *fakeConfluence) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer GinkgoRecover()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+	path := strings.TrimPrefix(r.URL.Path, "/wiki/rest/api/content")
+	switch {
+	case r.Method == http.MethodGet && path == "/search":
+		results := []confluencePage{}
+		if match := cqlLabel.FindStringSubmatch(r.URL.Query().Get("cql")); match != nil {
+			if id, ok := f.labels[match[1]]; ok {
+				results = append(results, f.pages[id])
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"results": results})
+	case r.Method == http.MethodPost && path == "":
+		var page confluencePage
+		Expect(json.NewDecoder(r.Body).Decode(&page)).To(Succeed())
+		f.nextID++
+		page.ID = strconv.Itoa(f.nextID)
+		page.Version = &confluenceVersion{Number: 1}
+		for _, label := range page.Metadata.Labels {
+			f.labels[label.Name] = page.ID
+		}
+		f.pages[page.ID] = page
+		writeJSON(w, http.StatusOK, page)
+	case r.Method == http.MethodPut:
+		id := strings.TrimPrefix(path, "/")
+		var page confluencePage
+		Expect(json.NewDecoder(r.Body).Decode(&page)).To(Succeed())
+		existing, ok := f.pages[id]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]any{"message": "no page " + id})
+			return
+		}
+		if page.Version == nil || page.Version.Number != existing.Version.Number+1 {
+			writeJSON(w, http.StatusConflict, map[string]any{"message": "version must be incremented"})
+			return
+		}
+		page.Metadata = existing.Metadata
+		f.pages[id] = page
+		writeJSON(w, http.StatusOK, page)
+	case r.Method == http.MethodDelete:
+		id := strings.TrimPrefix(path, "/")
+		if _, ok := f.pages[id]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]any{"message": "no page " + id})
+			return
+		}
+		delete(f.pages, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+var _ = Describe("ConfluenceOutput", func() {
+	ctx := context.Background()
+
+	var (
+		wiki    *fakeConfluence
+		baseURL string
+		opts    Options
+		runbook *runbookv1alpha1.Runbook
+	)
+
+	BeforeEach(func() {
+		wiki = &fakeConfluence{pages: make(map[string]confluencePage), labels: make(map[string]string)}
+		server := httptest.NewServer(wiki)
+		DeferCleanup(server.Close)
+		baseURL = server.URL + "/wiki"
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "wiki-token", Namespace: "payments"},
+			Data:       map[string][]byte{"token": []byte("wiki-secret")},
+		}
+		opts = Options{APIReader: fake.NewClientBuilder().WithObjects(secret).Build()}
+
+		runbook = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-errors", Namespace: "payments", Generation: 1},
+			Spec:       runbookv1alpha1.RunbookSpec{AlertName: "APIErrors"},
+		}
+	})
+
+	newOutput := func() Output {
+		output, err := DefaultRegistry.New(runbookv1alpha1.OutputConfig{
+			Format:      "confluence",
+			Destination: baseURL,
+			SecretRef:   &corev1.LocalObjectReference{Name: "wiki-token"},
+			Parameters:  map[string]string{"space": "OPS", "parentID": "42"},
+		}, opts)
+		Expect(err).NotTo(HaveOccurred())
+		return output
+	}
+
+	It("should create the page under the parent and update it afterwards", func() {
+		output := newOutput()
+		location, err := output.Generate(ctx, runbook, "# APIErrors\n\n```bash\nkubectl get pods\n```\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal(baseURL + "/pages/viewpage.action?pageId=1"))
+		Expect(output.(Versioned).Revision(location)).To(Equal("1"))
+
+		page := wiki.pages["1"]
+		Expect(page.Title).To(Equal("APIErrors (payments/api-errors)"))
+		Expect(page.Space.Key).To(Equal("OPS"))
+		Expect(page.Ancestors).To(Equal([]confluenceAncestor{{ID: "42"}}))
+		Expect(page.Body.Storage.Representation).To(Equal("storage"))
+		Expect(page.Body.Storage.Value).To(ContainSubstring(`<ac:parameter ac:name="language">bash</ac:parameter>`))
+		Expect(wiki.auth).To(HaveEach("Bearer wiki-secret"))
+
+		By("Finding the page again by its label")
+		runbook.Generation = 2
+		output = newOutput()
+		location, err = output.Generate(ctx, runbook, "# APIErrors\n\nUpdated")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal(baseURL + "/pages/viewpage.action?pageId=1"))
+		Expect(output.(Versioned).Revision(location)).To(Equal("2"))
+		Expect(wiki.pages).To(HaveLen(1))
+		Expect(wiki.pages["1"].Version.Message).To(Equal("Runbook payments/api-errors generation 2"))
+		Expect(wiki.pages["1"].Body.Storage.Value).To(ContainSubstring("<p>Updated</p>"))
+	})
+
+	It("should delete the page on cleanup", func() {
+		output := newOutput()
+		location, err := output.Generate(ctx, runbook, "# APIErrors")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(output.Cleanup(ctx, runbook, location)).To(Succeed())
+		Expect(wiki.pages).To(BeEmpty())
+
+		By("Treating a page that is already gone as deleted")
+		Expect(output.Cleanup(ctx, runbook, location)).To(Succeed())
+	})
+
+	It("should use basic authentication with a username", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "wiki-token", Namespace: "payments"},
+			Data:       map[string][]byte{"username": []byte("bot@example.com"), "token": []byte("wiki-secret")},
+		}
+		opts.APIReader = fake.NewClientBuilder().WithObjects(secret).Build()
+
+		_, err := newOutput().Generate(ctx, runbook, "# APIErrors")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wiki.auth).To(HaveEach(HavePrefix("Basic ")))
+	})
+})
+
+var _ = Describe("ConfluenceStorageFormat", func() {
+	It("should convert markdown to XHTML with code macros", func() {
+		storage, err := ConfluenceStorageFormat("## Steps\n\n1. **Check**  \n   done\n\n```sh\necho ']]>'\n```\n\n<script>alert(1)</script>\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(storage).To(ContainSubstring("<h2>Steps</h2>"))
+		Expect(storage).To(ContainSubstring("<strong>Check</strong><br />"))
+		Expect(storage).To(ContainSubstring(`<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">sh</ac:parameter>` +
+			"<ac:plain-text-body><![CDATA[echo ']]]]><![CDATA[>'\n]]></ac:plain-text-body></ac:structured-macro>"))
+		Expect(storage).NotTo(ContainSubstring("<script>"))
+	})
+})
@@ -0,0 +1,523 @@
+package outputs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"golang.org/x/crypto/ssh"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+func init() {
+	Register("git", func(config runbookv1alpha1.OutputConfig, opts Options) (Output, error) {
+		return &GitOutput{Config: config, Secrets: opts.secretReader(), Publisher: DefaultGitPublisher}, nil
+	})
+}
+
+// Defaults of the git output parameters
+const (
+	defaultGitBranch      = "main"
+	defaultGitAuthorName  = "runbook-operator"
+	defaultGitAuthorEmail = "runbook-operator@noreply.runbook.io"
+)
+
+// maxGitPushAttempts bounds how often a batch is rebased on a remote branch
+// that moved while it was being committed
+const maxGitPushAttempts = 3
+
+// gitPushTimeout bounds the pushes started by the batch timer
+const gitPushTimeout = 2 * time.Minute
+
+// GitOutput commits rendered runbooks to a git repository. The repository
+// URL and credentials come from the Secret of the output configuration, the
+// Destination is the directory the runbooks are written to and may contain
+// {namespace}, {name}, {team} and {severity} placeholders.
+//
+// The Secret keys are url, username, password or token for HTTPS, and
+// sshPrivateKey and knownHosts for SSH. The url may also be set as a
+// parameter, along with branch, authorName and authorEmail.
+type GitOutput struct {
+	Config  runbookv1alpha1.OutputConfig
+	Secrets client.Reader
+
+	// Publisher batches the commits of many runbooks
+	Publisher *GitPublisher
+}
+
+func (g *GitOutput) Generate(ctx context.Context, runbook *runbookv1alpha1.Runbook, content string) (string, error) {
+	remote, err := g.remote(ctx, runbook.Namespace)
+	if err != nil {
+		return "", err
+	}
+	file := layoutPath(g.Config.Destination, runbook, outputFilename(runbook, "md"))
+	push := g.Publisher.Publish(remote, gitChange{
+		Path:    file,
+		Content: []byte(content),
+		Summary: fmt.Sprintf("Update runbook %s/%s (generation %d)", runbook.Namespace, runbook.Name, runbook.Generation),
+	})
+	// The location is only reported once the runbook is in the repository,
+	// a failed push is retried by the next reconcile
+	if err := push.Wait(ctx); err != nil {
+		return "", err
+	}
+	return gitLocation(remote, file), nil
+}
+
+// Cleanup removes the runbook from the repository. Unlike generated
+// runbooks, removals are pushed right away so that a failure keeps the
+// runbook finalizer in place.
+func (g *GitOutput) Cleanup(ctx context.Context, runbook *runbookv1alpha1.Runbook, location string) error {
+	branch, file, err := parseGitLocation(location)
+	if err != nil {
+		return err
+	}
+	if !ownsFile(runbook, file, "md") {
+		return nil
+	}
+	remote, err := g.remote(ctx, runbook.Namespace)
+	if err != nil {
+		return err
+	}
+	remote.Branch = branch
+
+	push := g.Publisher.Publish(remote, gitChange{
+		Path:    file,
+		Summary: fmt.Sprintf("Remove runbook %s/%s", runbook.Namespace, runbook.Name),
+	})
+	if err := g.Publisher.FlushRemote(ctx, remote); err != nil {
+		return err
+	}
+	return push.Wait(ctx)
+}
+
+func (g *GitOutput) Describe() string {
+	return fmt.Sprintf("git repository directory %s", g.Config.Destination)
+}
+
+// remote resolves the repository, branch, credentials and author of the output
+func (g *GitOutput) remote(ctx context.Context, namespace string) (gitRemote, error) {
+	data, err := readSecret(ctx, g.Secrets, g.Config, namespace)
+	if err != nil {
+		return gitRemote{}, err
+	}
+
+	remote := gitRemote{
+		Credentials: namespace,
+		URL:         firstValue(string(data["url"]), g.Config.Parameters["url"]),
+		Branch:      firstValue(g.Config.Parameters["branch"], defaultGitBranch),
+		AuthorName:  firstValue(g.Config.Parameters["authorName"], defaultGitAuthorName),
+		AuthorEmail: firstValue(g.Config.Parameters["authorEmail"], defaultGitAuthorEmail),
+	}
+	if remote.URL == "" {
+		return gitRemote{}, errors.New("git output requires a repository url in its Secret or parameters")
+	}
+	if g.Config.SecretRef != nil {
+		remote.Credentials += "/" + g.Config.SecretRef.Name
+	}
+
+	remote.Auth, err = gitAuth(data)
+	if err != nil {
+		return gitRemote{}, err
+	}
+	return remote, nil
+}
+
+// gitAuth builds the credentials of the repository from the Secret data
+func gitAuth(data map[string][]byte) (transport.AuthMethod, error) {
+	if key := data["sshPrivateKey"]; len(key) > 0 {
+		auth, err := gitssh.NewPublicKeys(firstValue(string(data["username"]), "git"), key, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid sshPrivateKey: %w", err)
+		}
+		knownHosts := data["knownHosts"]
+		if len(knownHosts) == 0 {
+			return nil, errors.New("git outputs using SSH require knownHosts in their Secret")
+		}
+		auth.HostKeyCallback, err = knownHostsCallback(knownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid knownHosts: %w", err)
+		}
+		return auth, nil
+	}
+
+	password := firstValue(string(data["password"]), string(data["token"]))
+	if password == "" {
+		return nil, nil
+	}
+	return &githttp.BasicAuth{Username: firstValue(string(data["username"]), "git"), Password: password}, nil
+}
+
+// knownHostsCallback verifies SSH host keys against known_hosts content.
+// The callback only reads the file when it is created.
+func knownHostsCallback(knownHosts []byte) (ssh.HostKeyCallback, error) {
+	file, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name()) //nolint:errcheck
+
+	_, err = file.Write(knownHosts)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gitssh.NewKnownHostsCallback(file.Name())
+}
+
+// gitLocation identifies a file on a branch, without the credentials of the URL
+func gitLocation(remote gitRemote, file string) string {
+	location := remote.URL
+	if parsed, err := url.Parse(remote.URL); err == nil && parsed.User != nil {
+		parsed.User = nil
+		location = parsed.String()
+	}
+	return fmt.Sprintf("%s#%s:%s", location, remote.Branch, file)
+}
+
+func parseGitLocation(location string) (string, string, error) {
+	index := strings.LastIndex(location, "#")
+	if index < 0 {
+		return "", "", fmt.Errorf("invalid git location %q", location)
+	}
+	branch, file, ok := strings.Cut(location[index+1:], ":")
+	if !ok || branch == "" || file == "" {
+		return "", "", fmt.Errorf("invalid git location %q", location)
+	}
+	return branch, file, nil
+}
+
+// gitRemote is a branch of a repository and how to push to it
+type gitRemote struct {
+	URL         string
+	Branch      string
+	Auth        transport.AuthMethod
+	AuthorName  string
+	AuthorEmail string
+
+	// Credentials identifies the namespace and Secret the Auth was read from
+	Credentials string
+}
+
+// key identifies the batch of the remote. Runbooks only share a commit when
+// they are pushed with the same Secret of the same namespace and the same
+// author, so that no namespace pushes with the credentials of another one.
+func (r gitRemote) key() string {
+	return strings.Join([]string{r.URL, r.Branch, r.Credentials, r.AuthorName, r.AuthorEmail}, "\x00")
+}
+
+// gitChange writes a file, or removes it when Content is nil
+type gitChange struct {
+	Path    string
+	Content []byte
+	Summary string
+}
+
+// GitPublisher collects the changes of git outputs per repository, branch and
+// credentials and pushes them in a single commit, so that regenerating many runbooks does
+// not flood the repository with commits
+type GitPublisher struct {
+	// Interval is how long changes are collected before they are pushed.
+	// With a zero interval changes are only pushed by Flush and FlushRemote.
+	Interval time.Duration
+
+	mu      sync.Mutex
+	batches map[string]*gitBatch
+}
+
+// DefaultGitPublisher batches the changes of the git outputs created through
+// the registry. Outputs wait for the push of their batch, so the interval is
+// kept short.
+var DefaultGitPublisher = NewGitPublisher(2 * time.Second)
+
+// NewGitPublisher creates a publisher that pushes the collected changes every interval
+func NewGitPublisher(interval time.Duration) *GitPublisher {
+	return &GitPublisher{
+		Interval: interval,
+		batches:  make(map[string]*gitBatch),
+	}
+}
+
+type gitBatch struct {
+	remote  gitRemote
+	changes map[string]gitChange
+	timer   *time.Timer
+
+	// push is the push the collected changes will be part of
+	push *gitPush
+
+	// pushing serializes the pushes to the branch
+	pushing sync.Mutex
+}
+
+// gitPush is the result of pushing a batch
+type gitPush struct {
+	done chan struct{}
+	err  error
+}
+
+func newGitPush() *gitPush {
+	return &gitPush{done: make(chan struct{})}
+}
+
+// Wait blocks until the changes are pushed and returns the error of the push
+func (p *gitPush) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return p.err
+	}
+}
+
+// Publish queues the change for the next push to the branch and returns that push
+func (p *GitPublisher) Publish(remote gitRemote, change gitChange) *gitPush {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	batch, ok := p.batches[remote.key()]
+	if !ok {
+		batch = &gitBatch{changes: make(map[string]gitChange), push: newGitPush()}
+		p.batches[remote.key()] = batch
+	}
+	// The Secret of the batch may have been rotated since it was created
+	batch.remote = remote
+	batch.changes[change.Path] = change
+	p.schedule(batch)
+	return batch.push
+}
+
+// schedule starts the timer of the batch, p.mu must be held
+func (p *GitPublisher) schedule(batch *gitBatch) {
+	if p.Interval <= 0 || batch.timer != nil {
+		return
+	}
+	key := batch.remote.key()
+	batch.timer = time.AfterFunc(p.Interval, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), gitPushTimeout)
+		defer cancel()
+		_ = p.flush(ctx, key)
+	})
+}
+
+// Flush pushes the changes collected for every branch
+func (p *GitPublisher) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	keys := make([]string, 0, len(p.batches))
+	for key := range p.batches {
+		keys = append(keys, key)
+	}
+	p.mu.Unlock()
+
+	var errs []error
+	for _, key := range keys {
+		errs = append(errs, p.flush(ctx, key))
+	}
+	return errors.Join(errs...)
+}
+
+// FlushRemote pushes the changes collected for the branch of the remote
+func (p *GitPublisher) FlushRemote(ctx context.Context, remote gitRemote) error {
+	return p.flush(ctx, remote.key())
+}
+
+// Start pushes the pending changes when ctx is done, so that a stopping
+// operator does not lose them
+func (p *GitPublisher) Start(ctx context.Context) error {
+	<-ctx.Done()
+	flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return p.Flush(flushCtx)
+}
+
+func (p *GitPublisher) flush(ctx context.Context, key string) error {
+	p.mu.Lock()
+	batch, ok := p.batches[key]
+	p.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	batch.pushing.Lock()
+	defer batch.pushing.Unlock()
+
+	p.mu.Lock()
+	if batch.timer != nil {
+		batch.timer.Stop()
+		batch.timer = nil
+	}
+	changes := batch.changes
+	batch.changes = make(map[string]gitChange)
+	push := batch.push
+	batch.push = newGitPush()
+	remote := batch.remote
+	p.mu.Unlock()
+
+	if len(changes) == 0 {
+		return nil
+	}
+	push.err = pushGitChanges(ctx, remote, changes)
+	close(push.done)
+	return push.err
+}
+
+// pushGitChanges commits the changes on top of the remote branch and pushes
+// them, starting over when the branch moved in the meantime
+func pushGitChanges(ctx context.Context, remote gitRemote, changes map[string]gitChange) error {
+	var err error
+	for range maxGitPushAttempts {
+		err = commitAndPush(ctx, remote, changes)
+		if err == nil || !isNonFastForward(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func isNonFastForward(err error) bool {
+	return errors.Is(err, git.ErrForceNeeded) || strings.Contains(err.Error(), "non-fast-forward")
+}
+
+func commitAndPush(ctx context.Context, remote gitRemote, changes map[string]gitChange) error {
+	repository, err := openGitRemote(ctx, remote)
+	if err != nil {
+		return err
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return err
+	}
+
+	files := make([]string, 0, len(changes))
+	for file := range changes {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	summaries := make([]string, 0, len(files))
+	for _, file := range files {
+		change := changes[file]
+		if err := applyGitChange(worktree, change); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		summaries = append(summaries, change.Summary)
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return err
+	}
+	if status.IsClean() {
+		return nil
+	}
+
+	signature := &object.Signature{Name: remote.AuthorName, Email: remote.AuthorEmail, When: time.Now()}
+	if _, err := worktree.Commit(gitCommitMessage(summaries), &git.CommitOptions{Author: signature}); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
+	branch := plumbing.NewBranchReferenceName(remote.Branch)
+	err = repository.PushContext(ctx, &git.PushOptions{
+		RemoteName: git.DefaultRemoteName,
+		Auth:       remote.Auth,
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(branch + ":" + branch)},
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to push to %s: %w", gitLocation(remote, ""), err)
+	}
+	return nil
+}
+
+// openGitRemote clones the tip of the branch into memory, or starts it when
+// the repository is still empty
+func openGitRemote(ctx context.Context, remote gitRemote) (*git.Repository, error) {
+	branch := plumbing.NewBranchReferenceName(remote.Branch)
+	repository, err := git.CloneContext(ctx, memory.NewStorage(), memfs.New(), &git.CloneOptions{
+		URL:           remote.URL,
+		Auth:          remote.Auth,
+		ReferenceName: branch,
+		SingleBranch:  true,
+		Depth:         1,
+	})
+	if !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to clone %s: %w", gitLocation(remote, ""), err)
+		}
+		return repository, nil
+	}
+
+	repository, err = git.InitWithOptions(memory.NewStorage(), memfs.New(), git.InitOptions{DefaultBranch: branch})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := repository.CreateRemote(&gitconfig.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{remote.URL},
+	}); err != nil {
+		return nil, err
+	}
+	return repository, nil
+}
+
+func applyGitChange(worktree *git.Worktree, change gitChange) error {
+	fs := worktree.Filesystem
+	if change.Content == nil {
+		if _, err := fs.Stat(change.Path); os.IsNotExist(err) {
+			return nil
+		}
+		_, err := worktree.Remove(change.Path)
+		return err
+	}
+
+	if err := fs.MkdirAll(path.Dir(change.Path), 0o755); err != nil {
+		return err
+	}
+	file, err := fs.Create(change.Path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, strings.NewReader(string(change.Content)))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	_, err = worktree.Add(change.Path)
+	return err
+}
+
+// gitCommitMessage references every runbook of the batch
+func gitCommitMessage(summaries []string) string {
+	if len(summaries) == 1 {
+		return summaries[0]
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "Update %d runbooks\n\n", len(summaries))
+	for _, summary := range summaries {
+		fmt.Fprintf(&message, "- %s\n", summary)
+	}
+	return message.String()
+}
@@ -0,0 +1,292 @@
+package outputs
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+var _ = Describe("GitOutput", func() {
+	ctx := context.Background()
+
+	var (
+		remoteDir string
+		publisher *GitPublisher
+		output    *GitOutput
+	)
+
+	BeforeEach(func() {
+		remoteDir = filepath.Join(GinkgoT().TempDir(), "runbooks.git")
+		_, err := git.PlainInit(remoteDir, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "runbooks-repo", Namespace: "payments"},
+			Data:       map[string][]byte{"url": []byte(remoteDir)},
+		}
+		publisher = NewGitPublisher(0)
+		output = &GitOutput{
+			Config: runbookv1alpha1.OutputConfig{
+				Format:      "git",
+				Destination: "runbooks/{namespace}/{team}",
+				SecretRef:   &corev1.LocalObjectReference{Name: "runbooks-repo"},
+				Parameters:  map[string]string{"branch": "docs"},
+			},
+			Secrets:   fake.NewClientBuilder().WithObjects(secret).Build(),
+			Publisher: publisher,
+		}
+	})
+
+	newRunbook := func(name, alertName string) *runbookv1alpha1.Runbook {
+		return &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "payments", Generation: 3},
+			Spec:       runbookv1alpha1.RunbookSpec{AlertName: alertName, Team: "platform"},
+		}
+	}
+
+	headCommit := func() *object.Commit {
+		repository, err := git.PlainOpen(remoteDir)
+		Expect(err).NotTo(HaveOccurred())
+		ref, err := repository.Reference(plumbing.NewBranchReferenceName("docs"), true)
+		Expect(err).NotTo(HaveOccurred())
+		commit, err := repository.CommitObject(ref.Hash())
+		Expect(err).NotTo(HaveOccurred())
+		return commit
+	}
+
+	// generate runs Generate in the background, since it waits for the push
+	generate := func(runbook *runbookv1alpha1.Runbook, content string) <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			_, err := output.Generate(ctx, runbook, content)
+			result <- err
+		}()
+		return result
+	}
+
+	// pending counts the changes waiting for a push
+	pending := func() int {
+		publisher.mu.Lock()
+		defer publisher.mu.Unlock()
+		count := 0
+		for _, batch := range publisher.batches {
+			count += len(batch.changes)
+		}
+		return count
+	}
+
+	// flush pushes the changes once count of them are waiting
+	flush := func(count int) error {
+		Eventually(pending).Should(Equal(count))
+		return publisher.Flush(ctx)
+	}
+
+	fileContent := func(commit *object.Commit, name string) string {
+		file, err := commit.File(name)
+		Expect(err).NotTo(HaveOccurred())
+		reader, err := file.Reader()
+		Expect(err).NotTo(HaveOccurred())
+		defer reader.Close() //nolint:errcheck
+		content, err := io.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+		return string(content)
+	}
+
+	It("should commit the runbooks of a batch together", func() {
+		errorsPushed := generate(newRunbook("api-errors", "APIErrors"), "# API errors")
+		latencyPushed := generate(newRunbook("api-latency", "APILatency"), "# API latency")
+		Consistently(errorsPushed).ShouldNot(Receive())
+
+		Expect(flush(2)).To(Succeed())
+		Eventually(errorsPushed).Should(Receive(BeNil()))
+		Eventually(latencyPushed).Should(Receive(BeNil()))
+
+		commit := headCommit()
+		Expect(commit.NumParents()).To(BeZero())
+		Expect(commit.Author.Name).To(Equal("runbook-operator"))
+		Expect(commit.Message).To(ContainSubstring("Update 2 runbooks"))
+		Expect(commit.Message).To(ContainSubstring("payments/api-errors (generation 3)"))
+		Expect(commit.Message).To(ContainSubstring("payments/api-latency (generation 3)"))
+		Expect(fileContent(commit, "runbooks/payments/platform/payments.api-errors.md")).To(Equal("# API errors"))
+		Expect(fileContent(commit, "runbooks/payments/platform/payments.api-latency.md")).To(Equal("# API latency"))
+
+		By("Committing only what changed")
+		pushed := generate(newRunbook("api-errors", "APIErrors"), "# API errors")
+		Expect(flush(1)).To(Succeed())
+		Eventually(pushed).Should(Receive(BeNil()))
+		Expect(headCommit().Hash).To(Equal(commit.Hash))
+
+		pushed = generate(newRunbook("api-errors", "APIErrors"), "# API errors, revised")
+		Expect(flush(1)).To(Succeed())
+		Eventually(pushed).Should(Receive(BeNil()))
+		updated := headCommit()
+		Expect(updated.ParentHashes).To(Equal([]plumbing.Hash{commit.Hash}))
+		Expect(updated.Message).To(Equal("Update runbook payments/api-errors (generation 3)"))
+	})
+
+	It("should report the location once the batch is pushed", func() {
+		publisher.Interval = 50 * time.Millisecond
+
+		location, err := output.Generate(ctx, newRunbook("api-errors", "APIErrors"), "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal(remoteDir + "#docs:runbooks/payments/platform/payments.api-errors.md"))
+		Expect(fileContent(headCommit(), "runbooks/payments/platform/payments.api-errors.md")).To(Equal("# API errors"))
+
+		By("Pushing on top of the history from a shallow clone")
+		_, err = output.Generate(ctx, newRunbook("api-latency", "APILatency"), "# API latency")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = output.Generate(ctx, newRunbook("api-errors", "APIErrors"), "# API errors, revised")
+		Expect(err).NotTo(HaveOccurred())
+
+		commit := headCommit()
+		Expect(fileContent(commit, "runbooks/payments/platform/payments.api-errors.md")).To(Equal("# API errors, revised"))
+		Expect(fileContent(commit, "runbooks/payments/platform/payments.api-latency.md")).To(Equal("# API latency"))
+		history, err := commit.Parents().Next()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.NumParents()).To(Equal(1))
+
+		By("Giving up waiting when the context is done")
+		publisher.Interval = time.Hour
+		waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = output.Generate(waitCtx, newRunbook("api-errors", "APIErrors"), "# API errors")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("should remove the runbook from the repository on cleanup", func() {
+		publisher.Interval = 10 * time.Millisecond
+		runbook := newRunbook("api-errors", "APIErrors")
+		location, err := output.Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Pushing the removal without waiting for the interval")
+		publisher.Interval = time.Hour
+		Expect(output.Cleanup(ctx, runbook, location)).To(Succeed())
+
+		commit := headCommit()
+		Expect(commit.Message).To(Equal("Remove runbook payments/api-errors"))
+		_, err = commit.File("runbooks/payments/platform/payments.api-errors.md")
+		Expect(err).To(MatchError(object.ErrFileNotFound))
+	})
+
+	It("should keep the files of runbooks that share an alertName apart", func() {
+		publisher.Interval = 10 * time.Millisecond
+		runbook := newRunbook("api-errors", "APIErrors")
+		generated := newRunbook("api-errors-generated", "APIErrors")
+		location, err := output.Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+		generatedLocation, err := output.Generate(ctx, generated, "# API errors, generated")
+		Expect(err).NotTo(HaveOccurred())
+
+		commit := headCommit()
+		Expect(fileContent(commit, "runbooks/payments/platform/payments.api-errors.md")).To(Equal("# API errors"))
+		Expect(fileContent(commit, "runbooks/payments/platform/payments.api-errors-generated.md")).To(Equal("# API errors, generated"))
+
+		By("Only removing the file of the runbook")
+		Expect(output.Cleanup(ctx, generated, location)).To(Succeed())
+		Expect(output.Cleanup(ctx, generated, generatedLocation)).To(Succeed())
+		commit = headCommit()
+		Expect(fileContent(commit, "runbooks/payments/platform/payments.api-errors.md")).To(Equal("# API errors"))
+		_, err = commit.File("runbooks/payments/platform/payments.api-errors-generated.md")
+		Expect(err).To(MatchError(object.ErrFileNotFound))
+	})
+
+	It("should only batch runbooks pushed with the same Secret", func() {
+		secrets := []client.Object{
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "runbooks-repo", Namespace: "payments"},
+				Data:       map[string][]byte{"url": []byte(remoteDir), "token": []byte("payments-token")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "runbooks-repo", Namespace: "checkout"},
+				Data:       map[string][]byte{"url": []byte(remoteDir), "token": []byte("checkout-token")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "docs-repo", Namespace: "payments"},
+				Data:       map[string][]byte{"url": []byte(remoteDir), "token": []byte("docs-token")},
+			},
+		}
+		output.Secrets = fake.NewClientBuilder().WithObjects(secrets...).Build()
+		docsOutput := *output
+		docsOutput.Config.SecretRef = &corev1.LocalObjectReference{Name: "docs-repo"}
+
+		checkout := newRunbook("cart-errors", "CartErrors")
+		checkout.Namespace = "checkout"
+		paymentsPushed := generate(newRunbook("api-errors", "APIErrors"), "# API errors")
+		checkoutPushed := generate(checkout, "# Cart errors")
+		docsPushed := make(chan error, 1)
+		go func() {
+			_, err := docsOutput.Generate(ctx, newRunbook("api-latency", "APILatency"), "# API latency")
+			docsPushed <- err
+		}()
+		Eventually(pending).Should(Equal(3))
+
+		publisher.mu.Lock()
+		tokens := map[string]string{}
+		for _, batch := range publisher.batches {
+			tokens[batch.remote.Credentials] = batch.remote.Auth.(*githttp.BasicAuth).Password
+		}
+		publisher.mu.Unlock()
+		Expect(tokens).To(Equal(map[string]string{
+			"payments/runbooks-repo": "payments-token",
+			"checkout/runbooks-repo": "checkout-token",
+			"payments/docs-repo":     "docs-token",
+		}))
+
+		Expect(publisher.Flush(ctx)).To(Succeed())
+		Eventually(paymentsPushed).Should(Receive(BeNil()))
+		Eventually(checkoutPushed).Should(Receive(BeNil()))
+		Eventually(docsPushed).Should(Receive(BeNil()))
+
+		By("Pushing a commit for each Secret")
+		commit := headCommit()
+		var messages []string
+		Expect(object.NewCommitPreorderIter(commit, nil, nil).ForEach(func(c *object.Commit) error {
+			messages = append(messages, c.Message)
+			return nil
+		})).To(Succeed())
+		Expect(messages).To(ConsistOf(
+			"Update runbook payments/api-errors (generation 3)",
+			"Update runbook checkout/cart-errors (generation 3)",
+			"Update runbook payments/api-latency (generation 3)",
+		))
+		Expect(fileContent(commit, "runbooks/payments/platform/payments.api-errors.md")).To(Equal("# API errors"))
+		Expect(fileContent(commit, "runbooks/checkout/platform/checkout.cart-errors.md")).To(Equal("# Cart errors"))
+		Expect(fileContent(commit, "runbooks/payments/platform/payments.api-latency.md")).To(Equal("# API latency"))
+	})
+
+	It("should report the error of the push to every runbook of the batch", func() {
+		remoteDir = filepath.Join(GinkgoT().TempDir(), "missing.git")
+		output.Config.SecretRef = nil
+		output.Config.Parameters["url"] = remoteDir
+
+		errorsPushed := generate(newRunbook("api-errors", "APIErrors"), "# API errors")
+		latencyPushed := generate(newRunbook("api-latency", "APILatency"), "# API latency")
+		Expect(flush(2)).NotTo(Succeed())
+		Eventually(errorsPushed).Should(Receive(MatchError(ContainSubstring("repository not found"))))
+		Eventually(latencyPushed).Should(Receive(MatchError(ContainSubstring("repository not found"))))
+		Expect(pending()).To(BeZero())
+
+		By("Pushing the runbooks again once the repository exists")
+		_, err := git.PlainInit(remoteDir, true)
+		Expect(err).NotTo(HaveOccurred())
+		pushed := generate(newRunbook("api-errors", "APIErrors"), "# API errors")
+		Expect(flush(1)).To(Succeed())
+		Eventually(pushed).Should(Receive(BeNil()))
+		Expect(fileContent(headCommit(), "runbooks/payments/platform/payments.api-errors.md")).To(Equal("# API errors"))
+	})
+})
@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,18 +9,47 @@ import (
 	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
 )
 
+func init() {
+	Register("markdown", func(config runbookv1alpha1.OutputConfig, _ Options) (Output, error) {
+		return &MarkdownOutput{BasePath: config.Destination}, nil
+	})
+}
+
 type MarkdownOutput struct {
 	BasePath string
 }
 
-func (m *MarkdownOutput) Generate(runbook *runbookv1alpha1.Runbook, content string) error {
-	filename := fmt.Sprintf("%s.md", runbook.Spec.AlertName)
+func (m *MarkdownOutput) Generate(_ context.Context, runbook *runbookv1alpha1.Runbook, content string) (string, error) {
+	filename := outputFilename(runbook, "md")
 	fullPath := filepath.Join(m.BasePath, filename)
 
 	// Ensure directory exists
 	if err := os.MkdirAll(m.BasePath, 0755); err != nil {
-		return err
+		return "", err
 	}
 
-	return os.WriteFile(fullPath, []byte(content), 0644)
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return fullPath, nil
+}
+
+func (m *MarkdownOutput) Cleanup(_ context.Context, runbook *runbookv1alpha1.Runbook, location string) error {
+	return removeFile(runbook, location, "md")
+}
+
+func (m *MarkdownOutput) Describe() string {
+	return fmt.Sprintf("markdown file in %s", m.BasePath)
+}
+
+// removeFile deletes the generated file of the runbook, treating an already
+// missing file as removed
+func removeFile(runbook *runbookv1alpha1.Runbook, path string, extension string) error {
+	if !ownsFile(runbook, filepath.ToSlash(path), extension) {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,248 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/generator"
+)
+
+// ErrUnknownFormat is returned when no output is registered for a format
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// Output publishes a rendered runbook to a destination
+type Output interface {
+	// Generate publishes the runbook and returns the location it was published to
+	Generate(ctx context.Context, runbook *runbookv1alpha1.Runbook, content string) (string, error)
+
+	// Cleanup removes what Generate published at location
+	Cleanup(ctx context.Context, runbook *runbookv1alpha1.Runbook, location string) error
+
+	// Describe returns a short human readable description of the output
+	Describe() string
+}
+
+// Versioned is implemented by outputs that publish versioned documents, such
+// as wiki pages
+type Versioned interface {
+	// Revision returns the revision Generate published at location
+	Revision(location string) string
+}
+
+// Options carries the dependencies shared by all outputs
+type Options struct {
+	// Client gives outputs access to the cluster, e.g. to read Secrets
+	Client client.Client
+
+	// Generator renders runbooks with the loaded templates
+	Generator *generator.RunbookGenerator
+
+	// APIReader reads the Secrets of outputs without caching every Secret
+	// of the cluster, defaults to Client
+	APIReader client.Reader
+
+	// ConfigMapNamespaces are the namespaces configmap outputs may write to
+	// besides the namespace of the runbook
+	ConfigMapNamespaces []string
+
+	// S3AmbientCredentials lets s3 outputs without a Secret use the
+	// credentials of the operator, from the AWS environment variables or
+	// the instance role
+	S3AmbientCredentials bool
+}
+
+func (o Options) secretReader() client.Reader {
+	if o.APIReader != nil {
+		return o.APIReader
+	}
+	return o.Client
+}
+
+// readSecret returns the data of the Secret the output configuration
+// references in the runbook namespace, or nil without a reference
+func readSecret(ctx context.Context, reader client.Reader, config runbookv1alpha1.OutputConfig, namespace string) (map[string][]byte, error) {
+	if config.SecretRef == nil {
+		return nil, nil
+	}
+	if reader == nil {
+		return nil, fmt.Errorf("%s output cannot read Secret %s without a client", config.Format, config.SecretRef.Name)
+	}
+
+	var secret corev1.Secret
+	if err := reader.Get(ctx, types.NamespacedName{Name: config.SecretRef.Name, Namespace: namespace}, &secret); err != nil {
+		return nil, fmt.Errorf("failed to read Secret %s of %s output: %w", config.SecretRef.Name, config.Format, err)
+	}
+	return secret.Data, nil
+}
+
+// expandLayout fills the {namespace}, {name}, {team} and {severity}
+// placeholders of a layout
+func expandLayout(layout string, runbook *runbookv1alpha1.Runbook) string {
+	return strings.NewReplacer(
+		"{namespace}", runbook.Namespace,
+		"{name}", runbook.Name,
+		"{team}", firstValue(runbook.Spec.Team, "unknown"),
+		"{severity}", firstValue(runbook.Spec.Severity, "unknown"),
+	).Replace(layout)
+}
+
+// runbookID identifies a runbook in external systems. Namespaces cannot
+// contain dots, so runbooks of different namespaces never share an ID.
+func runbookID(runbook *runbookv1alpha1.Runbook) string {
+	return runbook.Namespace + "." + runbook.Name
+}
+
+// outputFilename names the file of a runbook after its ID rather than its
+// alertName, which runbooks of different namespaces and those generated from
+// PrometheusRules may share
+func outputFilename(runbook *runbookv1alpha1.Runbook, extension string) string {
+	return runbookID(runbook) + "." + extension
+}
+
+// ownsFile reports whether a generated file is named after the runbook.
+// Files written before they were named after runbooks may belong to another
+// runbook with the same alertName, so Cleanup leaves them in place.
+func ownsFile(runbook *runbookv1alpha1.Runbook, filename, extension string) bool {
+	return path.Base(filename) == outputFilename(runbook, extension)
+}
+
+// layoutPath expands a directory layout and appends the file name. Cleaning
+// the path as an absolute one keeps it within the destination.
+func layoutPath(layout string, runbook *runbookv1alpha1.Runbook, filename string) string {
+	return strings.TrimPrefix(path.Join("/", expandLayout(layout, runbook), filename), "/")
+}
+
+// contentTypes maps the formats outputs can store to their media types
+var contentTypes = map[string]string{
+	generator.FormatMarkdown: "text/markdown; charset=utf-8",
+	generator.FormatHTML:     "text/html; charset=utf-8",
+	"pdf":                    "application/pdf",
+}
+
+// contentExtensions maps the formats outputs can store to their file extensions
+var contentExtensions = map[string]string{
+	generator.FormatMarkdown: "md",
+	generator.FormatHTML:     "html",
+	"pdf":                    "pdf",
+}
+
+// renderContent renders the runbook in the format an output stores. Markdown
+// is the content rendered by the controller, html and pdf are rendered from
+// the runbook itself.
+func renderContent(ctx context.Context, gen *generator.RunbookGenerator, runbook *runbookv1alpha1.Runbook, config runbookv1alpha1.OutputConfig, format, markdown string) ([]byte, error) {
+	switch format {
+	case generator.FormatMarkdown:
+		return []byte(markdown), nil
+	case generator.FormatHTML:
+		if gen == nil {
+			return nil, fmt.Errorf("%s output with html content requires a generator", config.Format)
+		}
+		content, err := gen.GenerateHTML(ctx, runbook, runbookv1alpha1.OutputConfig{
+			Format:    generator.FormatHTML,
+			Template:  config.Template,
+			Variables: config.Variables,
+		})
+		return []byte(content), err
+	case "pdf":
+		var buf bytes.Buffer
+		if err := RenderPDF(runbook, &buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("%s output cannot store %q content", config.Format, format)
+	}
+}
+
+func firstValue(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Factory creates an Output for a single output configuration
+type Factory func(config runbookv1alpha1.OutputConfig, opts Options) (Output, error)
+
+// Registry maps output formats to the factories that create them
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// DefaultRegistry holds the built-in outputs and anything registered through Register
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]Factory),
+	}
+}
+
+// Register adds an output format to the default registry. It is meant to be
+// called from init functions and panics if the format is already registered.
+func Register(format string, factory Factory) {
+	DefaultRegistry.Register(format, factory)
+}
+
+// Register adds an output format to the registry and panics if the format is
+// already registered
+func (r *Registry) Register(format string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if factory == nil {
+		panic(fmt.Sprintf("outputs: factory for format %q is nil", format))
+	}
+	if _, exists := r.factories[format]; exists {
+		panic(fmt.Sprintf("outputs: format %q registered twice", format))
+	}
+	r.factories[format] = factory
+}
+
+// New creates the output for the given configuration
+func (r *Registry) New(config runbookv1alpha1.OutputConfig, opts Options) (Output, error) {
+	r.mu.RLock()
+	factory, exists := r.factories[config.Format]
+	r.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, config.Format)
+	}
+
+	return factory(config, opts)
+}
+
+// Has reports whether the format is registered
+func (r *Registry) Has(format string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, exists := r.factories[format]
+	return exists
+}
+
+// Formats returns the registered formats in alphabetical order
+func (r *Registry) Formats() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	formats := make([]string, 0, len(r.factories))
+	for format := range r.factories {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
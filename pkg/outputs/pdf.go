@@ -0,0 +1,198 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+func init() {
+	Register("pdf", func(config runbookv1alpha1.OutputConfig, _ Options) (Output, error) {
+		return &PDFOutput{BasePath: config.Destination}, nil
+	})
+}
+
+// PDFOutput renders runbooks to PDF files with a pure Go PDF writer
+type PDFOutput struct {
+	BasePath string
+}
+
+// severityColors maps severities to the RGB color of the severity badge
+var severityColors = map[string][3]int{
+	"critical": {255, 107, 107},
+	"warning":  {254, 202, 87},
+	"info":     {72, 202, 228},
+}
+
+// Generate renders the runbook layout to a PDF file. Like the HTML output it
+// is built from the runbook itself rather than the markdown content.
+func (p *PDFOutput) Generate(_ context.Context, runbook *runbookv1alpha1.Runbook, _ string) (string, error) {
+	filename := outputFilename(runbook, "pdf")
+	fullPath := filepath.Join(p.BasePath, filename)
+
+	if err := os.MkdirAll(p.BasePath, 0755); err != nil {
+		return "", err
+	}
+
+	file, err := os.Create(fullPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := RenderPDF(runbook, file); err != nil {
+		return "", err
+	}
+	return fullPath, nil
+}
+
+func (p *PDFOutput) Cleanup(_ context.Context, runbook *runbookv1alpha1.Runbook, location string) error {
+	return removeFile(runbook, location, "pdf")
+}
+
+func (p *PDFOutput) Describe() string {
+	return fmt.Sprintf("PDF file in %s", p.BasePath)
+}
+
+// RenderPDF writes the runbook as a PDF document to w
+func RenderPDF(runbook *runbookv1alpha1.Runbook, w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AliasNbPages("")
+
+	// The core fonts are cp1252 encoded, so text is translated from UTF-8
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Generated by RunbookOperator - page %d/{nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	writePDFTitle(pdf, tr, runbook)
+
+	content := runbook.Spec.Content
+	if content.Impact != "" {
+		writePDFHeading(pdf, tr, "Impact")
+		writePDFParagraph(pdf, tr, content.Impact)
+	}
+
+	if len(content.Investigation) > 0 {
+		writePDFHeading(pdf, tr, "Investigation Steps")
+		for i, step := range content.Investigation {
+			writePDFStep(pdf, tr, fmt.Sprintf("%d. %s", i+1, step.Description))
+			writePDFCode(pdf, tr, step.Command)
+			if step.Expected != "" {
+				writePDFParagraph(pdf, tr, "Expected: "+step.Expected)
+			}
+		}
+	}
+
+	if len(content.Remediation) > 0 {
+		writePDFHeading(pdf, tr, "Remediation Steps")
+		for i, step := range content.Remediation {
+			title := fmt.Sprintf("%d. %s", i+1, step.Description)
+			if step.Risk != "" {
+				title = fmt.Sprintf("%s (Risk: %s)", title, step.Risk)
+			}
+			writePDFStep(pdf, tr, title)
+			writePDFCode(pdf, tr, step.Command)
+		}
+	}
+
+	if content.Prevention != "" {
+		writePDFHeading(pdf, tr, "Prevention")
+		writePDFParagraph(pdf, tr, content.Prevention)
+	}
+
+	if len(content.References) > 0 {
+		writePDFHeading(pdf, tr, "References")
+		pdf.SetFont("Helvetica", "", 11)
+		for _, ref := range content.References {
+			label := ref.Title
+			if ref.Type != "" {
+				label = fmt.Sprintf("%s (%s)", ref.Title, ref.Type)
+			}
+			pdf.SetTextColor(0, 0, 0)
+			pdf.Write(6, "- ")
+			pdf.SetTextColor(30, 90, 200)
+			pdf.WriteLinkString(6, tr(label), ref.URL)
+			pdf.Ln(6)
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+func writePDFTitle(pdf *fpdf.Fpdf, tr func(string) string, runbook *runbookv1alpha1.Runbook) {
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.MultiCell(0, 10, tr(fmt.Sprintf("%s Runbook", runbook.Spec.AlertName)), "", "L", false)
+	pdf.Ln(2)
+
+	severity := runbook.Spec.Severity
+	color, ok := severityColors[severity]
+	if !ok {
+		color = [3]int{200, 200, 200}
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(color[0], color[1], color[2])
+	pdf.CellFormat(30, 7, tr(severity), "", 0, "C", true, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 7, tr(fmt.Sprintf("   Team: %s   Generated: %s",
+		runbook.Spec.Team, time.Now().Format("2006-01-02 15:04:05"))), "", 1, "L", false, 0, "")
+
+	pdf.Ln(2)
+	pdf.SetDrawColor(200, 200, 200)
+	x, y := pdf.GetXY()
+	pageWidth, _ := pdf.GetPageSize()
+	_, _, right, _ := pdf.GetMargins()
+	pdf.Line(x, y, pageWidth-right, y)
+	pdf.Ln(4)
+}
+
+func writePDFHeading(pdf *fpdf.Fpdf, tr func(string) string, heading string) {
+	pdf.Ln(3)
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(0, 8, tr(heading), "", 1, "L", false, 0, "")
+	pdf.Ln(1)
+}
+
+func writePDFStep(pdf *fpdf.Fpdf, tr func(string) string, title string) {
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.MultiCell(0, 6, tr(title), "", "L", false)
+}
+
+func writePDFParagraph(pdf *fpdf.Fpdf, tr func(string) string, text string) {
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.MultiCell(0, 6, tr(text), "", "L", false)
+	pdf.Ln(1)
+}
+
+// writePDFCode renders a command as a shaded monospace block
+func writePDFCode(pdf *fpdf.Fpdf, tr func(string) string, command string) {
+	if command == "" {
+		return
+	}
+
+	pdf.Ln(1)
+	pdf.SetFont("Courier", "", 9)
+	pdf.SetFillColor(233, 236, 239)
+	pdf.SetTextColor(33, 37, 41)
+	pdf.MultiCell(0, 5, tr(command), "", "L", true)
+	pdf.Ln(2)
+}
@@ -0,0 +1,111 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// pdfPageCount reads the page count of the page tree of a PDF document
+var pdfPageCount = regexp.MustCompile(`/Type /Pages\n/Kids \[[^\]]*\]\n/Count (\d+)`)
+
+var _ = Describe("RenderPDF", func() {
+	var runbook *runbookv1alpha1.Runbook
+
+	BeforeEach(func() {
+		runbook = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-errors", Namespace: "payments"},
+			Spec: runbookv1alpha1.RunbookSpec{
+				AlertName: "APIErrors",
+				Severity:  "critical",
+				Team:      "Zahlungsverkehr – Köln",
+				Content: runbookv1alpha1.RunbookContent{
+					Impact: "Checkout requests fail",
+					Investigation: []runbookv1alpha1.InvestigationStep{
+						{Description: "Check the pods", Command: "kubectl get pods", Expected: "All pods are running"},
+					},
+					Remediation: []runbookv1alpha1.RemediationStep{
+						{Description: "Restart the deployment", Command: "kubectl rollout restart deploy/api", Risk: "low"},
+					},
+					References: []runbookv1alpha1.Reference{
+						{Title: "Dashboard", URL: "https://grafana.example.com/d/api", Type: "dashboard"},
+					},
+				},
+			},
+		}
+	})
+
+	It("should write a single page document for a short runbook", func() {
+		var buf bytes.Buffer
+		Expect(RenderPDF(runbook, &buf)).To(Succeed())
+
+		Expect(buf.String()).To(HavePrefix("%PDF-1."))
+		Expect(buf.String()).To(HaveSuffix("%%EOF\n"))
+		match := pdfPageCount.FindStringSubmatch(buf.String())
+		Expect(match).To(HaveLen(2))
+		Expect(match[1]).To(Equal("1"))
+		Expect(buf.String()).To(ContainSubstring("(https://grafana.example.com/d/api)"))
+	})
+
+	It("should break long runbooks across pages", func() {
+		for i := range 60 {
+			runbook.Spec.Content.Investigation = append(runbook.Spec.Content.Investigation, runbookv1alpha1.InvestigationStep{
+				Description: fmt.Sprintf("Check replica %d", i),
+				Command:     fmt.Sprintf("kubectl logs api-%d", i),
+			})
+		}
+
+		var buf bytes.Buffer
+		Expect(RenderPDF(runbook, &buf)).To(Succeed())
+		match := pdfPageCount.FindStringSubmatch(buf.String())
+		Expect(match).To(HaveLen(2))
+		Expect(match[1]).NotTo(Equal("1"))
+	})
+
+	It("should write the file named after the runbook and remove it on cleanup", func() {
+		dir := GinkgoT().TempDir()
+		output, err := DefaultRegistry.New(runbookv1alpha1.OutputConfig{Format: "pdf", Destination: dir}, Options{})
+		Expect(err).NotTo(HaveOccurred())
+
+		location, err := output.Generate(context.Background(), runbook, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal(filepath.Join(dir, "payments.api-errors.pdf")))
+		Expect(os.ReadFile(location)).To(HavePrefix("%PDF-1."))
+
+		Expect(output.Cleanup(context.Background(), runbook, location)).To(Succeed())
+		Expect(location).NotTo(BeAnExistingFile())
+	})
+
+	It("should keep the files of runbooks that share an alertName apart", func() {
+		dir := GinkgoT().TempDir()
+		output, err := DefaultRegistry.New(runbookv1alpha1.OutputConfig{Format: "pdf", Destination: dir}, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		other := runbook.DeepCopy()
+		other.Namespace = "checkout"
+
+		location, err := output.Generate(context.Background(), runbook, "")
+		Expect(err).NotTo(HaveOccurred())
+		otherLocation, err := output.Generate(context.Background(), other, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(otherLocation).NotTo(Equal(location))
+
+		Expect(output.Cleanup(context.Background(), other, otherLocation)).To(Succeed())
+		Expect(otherLocation).NotTo(BeAnExistingFile())
+		Expect(location).To(BeAnExistingFile())
+
+		By("Leaving files that are not named after the runbook in place")
+		legacy := filepath.Join(dir, "APIErrors.pdf")
+		Expect(os.WriteFile(legacy, []byte("%PDF-1.3"), 0o644)).To(Succeed())
+		Expect(output.Cleanup(context.Background(), other, legacy)).To(Succeed())
+		Expect(legacy).To(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,205 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/generator"
+)
+
+func init() {
+	Register("s3", func(config runbookv1alpha1.OutputConfig, opts Options) (Output, error) {
+		content := firstValue(config.Parameters["content"], generator.FormatMarkdown)
+		if _, ok := contentTypes[content]; !ok {
+			return nil, fmt.Errorf("s3 output cannot store %q content, use markdown, html or pdf", content)
+		}
+		if _, err := parseS3Destination(config.Destination); err != nil {
+			return nil, err
+		}
+		if config.SecretRef == nil {
+			// The credentials of the operator reach every bucket of its role,
+			// and would be sent to any endpoint a runbook names
+			if !opts.S3AmbientCredentials {
+				return nil, errors.New("s3 output requires a secretRef with its credentials")
+			}
+			if config.Parameters["endpoint"] != "" {
+				return nil, errors.New("s3 output requires a secretRef with its credentials to use a custom endpoint")
+			}
+		}
+		return &S3Output{
+			Config:    config,
+			Content:   content,
+			Secrets:   opts.secretReader(),
+			Generator: opts.Generator,
+		}, nil
+	})
+}
+
+// s3RunbookMetadata is the user metadata of uploaded objects naming their
+// runbook, in the canonical form S3 returns it in
+const s3RunbookMetadata = "Runbook"
+
+// defaultS3Endpoint is used when the output does not override the endpoint
+const defaultS3Endpoint = "https://s3.amazonaws.com"
+
+// S3Output uploads rendered runbooks to a bucket of an S3 compatible object
+// storage. The Destination is the bucket followed by the key prefix, e.g.
+// runbooks/{team}, and may contain {namespace}, {name}, {team} and {severity}
+// placeholders.
+//
+// The Secret keys are accessKeyID, secretAccessKey and the optional
+// sessionToken. Only when the operator allows ambient credentials, outputs
+// to the default endpoint may omit the Secret and use the AWS environment
+// variables or the instance role of the operator. The parameters are content
+// (markdown, html or pdf), endpoint, region and pathStyle.
+type S3Output struct {
+	Config runbookv1alpha1.OutputConfig
+
+	// Content is the format uploaded, markdown, html or pdf
+	Content string
+
+	Secrets   client.Reader
+	Generator *generator.RunbookGenerator
+}
+
+func (s *S3Output) Generate(ctx context.Context, runbook *runbookv1alpha1.Runbook, content string) (string, error) {
+	body, err := renderContent(ctx, s.Generator, runbook, s.Config, s.Content, content)
+	if err != nil {
+		return "", err
+	}
+
+	storage, err := s.client(ctx, runbook.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	// The destination was validated when the output was created
+	destination, _ := parseS3Destination(s.Config.Destination)
+	key := layoutPath(destination.Prefix, runbook, outputFilename(runbook, contentExtensions[s.Content]))
+
+	_, err = storage.PutObject(ctx, destination.Bucket, key, bytes.NewReader(body), int64(len(body)), minio.PutObjectOptions{
+		ContentType: contentTypes[s.Content],
+		UserMetadata: map[string]string{
+			s3RunbookMetadata:    runbook.Namespace + "/" + runbook.Name,
+			"runbook-generation": strconv.FormatInt(runbook.Generation, 10),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s to bucket %s: %w", key, destination.Bucket, err)
+	}
+	return s3Location(destination.Bucket, key), nil
+}
+
+// Cleanup deletes the uploaded object, an already deleted object is not an
+// error. Objects uploaded for another runbook are left in place.
+func (s *S3Output) Cleanup(ctx context.Context, runbook *runbookv1alpha1.Runbook, location string) error {
+	bucket, key, err := parseS3Location(location)
+	if err != nil {
+		return err
+	}
+	storage, err := s.client(ctx, runbook.Namespace)
+	if err != nil {
+		return err
+	}
+
+	object, err := storage.StatObject(ctx, bucket, key, minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s from bucket %s: %w", key, bucket, err)
+	}
+	if object.UserMetadata[s3RunbookMetadata] != runbook.Namespace+"/"+runbook.Name {
+		return nil
+	}
+
+	if err := storage.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete %s from bucket %s: %w", key, bucket, err)
+	}
+	return nil
+}
+
+func (s *S3Output) Describe() string {
+	return fmt.Sprintf("%s object in s3://%s", s.Content, s.Config.Destination)
+}
+
+// client connects to the endpoint of the output with the credentials of its Secret
+func (s *S3Output) client(ctx context.Context, namespace string) (*minio.Client, error) {
+	data, err := readSecret(ctx, s.Secrets, s.Config, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := url.Parse(firstValue(s.Config.Parameters["endpoint"], defaultS3Endpoint))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid s3 endpoint %q, use http(s)://host[:port]", s.Config.Parameters["endpoint"])
+	}
+
+	lookup := minio.BucketLookupAuto
+	if pathStyle := s.Config.Parameters["pathStyle"]; pathStyle != "" {
+		enabled, err := strconv.ParseBool(pathStyle)
+		if err != nil {
+			return nil, fmt.Errorf("invalid s3 pathStyle %q: %w", pathStyle, err)
+		}
+		lookup = minio.BucketLookupDNS
+		if enabled {
+			lookup = minio.BucketLookupPath
+		}
+	}
+
+	return minio.New(endpoint.Host, &minio.Options{
+		Creds:        s3Credentials(data),
+		Secure:       endpoint.Scheme == "https",
+		Region:       s.Config.Parameters["region"],
+		BucketLookup: lookup,
+	})
+}
+
+// s3Credentials uses the keys of the Secret, or the AWS environment
+// variables and instance role without a Secret, which the factory only
+// admits when the operator allows ambient credentials
+func s3Credentials(data map[string][]byte) *credentials.Credentials {
+	if data == nil {
+		return credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.IAM{},
+		})
+	}
+	return credentials.NewStaticV4(string(data["accessKeyID"]), string(data["secretAccessKey"]), string(data["sessionToken"]))
+}
+
+// s3Destination is a bucket and the key prefix of the objects
+type s3Destination struct {
+	Bucket string
+	Prefix string
+}
+
+func parseS3Destination(destination string) (s3Destination, error) {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(destination, "s3://"), "/")
+	if bucket == "" {
+		return s3Destination{}, fmt.Errorf("s3 output destination %q does not name a bucket", destination)
+	}
+	return s3Destination{Bucket: bucket, Prefix: prefix}, nil
+}
+
+func s3Location(bucket, key string) string {
+	return fmt.Sprintf("s3://%s/%s", bucket, key)
+}
+
+func parseS3Location(location string) (string, string, error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+	if !ok || bucket == "" || key == "" || !strings.HasPrefix(location, "s3://") {
+		return "", "", fmt.Errorf("invalid s3 location %q", location)
+	}
+	return bucket, key, nil
+}
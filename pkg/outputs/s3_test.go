@@ -0,0 +1,218 @@
+package outputs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// s3Object is an object stored by fakeS3
+type s3Object struct {
+	ContentType   string
+	Authorization string
+	Runbook       string
+	Body          []byte
+}
+
+// fakeS3 stands in for an S3 compatible storage with path style addressing
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]s3Object
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body, err = decodeAWSChunked(body)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = s3Object{
+			ContentType:   r.Header.Get("Content-Type"),
+			Authorization: r.Header.Get("Authorization"),
+			Runbook:       r.Header.Get("X-Amz-Meta-Runbook"),
+			Body:          body,
+		}
+		w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodHead:
+		object, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.ContentType)
+		w.Header().Set("X-Amz-Meta-Runbook", object.Runbook)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// decodeAWSChunked strips the chunk signatures of a streaming upload
+func decodeAWSChunked(body []byte) ([]byte, error) {
+	var decoded []byte
+	reader := bufio.NewReader(bytes.NewReader(body))
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return decoded, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, chunk[:size]...)
+	}
+}
+
+var _ = Describe("S3Output", func() {
+	ctx := context.Background()
+
+	var (
+		storage *fakeS3
+		server  *httptest.Server
+		opts    Options
+		runbook *runbookv1alpha1.Runbook
+	)
+
+	BeforeEach(func() {
+		storage = &fakeS3{objects: make(map[string]s3Object)}
+		server = httptest.NewServer(storage)
+		DeferCleanup(server.Close)
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "runbooks-bucket", Namespace: "payments"},
+			Data: map[string][]byte{
+				"accessKeyID":     []byte("runbooks"),
+				"secretAccessKey": []byte("runbooks-secret"),
+			},
+		}
+		opts = Options{APIReader: fake.NewClientBuilder().WithObjects(secret).Build()}
+
+		runbook = &runbookv1alpha1.Runbook{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-errors", Namespace: "payments", Generation: 2},
+			Spec:       runbookv1alpha1.RunbookSpec{AlertName: "APIErrors", Team: "platform"},
+		}
+	})
+
+	newOutput := func(content string) Output {
+		output, err := DefaultRegistry.New(runbookv1alpha1.OutputConfig{
+			Format:      "s3",
+			Destination: "runbooks/site/{team}",
+			SecretRef:   &corev1.LocalObjectReference{Name: "runbooks-bucket"},
+			Parameters: map[string]string{
+				"content":   content,
+				"endpoint":  server.URL,
+				"region":    "us-east-1",
+				"pathStyle": "true",
+			},
+		}, opts)
+		Expect(err).NotTo(HaveOccurred())
+		return output
+	}
+
+	It("should upload the runbook with its content type and delete it on cleanup", func() {
+		output := newOutput("")
+		location, err := output.Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal("s3://runbooks/site/platform/payments.api-errors.md"))
+
+		object, ok := storage.objects["/runbooks/site/platform/payments.api-errors.md"]
+		Expect(ok).To(BeTrue())
+		Expect(object.Body).To(Equal([]byte("# API errors")))
+		Expect(object.ContentType).To(Equal("text/markdown; charset=utf-8"))
+		Expect(object.Authorization).To(ContainSubstring("Credential=runbooks/"))
+
+		Expect(output.Cleanup(ctx, runbook, location)).To(Succeed())
+		Expect(storage.objects).To(BeEmpty())
+
+		By("Treating an already deleted object as removed")
+		Expect(output.Cleanup(ctx, runbook, location)).To(Succeed())
+	})
+
+	It("should keep the objects of runbooks that share an alertName apart", func() {
+		output := newOutput("")
+		other := runbook.DeepCopy()
+		other.Name = "api-errors-generated"
+
+		location, err := output.Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+		otherLocation, err := output.Generate(ctx, other, "# API errors, generated")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(otherLocation).To(Equal("s3://runbooks/site/platform/payments.api-errors-generated.md"))
+		Expect(storage.objects).To(HaveLen(2))
+
+		By("Only deleting objects uploaded for the runbook")
+		Expect(output.Cleanup(ctx, other, location)).To(Succeed())
+		Expect(storage.objects).To(HaveKey("/runbooks/site/platform/payments.api-errors.md"))
+		Expect(output.Cleanup(ctx, other, otherLocation)).To(Succeed())
+		Expect(storage.objects).To(HaveLen(1))
+	})
+
+	It("should upload PDF documents", func() {
+		location, err := newOutput("pdf").Generate(ctx, runbook, "# API errors")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal("s3://runbooks/site/platform/payments.api-errors.pdf"))
+
+		object := storage.objects["/runbooks/site/platform/payments.api-errors.pdf"]
+		Expect(object.ContentType).To(Equal("application/pdf"))
+		Expect(string(object.Body)).To(HavePrefix("%PDF"))
+	})
+
+	It("should reject content it cannot store", func() {
+		_, err := DefaultRegistry.New(runbookv1alpha1.OutputConfig{
+			Format:      "s3",
+			Destination: "runbooks",
+			Parameters:  map[string]string{"content": "docx"},
+		}, opts)
+		Expect(err).To(MatchError(ContainSubstring(`cannot store "docx" content`)))
+	})
+
+	It("should only use the credentials of the operator when it allows them", func() {
+		config := runbookv1alpha1.OutputConfig{Format: "s3", Destination: "runbooks"}
+		_, err := DefaultRegistry.New(config, opts)
+		Expect(err).To(MatchError("s3 output requires a secretRef with its credentials"))
+
+		opts.S3AmbientCredentials = true
+		Expect(DefaultRegistry.New(config, opts)).Error().NotTo(HaveOccurred())
+
+		By("Never sending them to an endpoint the runbook names")
+		config.Parameters = map[string]string{"endpoint": server.URL}
+		_, err = DefaultRegistry.New(config, opts)
+		Expect(err).To(MatchError(ContainSubstring("requires a secretRef with its credentials to use a custom endpoint")))
+	})
+})
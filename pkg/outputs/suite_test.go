@@ -0,0 +1,20 @@
+package outputs
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// These tests use Ginkgo (BDD-style Go testing framework). Refer to
+// http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
+//
+// Outputs that publish to remote services are exercised against local
+// stand-ins, such as a bare git repository.
+
+func TestOutputs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Outputs Suite")
+}
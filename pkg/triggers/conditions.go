@@ -0,0 +1,266 @@
+package triggers
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operators supported in trigger conditions, longest first so that parsing
+// prefers ">=" over ">"
+var operators = []string{"!=", "=~", "!~", ">=", "<=", "=", ">", "<"}
+
+// Condition is a parsed trigger condition such as severity=critical or duration>5m
+type Condition struct {
+	Key      string
+	Operator string
+	Value    string
+}
+
+func (c Condition) String() string {
+	return c.Key + c.Operator + c.Value
+}
+
+// Values resolves the keys referenced by conditions
+type Values interface {
+	Lookup(key string) (string, bool)
+}
+
+// Labels resolves condition keys from a label set
+type Labels map[string]string
+
+// Lookup returns the value of the label
+func (l Labels) Lookup(key string) (string, bool) {
+	value, ok := l[key]
+	return value, ok
+}
+
+// Parse parses a condition of the form <key><operator><value>
+func Parse(expr string) (Condition, error) {
+	index := strings.IndexAny(expr, "=!<>")
+	if index <= 0 {
+		return Condition{}, fmt.Errorf("invalid condition %q: expected <key><operator><value>", expr)
+	}
+
+	for _, operator := range operators {
+		if !strings.HasPrefix(expr[index:], operator) {
+			continue
+		}
+
+		condition := Condition{
+			Key:      strings.TrimSpace(expr[:index]),
+			Operator: operator,
+			Value:    strings.TrimSpace(expr[index+len(operator):]),
+		}
+		if condition.Key == "" {
+			return Condition{}, fmt.Errorf("invalid condition %q: missing key", expr)
+		}
+		if operator == "=~" || operator == "!~" {
+			if _, err := regexp.Compile(condition.Value); err != nil {
+				return Condition{}, fmt.Errorf("invalid condition %q: %v", expr, err)
+			}
+		}
+		return condition, nil
+	}
+
+	return Condition{}, fmt.Errorf("invalid condition %q: unknown operator", expr)
+}
+
+// Validate parses every condition and returns the first error
+func Validate(conditions []string) error {
+	for _, expr := range conditions {
+		if _, err := Parse(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match reports whether every condition holds for the values. An empty list always matches.
+func Match(conditions []string, values Values) (bool, error) {
+	for _, expr := range conditions {
+		condition, err := Parse(expr)
+		if err != nil {
+			return false, err
+		}
+		matched, err := condition.Evaluate(values)
+		if err != nil {
+			return false, err
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Evaluate checks the condition against the values. Missing keys only
+// satisfy the negated operators.
+func (c Condition) Evaluate(values Values) (bool, error) {
+	actual, ok := values.Lookup(c.Key)
+
+	switch c.Operator {
+	case "=":
+		return ok && actual == c.Value, nil
+	case "!=":
+		return !ok || actual != c.Value, nil
+	case "=~", "!~":
+		re, err := regexp.Compile("^(?:" + c.Value + ")$")
+		if err != nil {
+			return false, fmt.Errorf("condition %s: %v", c, err)
+		}
+		if c.Operator == "=~" {
+			return ok && re.MatchString(actual), nil
+		}
+		return !ok || !re.MatchString(actual), nil
+	}
+
+	if !ok {
+		return false, nil
+	}
+	order, err := compare(actual, c.Value)
+	if err != nil {
+		return false, fmt.Errorf("condition %s: %v", c, err)
+	}
+
+	switch c.Operator {
+	case ">":
+		return order > 0, nil
+	case ">=":
+		return order >= 0, nil
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	default:
+		return false, fmt.Errorf("condition %s: unknown operator", c)
+	}
+}
+
+// compare orders two values as durations when both parse as one, and as numbers otherwise
+func compare(actual, expected string) (int, error) {
+	if a, err := time.ParseDuration(actual); err == nil {
+		if e, err := time.ParseDuration(expected); err == nil {
+			return cmp.Compare(a, e), nil
+		}
+	}
+
+	a, err := strconv.ParseFloat(actual, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is neither a number nor a duration", actual)
+	}
+	e, err := strconv.ParseFloat(expected, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is neither a number nor a duration", expected)
+	}
+	return cmp.Compare(a, e), nil
+}
+
+// Alert resolves condition keys from the labels of a firing alert. The
+// duration key resolves to how long the alert has been firing.
+type Alert struct {
+	Labels   map[string]string
+	StartsAt time.Time
+	Now      time.Time
+}
+
+// Lookup returns the alert label, or the firing duration for the duration key
+func (a Alert) Lookup(key string) (string, bool) {
+	if key == "duration" && !a.StartsAt.IsZero() {
+		return a.Now.Sub(a.StartsAt).String(), true
+	}
+	value, ok := a.Labels[key]
+	return value, ok
+}
+
+// JSON resolves condition keys as dotted paths into a decoded JSON document,
+// e.g. pipeline.status or jobs.0.name. Numbers and booleans resolve to their
+// JSON text.
+type JSON struct {
+	Document any
+}
+
+// Lookup returns the scalar value at the path of the key
+func (j JSON) Lookup(key string) (string, bool) {
+	value := j.Document
+	for _, part := range strings.Split(key, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			child, ok := node[part]
+			if !ok {
+				return "", false
+			}
+			value = child
+		case []any:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", false
+			}
+			value = node[index]
+		default:
+			return "", false
+		}
+	}
+	return scalarString(value)
+}
+
+// Flatten returns the scalar values of the document by their dotted path, in
+// path order and at most limit of them
+func (j JSON) Flatten(limit int) map[string]string {
+	values := map[string]string{}
+	flatten(values, "", j.Document, limit)
+	return values
+}
+
+func flatten(values map[string]string, path string, value any, limit int) {
+	if len(values) >= limit {
+		return
+	}
+
+	switch node := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			flatten(values, joinPath(path, key), node[key], limit)
+		}
+	case []any:
+		for i, item := range node {
+			flatten(values, joinPath(path, strconv.Itoa(i)), item, limit)
+		}
+	default:
+		if s, ok := scalarString(value); ok && path != "" {
+			values[path] = s
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func scalarString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		return v.String(), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
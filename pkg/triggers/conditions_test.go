@@ -0,0 +1,140 @@
+package triggers_test
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/guibes/runbook-operator/pkg/triggers"
+)
+
+// lookup resolves a key that must be present
+func lookup(values triggers.Values, key string) string {
+	value, ok := values.Lookup(key)
+	ExpectWithOffset(1, ok).To(BeTrue(), key)
+	return value
+}
+
+var _ = Describe("Parse", func() {
+	DescribeTable("should split the key, operator and value",
+		func(expr string, expected triggers.Condition) {
+			Expect(triggers.Parse(expr)).To(Equal(expected))
+		},
+		Entry("equals", "severity=critical", triggers.Condition{Key: "severity", Operator: "=", Value: "critical"}),
+		Entry("not equals", "env != dev", triggers.Condition{Key: "env", Operator: "!=", Value: "dev"}),
+		Entry("regex", "pod=~api-.*", triggers.Condition{Key: "pod", Operator: "=~", Value: "api-.*"}),
+		Entry("negated regex", "pod!~canary-.*", triggers.Condition{Key: "pod", Operator: "!~", Value: "canary-.*"}),
+		Entry("greater or equal before greater", "duration>=5m", triggers.Condition{Key: "duration", Operator: ">=", Value: "5m"}),
+		Entry("less or equal before less", "replicas<=2", triggers.Condition{Key: "replicas", Operator: "<=", Value: "2"}),
+		Entry("values containing operators", "query=a=b", triggers.Condition{Key: "query", Operator: "=", Value: "a=b"}),
+	)
+
+	DescribeTable("should reject invalid conditions",
+		func(expr string, message string) {
+			_, err := triggers.Parse(expr)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("without operator", "severity", "expected <key><operator><value>"),
+		Entry("without key", "=critical", "expected <key><operator><value>"),
+		Entry("with a blank key", "  =critical", "missing key"),
+		Entry("with an invalid regex", "pod=~api-(", "missing closing )"),
+	)
+
+	It("should validate every condition", func() {
+		Expect(triggers.Validate([]string{"severity=critical", "duration>5m"})).To(Succeed())
+		Expect(triggers.Validate([]string{"severity=critical", "duration"})).To(MatchError(ContainSubstring(`"duration"`)))
+	})
+})
+
+var _ = Describe("Match", func() {
+	labels := triggers.Labels{"severity": "critical", "pod": "api-7d9f", "replicas": "3", "latency": "250ms"}
+
+	DescribeTable("should evaluate conditions against the labels",
+		func(conditions []string, expected bool) {
+			Expect(triggers.Match(conditions, labels)).To(Equal(expected))
+		},
+		Entry("without conditions", nil, true),
+		Entry("equal label", []string{"severity=critical"}, true),
+		Entry("different label", []string{"severity=warning"}, false),
+		Entry("missing label", []string{"team=payments"}, false),
+		Entry("missing label negated", []string{"team!=payments"}, true),
+		Entry("anchored regex", []string{"pod=~api-.*"}, true),
+		Entry("unanchored regex does not match a substring", []string{"pod=~7d9"}, false),
+		Entry("negated regex", []string{"pod!~canary-.*"}, true),
+		Entry("missing label negated regex", []string{"team!~pay.*"}, true),
+		Entry("numbers", []string{"replicas>2", "replicas<=3"}, true),
+		Entry("numbers compared numerically", []string{"replicas<10"}, true),
+		Entry("durations", []string{"latency>100ms", "latency<1s"}, true),
+		Entry("missing label ordered", []string{"errors>1"}, false),
+		Entry("all conditions must hold", []string{"severity=critical", "replicas>5"}, false),
+	)
+
+	It("should fail on values that cannot be ordered", func() {
+		_, err := triggers.Match([]string{"severity>2"}, labels)
+		Expect(err).To(MatchError(`condition severity>2: "critical" is neither a number nor a duration`))
+
+		_, err = triggers.Match([]string{"severity"}, labels)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Alert", func() {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	It("should resolve duration to how long the alert has been firing", func() {
+		alert := triggers.Alert{Labels: map[string]string{"severity": "critical"}, StartsAt: now.Add(-6 * time.Minute), Now: now}
+		Expect(triggers.Match([]string{"severity=critical", "duration>5m"}, alert)).To(BeTrue())
+		Expect(triggers.Match([]string{"duration>10m"}, alert)).To(BeFalse())
+	})
+
+	It("should fall back to the duration label without a start time", func() {
+		alert := triggers.Alert{Labels: map[string]string{"duration": "1h"}, Now: now}
+		Expect(lookup(alert, "duration")).To(Equal("1h"))
+	})
+})
+
+var _ = Describe("JSON", func() {
+	var document triggers.JSON
+
+	BeforeEach(func() {
+		decoder := json.NewDecoder(strings.NewReader(`{
+			"pipeline": {"status": "failed", "attempt": 3, "retried": true},
+			"jobs": [{"name": "build"}, {"name": "deploy", "duration": 1.5}],
+			"owner": null
+		}`))
+		decoder.UseNumber()
+		var value any
+		Expect(decoder.Decode(&value)).To(Succeed())
+		document = triggers.JSON{Document: value}
+	})
+
+	It("should resolve dotted paths to scalar values", func() {
+		Expect(lookup(document, "pipeline.status")).To(Equal("failed"))
+		Expect(lookup(document, "pipeline.attempt")).To(Equal("3"))
+		Expect(lookup(document, "pipeline.retried")).To(Equal("true"))
+		Expect(lookup(document, "jobs.1.name")).To(Equal("deploy"))
+		Expect(triggers.Match([]string{"pipeline.status=failed", "pipeline.attempt>=3", "jobs.1.duration<2"}, document)).To(BeTrue())
+	})
+
+	It("should not resolve objects, nulls or missing paths", func() {
+		for _, key := range []string{"pipeline", "owner", "jobs.2.name", "jobs.name", "pipeline.status.code"} {
+			_, ok := document.Lookup(key)
+			Expect(ok).To(BeFalse(), key)
+		}
+	})
+
+	It("should flatten the scalar values in path order up to the limit", func() {
+		Expect(document.Flatten(10)).To(Equal(map[string]string{
+			"jobs.0.name":      "build",
+			"jobs.1.duration":  "1.5",
+			"jobs.1.name":      "deploy",
+			"pipeline.attempt": "3",
+			"pipeline.retried": "true",
+			"pipeline.status":  "failed",
+		}))
+		Expect(document.Flatten(2)).To(Equal(map[string]string{"jobs.0.name": "build", "jobs.1.duration": "1.5"}))
+	})
+})
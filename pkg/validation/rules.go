@@ -0,0 +1,184 @@
+package validation
+
+import (
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+	"github.com/guibes/runbook-operator/pkg/commands"
+	"github.com/guibes/runbook-operator/pkg/triggers"
+)
+
+var (
+	specPath    = field.NewPath("spec")
+	contentPath = specPath.Child("content")
+)
+
+// referenceTypes are the accepted values of Reference.Type
+var referenceTypes = map[string]bool{
+	"wiki":          true,
+	"dashboard":     true,
+	"documentation": true,
+	"runbook":       true,
+}
+
+// DefaultRules returns the rules applied to every runbook
+func DefaultRules() []Rule {
+	return []Rule{
+		RuleFunc("alert-name", validateAlertName),
+		RuleFunc("critical-impact", validateCriticalImpact),
+		RuleFunc("investigation-steps", validateInvestigationSteps),
+		RuleFunc("automated-commands", validateAutomatedCommands),
+		RuleFunc("reference-types", validateReferenceTypes),
+		RuleFunc("trigger-conditions", validateTriggerConditions),
+		RuleFunc("command-placeholders", validateCommandPlaceholders),
+		RuleFunc("investigation-expectations", validateInvestigationExpectations),
+	}
+}
+
+// RuleFunc adapts a function to the Rule interface
+func RuleFunc(name string, validate func(runbook *runbookv1alpha1.Runbook) []Finding) Rule {
+	return &funcRule{name: name, validate: validate}
+}
+
+type funcRule struct {
+	name     string
+	validate func(runbook *runbookv1alpha1.Runbook) []Finding
+}
+
+func (r *funcRule) Name() string {
+	return r.name
+}
+
+func (r *funcRule) Validate(runbook *runbookv1alpha1.Runbook) []Finding {
+	return r.validate(runbook)
+}
+
+func validateAlertName(runbook *runbookv1alpha1.Runbook) []Finding {
+	if runbook.Spec.AlertName == "" {
+		return []Finding{errorAt(specPath.Child("alertName"), "alert name is required")}
+	}
+	return nil
+}
+
+// validateCriticalImpact requires critical runbooks to describe their impact
+func validateCriticalImpact(runbook *runbookv1alpha1.Runbook) []Finding {
+	if runbook.Spec.Severity == "critical" && runbook.Spec.Content.Impact == "" {
+		return []Finding{errorAt(contentPath.Child("impact"), "impact is required for critical alerts")}
+	}
+	return nil
+}
+
+func validateInvestigationSteps(runbook *runbookv1alpha1.Runbook) []Finding {
+	if len(runbook.Spec.Content.Investigation) == 0 {
+		return []Finding{warningAt(contentPath.Child("investigation"), "at least one investigation step is recommended")}
+	}
+	return nil
+}
+
+// validateAutomatedCommands requires a command or script for every step that can be automated
+func validateAutomatedCommands(runbook *runbookv1alpha1.Runbook) []Finding {
+	var findings []Finding
+	for i, step := range runbook.Spec.Content.Remediation {
+		if step.Automated && step.Command == "" && step.Script == "" {
+			findings = append(findings, errorAt(contentPath.Child("remediation").Index(i).Child("command"),
+				"automated remediation steps require a command"))
+		}
+		if step.Script != "" && (runbook.Spec.Content.Automation == nil || runbook.Spec.Content.Automation.ScriptsConfigMap == "") {
+			findings = append(findings, errorAt(contentPath.Child("remediation").Index(i).Child("script"),
+				"scripts require automation.scriptsConfigMap to be set"))
+		}
+	}
+	return findings
+}
+
+func validateReferenceTypes(runbook *runbookv1alpha1.Runbook) []Finding {
+	var findings []Finding
+	for i, ref := range runbook.Spec.Content.References {
+		if ref.Type != "" && !referenceTypes[ref.Type] {
+			findings = append(findings, errorAt(contentPath.Child("references").Index(i).Child("type"),
+				"unsupported reference type %q, must be one of wiki, dashboard, documentation or runbook", ref.Type))
+		}
+	}
+	return findings
+}
+
+// validateTriggerConditions checks that every automation trigger condition
+// parses and that webhook triggers can verify their requests
+func validateTriggerConditions(runbook *runbookv1alpha1.Runbook) []Finding {
+	automation := runbook.Spec.Content.Automation
+	if automation == nil {
+		return nil
+	}
+
+	var findings []Finding
+	for i, trigger := range automation.Triggers {
+		if trigger.Type == "webhook" && automation.WebhookSecretRef == nil {
+			findings = append(findings, errorAt(contentPath.Child("automation", "triggers").Index(i).Child("type"),
+				"webhook triggers require automation.webhookSecretRef to verify request signatures"))
+		}
+		for j, condition := range trigger.Conditions {
+			if _, err := triggers.Parse(condition); err != nil {
+				findings = append(findings, errorAt(
+					contentPath.Child("automation", "triggers").Index(i).Child("conditions").Index(j), "%v", err))
+			}
+		}
+	}
+	return findings
+}
+
+// validateCommandPlaceholders checks that the placeholders of every command
+// parse and reference labels with an example value, so that generated docs
+// render them and the labels an alert must carry are declared
+func validateCommandPlaceholders(runbook *runbookv1alpha1.Runbook) []Finding {
+	var findings []Finding
+	check := func(path *field.Path, command string) {
+		labels, err := commands.Labels(command)
+		if err != nil {
+			findings = append(findings, errorAt(path, "%v", err))
+			return
+		}
+		for _, label := range labels {
+			if _, ok := runbook.Spec.ExampleLabels[label]; !ok {
+				findings = append(findings, errorAt(path,
+					"placeholder {{ .Labels.%s }} has no example value in spec.exampleLabels", label))
+			}
+		}
+	}
+
+	for i, step := range runbook.Spec.Content.Investigation {
+		check(contentPath.Child("investigation").Index(i).Child("command"), step.Command)
+	}
+	for i, step := range runbook.Spec.Content.Remediation {
+		check(contentPath.Child("remediation").Index(i).Child("command"), step.Command)
+	}
+	return findings
+}
+
+// validateInvestigationExpectations checks that automated investigation steps
+// have a command and that their expectations can be evaluated
+func validateInvestigationExpectations(runbook *runbookv1alpha1.Runbook) []Finding {
+	var findings []Finding
+	for i, step := range runbook.Spec.Content.Investigation {
+		path := contentPath.Child("investigation").Index(i)
+		if step.Automated && step.Command == "" {
+			findings = append(findings, errorAt(path.Child("command"), "automated investigation steps require a command"))
+		}
+		if step.Expectation == nil {
+			continue
+		}
+		if step.Expectation.Regex != "" {
+			if _, err := regexp.Compile(step.Expectation.Regex); err != nil {
+				findings = append(findings, errorAt(path.Child("expectation", "regex"), "%v", err))
+			}
+		}
+		if step.Expectation.Threshold != "" {
+			if _, err := triggers.Parse("output" + step.Expectation.Threshold); err != nil {
+				findings = append(findings, errorAt(path.Child("expectation", "threshold"),
+					"invalid threshold %q: expected <operator><value>", step.Expectation.Threshold))
+			}
+		}
+	}
+	return findings
+}
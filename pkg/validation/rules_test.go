@@ -0,0 +1,87 @@
+package validation
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+var _ = Describe("DefaultRules", func() {
+	var runbook *runbookv1alpha1.Runbook
+
+	BeforeEach(func() {
+		runbook = newRunbook()
+	})
+
+	// errorFields returns the fields of the error level findings
+	errorFields := func() []string {
+		var fields []string
+		for _, finding := range NewDefaultValidator().Validate(runbook).Errors() {
+			fields = append(fields, finding.Field)
+		}
+		return fields
+	}
+
+	It("should require the impact of critical alerts only", func() {
+		runbook.Spec.Content.Impact = ""
+		Expect(errorFields()).To(Equal([]string{"spec.content.impact"}))
+
+		runbook.Spec.Severity = "warning"
+		Expect(errorFields()).To(BeEmpty())
+	})
+
+	It("should require commands for automated steps and a ConfigMap for scripts", func() {
+		runbook.Spec.Content.Investigation[0].Command = ""
+		runbook.Spec.Content.Remediation[0].Command = ""
+		runbook.Spec.Content.Remediation = append(runbook.Spec.Content.Remediation,
+			runbookv1alpha1.RemediationStep{Description: "Drain the node", Script: "drain.sh", Automated: true})
+
+		Expect(errorFields()).To(ConsistOf(
+			"spec.content.investigation[0].command",
+			"spec.content.remediation[0].command",
+			"spec.content.remediation[1].script",
+		))
+
+		runbook.Spec.Content.Automation.ScriptsConfigMap = "remediation-scripts"
+		runbook.Spec.Content.Investigation[0].Command = "kubectl get pods"
+		runbook.Spec.Content.Remediation[0].Command = "kubectl get pods"
+		Expect(errorFields()).To(BeEmpty())
+	})
+
+	It("should reject unsupported reference types", func() {
+		runbook.Spec.Content.References[0].Type = "ticket"
+		Expect(errorFields()).To(Equal([]string{"spec.content.references[0].type"}))
+	})
+
+	It("should check trigger conditions and webhook secrets", func() {
+		runbook.Spec.Content.Automation.Triggers = append(runbook.Spec.Content.Automation.Triggers,
+			runbookv1alpha1.TriggerConfig{Type: "webhook", Conditions: []string{"ref=main", "=broken"}})
+
+		Expect(errorFields()).To(ConsistOf(
+			"spec.content.automation.triggers[1].type",
+			"spec.content.automation.triggers[1].conditions[1]",
+		))
+	})
+
+	It("should require example values for the labels of command placeholders", func() {
+		runbook.Spec.Content.Remediation[0].Command = "kubectl delete pod {{ .Labels.pod }} -n {{ .Labels.namespace }}"
+		runbook.Spec.Content.Investigation[0].Command = "kubectl get {{ .Spec.AlertName }}"
+
+		findings := NewDefaultValidator().Validate(runbook).Errors()
+		Expect(Strings(findings)).To(ConsistOf(
+			"spec.content.investigation[0].command: invalid placeholder .Spec.AlertName: only .Labels.<name> is supported",
+			"spec.content.remediation[0].command: placeholder {{ .Labels.namespace }} has no example value in spec.exampleLabels",
+		))
+	})
+
+	It("should check that expectations can be evaluated", func() {
+		runbook.Spec.Content.Investigation[0].Expectation.Regex = "Running("
+		runbook.Spec.Content.Investigation[0].Expectation.Threshold = "five"
+
+		Expect(errorFields()).To(ConsistOf(
+			"spec.content.investigation[0].expectation.regex",
+			"spec.content.investigation[0].expectation.threshold",
+		))
+	})
+})
@@ -0,0 +1,143 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// Level is the severity of a validation finding
+type Level string
+
+const (
+	// LevelError marks findings that make the runbook invalid
+	LevelError Level = "error"
+	// LevelWarning marks findings that are reported but do not fail the runbook
+	LevelWarning Level = "warning"
+)
+
+// Finding is a single problem reported by a rule
+type Finding struct {
+	// Field is the path of the offending field, e.g. spec.content.impact
+	Field string
+
+	// Level tells whether the finding fails the runbook
+	Level Level
+
+	// Message describes the problem
+	Message string
+
+	// Rule is the name of the rule that reported the finding
+	Rule string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Field, f.Message)
+}
+
+// Rule checks one aspect of a runbook
+type Rule interface {
+	// Name identifies the rule in findings
+	Name() string
+
+	// Validate returns the findings for the runbook, or nil when it passes
+	Validate(runbook *runbookv1alpha1.Runbook) []Finding
+}
+
+// Validator runs a set of rules against runbooks
+type Validator struct {
+	rules []Rule
+}
+
+// NewValidator creates a validator with the given rules
+func NewValidator(rules ...Rule) *Validator {
+	return &Validator{rules: rules}
+}
+
+// NewDefaultValidator creates a validator with the built-in rules
+func NewDefaultValidator() *Validator {
+	return NewValidator(DefaultRules()...)
+}
+
+// Validate runs every rule and collects their findings
+func (v *Validator) Validate(runbook *runbookv1alpha1.Runbook) Result {
+	var result Result
+	for _, rule := range v.rules {
+		for _, finding := range rule.Validate(runbook) {
+			finding.Rule = rule.Name()
+			result.Findings = append(result.Findings, finding)
+		}
+	}
+	return result
+}
+
+// Result holds the findings of a validation run
+type Result struct {
+	Findings []Finding
+}
+
+// Errors returns the findings that make the runbook invalid
+func (r Result) Errors() []Finding {
+	return r.filter(LevelError)
+}
+
+// Warnings returns the findings that do not fail the runbook
+func (r Result) Warnings() []Finding {
+	return r.filter(LevelWarning)
+}
+
+// Err returns an *Error when the result contains error level findings
+func (r Result) Err() error {
+	if len(r.Errors()) == 0 {
+		return nil
+	}
+	return &Error{Findings: r.Findings}
+}
+
+func (r Result) filter(level Level) []Finding {
+	var findings []Finding
+	for _, finding := range r.Findings {
+		if finding.Level == level {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// Error is returned for runbooks with error level findings. It keeps the
+// warnings as well so they can still be reported.
+type Error struct {
+	Findings []Finding
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("runbook validation failed: %s", strings.Join(Strings(Result(*e).Errors()), "; "))
+}
+
+// Result returns the findings as a validation result
+func (e *Error) Result() Result {
+	return Result(*e)
+}
+
+// Strings formats findings as field qualified messages
+func Strings(findings []Finding) []string {
+	if len(findings) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		messages = append(messages, finding.String())
+	}
+	return messages
+}
+
+func errorAt(path *field.Path, format string, args ...interface{}) Finding {
+	return Finding{Field: path.String(), Level: LevelError, Message: fmt.Sprintf(format, args...)}
+}
+
+func warningAt(path *field.Path, format string, args ...interface{}) Finding {
+	return Finding{Field: path.String(), Level: LevelWarning, Message: fmt.Sprintf(format, args...)}
+}
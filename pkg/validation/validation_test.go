@@ -0,0 +1,89 @@
+package validation
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	runbookv1alpha1 "github.com/guibes/runbook-operator/api/v1alpha1"
+)
+
+// newRunbook returns a runbook that passes every default rule
+func newRunbook() *runbookv1alpha1.Runbook {
+	return &runbookv1alpha1.Runbook{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-errors", Namespace: "payments"},
+		Spec: runbookv1alpha1.RunbookSpec{
+			AlertName:     "APIErrors",
+			Severity:      "critical",
+			ExampleLabels: map[string]string{"pod": "api-0"},
+			Content: runbookv1alpha1.RunbookContent{
+				Impact: "Checkout requests fail",
+				Investigation: []runbookv1alpha1.InvestigationStep{{
+					Description: "Check the pod",
+					Command:     "kubectl get pod {{ quote .Labels.pod }}",
+					Automated:   true,
+					Expectation: &runbookv1alpha1.Expectation{ExitCode: ptr.To[int32](0), Regex: "Running", Threshold: "<5"},
+				}},
+				Remediation: []runbookv1alpha1.RemediationStep{
+					{Description: "Restart the pod", Command: "kubectl delete pod {{ quote .Labels.pod }}", Automated: true},
+				},
+				References: []runbookv1alpha1.Reference{{Title: "Dashboard", URL: "https://grafana.example.com", Type: "dashboard"}},
+				Automation: &runbookv1alpha1.AutomationConfig{
+					Enabled:  true,
+					Triggers: []runbookv1alpha1.TriggerConfig{{Type: "alert", Conditions: []string{"severity=critical"}}},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("Validator", func() {
+	It("should report no findings for a complete runbook", func() {
+		result := NewDefaultValidator().Validate(newRunbook())
+		Expect(result.Findings).To(BeEmpty())
+		Expect(result.Err()).NotTo(HaveOccurred())
+	})
+
+	It("should name the rule of every finding and split errors from warnings", func() {
+		runbook := newRunbook()
+		runbook.Spec.AlertName = ""
+		runbook.Spec.Content.Investigation = nil
+
+		result := NewDefaultValidator().Validate(runbook)
+		Expect(result.Errors()).To(Equal([]Finding{
+			{Field: "spec.alertName", Level: LevelError, Message: "alert name is required", Rule: "alert-name"},
+		}))
+		Expect(result.Warnings()).To(Equal([]Finding{{
+			Field:   "spec.content.investigation",
+			Level:   LevelWarning,
+			Message: "at least one investigation step is recommended",
+			Rule:    "investigation-steps",
+		}}))
+
+		err := result.Err()
+		Expect(err).To(MatchError("runbook validation failed: spec.alertName: alert name is required"))
+		var validationErr *Error
+		Expect(err).To(BeAssignableToTypeOf(validationErr))
+		Expect(err.(*Error).Result().Warnings()).To(HaveLen(1))
+	})
+
+	It("should not fail runbooks with warnings only", func() {
+		runbook := newRunbook()
+		runbook.Spec.Content.Investigation = nil
+
+		Expect(NewDefaultValidator().Validate(runbook).Err()).NotTo(HaveOccurred())
+	})
+
+	It("should run custom rules", func() {
+		validator := NewValidator(RuleFunc("team", func(runbook *runbookv1alpha1.Runbook) []Finding {
+			if runbook.Spec.Team == "" {
+				return []Finding{warningAt(specPath.Child("team"), "team is recommended")}
+			}
+			return nil
+		}))
+
+		Expect(Strings(validator.Validate(newRunbook()).Findings)).To(Equal([]string{"spec.team: team is recommended"}))
+		Expect(Strings(nil)).To(BeNil())
+	})
+})